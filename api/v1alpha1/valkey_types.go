@@ -17,6 +17,10 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -25,58 +29,265 @@ type ValkeySpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// Image of Valkey to deploy
-	// +kubebuilder:validation:Required
-	Image string `json:"image"`
+	// Image of Valkey to deploy, operator default is used if empty
+	// +optional
+	Image string `json:"image,omitempty"`
 
-	// Replicas count
+	// Replicas count, operator default is used if omitted,
+	// zero stops the instance and keeps its volumes
 	// +kubebuilder:validation:Minimum=0
 	// +kubebuilder:validation:Maximum=5
-	Replicas int32 `json:"replicas"`
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Mode could be 'standalone', 'replication' or 'cluster'
+	// +kubebuilder:validation:Enum=standalone;replication;cluster
+	// +kubebuilder:default=standalone
+	// +optional
+	Mode TypeMode `json:"mode,omitempty"`
+
+	// Sentinel enables automatic failover in replication mode
+	// +optional
+	Sentinel *Sentinel `json:"sentinel,omitempty"`
+
+	// Cluster describes topology in cluster mode,
+	// replicas count is calculated from it and Replicas is ignored
+	// +optional
+	Cluster *Cluster `json:"cluster,omitempty"`
 
 	// User that will be admin
 	// +kubebuilder:validation:Required
 	User string `json:"user"`
 
-	// Password for admin
-	// +kubebuilder:validation:Required
-	Password string `json:"password"`
+	// Password for admin, it's stored in clear text, so Auth should be preferred.
+	// Random password is generated if neither Password nor Auth is set
+	// +optional
+	Password string `json:"password,omitempty"`
+
+	// Auth describes where admin password is taken from
+	// +optional
+	Auth *Auth `json:"auth,omitempty"`
 
 	// UsePersistentVolume for Valkey
-	// +kubebuilder:validation:Required
-	Volume Volume `json:"volume"`
+	// +optional
+	Volume Volume `json:"volume,omitempty"`
 
-	// Resource requirements
-	// +kubebuilder:validation:Required
-	Resource Resource `json:"resource"`
+	// Resource requirements, operator defaults are used for empty fields
+	// +optional
+	Resource Resource `json:"resource,omitempty"`
+
+	// DeletionPolicy could be 'Delete', 'Retain' or 'Snapshot', it tells what
+	// is done with volume claims when the instance is deleted
+	// +kubebuilder:validation:Enum=Delete;Retain;Snapshot
+	// +kubebuilder:default=Delete
+	// +optional
+	DeletionPolicy TypeDeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Config contains parameters of valkey.conf, e.g. 'maxmemory-policy: allkeys-lru'.
+	// Parameters which can be changed at runtime are applied by CONFIG SET,
+	// pods are restarted when the rest are changed
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
+
+	// TLS encrypts client, replication and cluster bus traffic
+	// +optional
+	TLS *TLS `json:"tls,omitempty"`
+
+	// RestoreFrom seeds empty data volumes with RDB file of a backup
+	// or another instance, it's resolved only when the instance is created.
+	// Valkey ignores RDB file when AOF is enabled on start, so appendonly
+	// can be set only after the instance is restored
+	// +optional
+	RestoreFrom *RestoreSource `json:"restoreFrom,omitempty"`
+
+	// Paused stops reconcile of the instance, child objects aren't changed
+	// until it's unset, e.g. while data is fixed manually.
+	// The same is done by AnnotationPaused
+	// +optional
+	Paused bool `json:"paused,omitempty"`
 }
 
+// AnnotationPaused set to "true" pauses reconcile like ValkeySpec.Paused,
+// so the instance can be paused without changing its spec
+const AnnotationPaused = "database.kuberly.io/paused"
+
 type Volume struct {
 	// Enabled means that persistent storage should be added
 	Enabled bool `json:"enabled"`
 
-	// Storage requirements (e.g., "200Mi", "1Gi", "10Gi", "1Ti")
+	// Storage requirements (e.g., "200Mi", "1Gi", "10Gi", "1Ti"),
+	// operator default is used if empty
 	// +kubebuilder:validation:Pattern=^[0-9]+[MGT]i$
-	Storage string `json:"storage"`
+	// +optional
+	Storage string `json:"storage,omitempty"`
+}
+
+// RestoreSource is either a completed backup or a running instance
+// in the same namespace
+// +kubebuilder:validation:XValidation:rule="has(self.backupName) != has(self.valkeyName)",message="exactly one of backupName or valkeyName should be set"
+type RestoreSource struct {
+	// BackupName is a name of completed ValkeyBackup which RDB file is downloaded
+	// +optional
+	BackupName string `json:"backupName,omitempty"`
+
+	// ValkeyName is a name of running instance which data is cloned,
+	// RDB file is taken from its primary by valkey-cli
+	// +optional
+	ValkeyName string `json:"valkeyName,omitempty"`
+
+	// Image of the init container which downloads backup, it should contain
+	// sh, sha256sum and curl with AWS signature support for S3 destination.
+	// Image of the instance is used to clone another instance
+	// +kubebuilder:default="curlimages/curl:8.10.1"
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// TLS takes certificates from existing Secret or issues them by cert-manager,
+// the Secret should contain 'tls.crt', 'tls.key' and 'ca.crt' valid for
+// names of pods and services. Renewed certificates are reloaded without restart
+// +kubebuilder:validation:XValidation:rule="!self.enabled || has(self.secretName) != has(self.issuerRef)",message="exactly one of secretName or issuerRef should be set"
+type TLS struct {
+	// Enabled means that Valkey listens on TLS port
+	Enabled bool `json:"enabled"`
+
+	// SecretName is a name of the Secret with certificates in the same namespace
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// IssuerRef points to cert-manager issuer, Certificate is created
+	// and written to the Secret named '<name>-tls'
+	// +optional
+	IssuerRef *IssuerRef `json:"issuerRef,omitempty"`
+
+	// DisablePlaintext closes plaintext port, so only TLS connections are accepted
+	// +optional
+	DisablePlaintext bool `json:"disablePlaintext,omitempty"`
+}
+
+type IssuerRef struct {
+	// Name of the issuer
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Kind could be 'Issuer' or 'ClusterIssuer'
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +kubebuilder:default=Issuer
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Group of the issuer
+	// +kubebuilder:default=cert-manager.io
+	// +optional
+	Group string `json:"group,omitempty"`
+}
+
+type Auth struct {
+	// PasswordSecretRef points to the key of existing Secret with admin password,
+	// the Secret isn't modified by operator and pods are restarted when it's changed
+	// +optional
+	PasswordSecretRef *SecretKeyRef `json:"passwordSecretRef,omitempty"`
+}
+
+type SecretKeyRef struct {
+	// Name of the Secret in the same namespace
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Key of the password in the Secret
+	// +kubebuilder:default=password
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+type Sentinel struct {
+	// Enabled means that Sentinel quorum should be deployed
+	Enabled bool `json:"enabled"`
+
+	// Replicas is a number of Sentinel instances
+	// +kubebuilder:validation:Minimum=3
+	// +kubebuilder:default=3
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Quorum is a number of Sentinels that need to agree
+	// about primary failure to start a failover
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=2
+	// +optional
+	Quorum int32 `json:"quorum,omitempty"`
+
+	// Image of Sentinel, Valkey image is used if empty
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+type Cluster struct {
+	// Shards is a number of primaries which share hash slots
+	// +kubebuilder:validation:Minimum=3
+	// +kubebuilder:default=3
+	Shards int32 `json:"shards"`
+
+	// ReplicasPerShard is a number of replicas of every primary
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=5
+	// +kubebuilder:default=1
+	// +optional
+	ReplicasPerShard int32 `json:"replicas_per_shard"`
+}
+
+// Size returns number of pods needed for the cluster
+func (c *Cluster) Size() int32 {
+	return c.Shards * (1 + c.ReplicasPerShard)
 }
 
 type Resource struct {
 	// Memory requirements (e.g., "512Mi", "1Gi")
-	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:Pattern=^[0-9]+[KMG]i$
-	Memory string `json:"memory"`
+	// +optional
+	Memory string `json:"memory,omitempty"`
 
 	// CPU requirements (e.g., "100m", "1", "2.5")
-	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:Pattern=^[0-9]+m?$
-	CPU string `json:"cpu"`
+	// +optional
+	CPU string `json:"cpu,omitempty"`
 
 	// Storage requirements (e.g., "200Mi", "1Gi", "10Gi", "1Ti")
-	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:Pattern=^[0-9]+[MGT]i$
-	Storage string `json:"storage"`
+	// +optional
+	Storage string `json:"storage,omitempty"`
 }
 
+type TypeMode string
+
+const (
+	// TypeModeStandalone runs every replica as independent Valkey server
+	TypeModeStandalone TypeMode = "standalone"
+	// TypeModeReplication runs the first replica as primary
+	// and configures the rest to replicate from it
+	TypeModeReplication TypeMode = "replication"
+	// TypeModeCluster runs sharded Valkey Cluster where
+	// hash slots are split between primaries
+	TypeModeCluster TypeMode = "cluster"
+)
+
+type TypeDeletionPolicy string
+
+const (
+	// TypeDeletionPolicyDelete removes volume claims together with the instance
+	TypeDeletionPolicyDelete TypeDeletionPolicy = "Delete"
+	// TypeDeletionPolicyRetain keeps volume claims, they are labeled with
+	// LabelRetainedFrom and adopted by a new instance with the same name
+	TypeDeletionPolicyRetain TypeDeletionPolicy = "Retain"
+	// TypeDeletionPolicySnapshot takes VolumeSnapshot of every claim,
+	// claims are removed only after snapshots are ready to use
+	TypeDeletionPolicySnapshot TypeDeletionPolicy = "Snapshot"
+)
+
+// LabelRetainedFrom is set on volume claims kept after the instance
+// was deleted, value is a name of the instance
+const LabelRetainedFrom = "database.kuberly.io/retained-from"
+
 type TypeStatus string
 
 const (
@@ -87,34 +298,217 @@ const (
 	TypeStatusStopped  TypeStatus = "stopped"
 )
 
+// TypePhase is a step of reconcile the instance is at, reconcile
+// is resumed from it instead of waiting for child objects in place
+type TypePhase string
+
+const (
+	// TypePhaseCreating means that child objects are created and finalizer is saved
+	TypePhaseCreating TypePhase = "creating"
+	// TypePhaseProvisioning means that pods or volumes aren't ready yet
+	TypePhaseProvisioning TypePhase = "provisioning"
+	// TypePhaseConfiguring means that replication or cluster slots are being set up
+	TypePhaseConfiguring TypePhase = "configuring"
+	// TypePhaseRunning means that instance matches the spec
+	TypePhaseRunning TypePhase = "running"
+	// TypePhaseDeleting means that child objects are being removed
+	TypePhaseDeleting TypePhase = "deleting"
+)
+
+// PasswordSecretRef returns reference to the Secret with password, if any
+func (s *ValkeySpec) PasswordSecretRef() *SecretKeyRef {
+	if s.Auth == nil {
+		return nil
+	}
+
+	return s.Auth.PasswordSecretRef
+}
+
+// TLSEnabled tells whether Valkey listens on TLS port
+func (s *ValkeySpec) TLSEnabled() bool {
+	return s.TLS != nil && s.TLS.Enabled
+}
+
+// PodsCount returns number of pods which should be running
+func (s *ValkeySpec) PodsCount() int32 {
+	if s.Mode == TypeModeCluster && s.Cluster != nil {
+		return s.Cluster.Size()
+	}
+
+	if s.Replicas == nil {
+		return 1
+	}
+
+	return *s.Replicas
+}
+
+// ClaimStorage returns size of every volume claim, resource storage
+// is used by instances created before volume storage was introduced
+func (s *ValkeySpec) ClaimStorage() string {
+	if s.Volume.Storage != "" {
+		return s.Volume.Storage
+	}
+
+	return s.Resource.Storage
+}
+
+// TypeStepState is a result of the flow step
+type TypeStepState string
+
+const (
+	// TypeStepStateDone means that step has nothing to do
+	TypeStepStateDone TypeStepState = "done"
+	// TypeStepStateWaiting means that step waits for child objects, next steps aren't run
+	TypeStepStateWaiting TypeStepState = "waiting"
+	// TypeStepStateFailed means that step returned an error
+	TypeStepStateFailed TypeStepState = "failed"
+	// TypeStepStatePending means that step wasn't run because previous one didn't finish
+	TypeStepStatePending TypeStepState = "pending"
+)
+
+// Condition types reported in ValkeyStatus
+const (
+	// ConditionReady means that instance accepts connections
+	ConditionReady = "Ready"
+	// ConditionProgressing means that resources are being created or changed
+	ConditionProgressing = "Progressing"
+	// ConditionDegraded means that instance works with reduced capacity or reconcile fails
+	ConditionDegraded = "Degraded"
+	// ConditionSecretReady means that Secret with password exists
+	ConditionSecretReady = "SecretReady"
+	// ConditionStorageReady means that every volume claim is bound
+	ConditionStorageReady = "StorageReady"
+	// ConditionDriftDetected means that manual changes of managed objects were reverted
+	ConditionDriftDetected = "DriftDetected"
+	// ConditionPaused means that reconcile is stopped by spec or annotation
+	ConditionPaused = "Paused"
+	// ConditionStorageResizing means that volume claims are expanded to the requested size
+	ConditionStorageResizing = "StorageResizing"
+	// ConditionCertificateReady means that every pod serves certificate from the TLS Secret
+	ConditionCertificateReady = "CertificateReady"
+)
+
+// Condition reasons reported in ValkeyStatus
+const (
+	ReasonReconciled            = "Reconciled"
+	ReasonCreating              = "Creating"
+	ReasonReplicasNotReady      = "ReplicasNotReady"
+	ReasonRebalancing           = "Rebalancing"
+	ReasonReconcileFailed       = "ReconcileFailed"
+	ReasonInvalidSpec           = "InvalidSpec"
+	ReasonForbidden             = "Forbidden"
+	ReasonDependencyNotReady    = "DependencyNotReady"
+	ReasonPaused                = "Paused"
+	ReasonResumed               = "Resumed"
+	ReasonSecretFound           = "SecretFound"
+	ReasonSecretNotFound        = "SecretNotFound"
+	ReasonClaimsBound           = "ClaimsBound"
+	ReasonClaimsPending         = "ClaimsPending"
+	ReasonStorageNotRequired    = "StorageNotRequired"
+	ReasonDriftCorrected        = "DriftCorrected"
+	ReasonInSync                = "InSync"
+	ReasonResizing              = "Resizing"
+	ReasonResized               = "Resized"
+	ReasonExpansionNotSupported = "ExpansionNotSupported"
+	ReasonShrinkNotSupported    = "ShrinkNotSupported"
+	ReasonCertificateLoaded     = "CertificateLoaded"
+	ReasonCertificateReloading  = "CertificateReloading"
+)
+
 // ValkeyStatus defines the observed state of Valkey
 type ValkeyStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
+	// ObservedGeneration is a generation of the spec which status was built for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions are the latest observations of the instance state
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Phase could be 'creating', 'provisioning', 'configuring', 'running' or 'deleting',
+	// it's kept when reconcile fails, so the next one continues from it
+	Phase TypePhase `json:"phase,omitempty"`
 	// Status could be 'healthy', 'failed', 'stopped'
 	Status TypeStatus `json:"status,omitempty"`
 	// Error will be filled if some occurs
 	Error string `json:"error,omitempty"`
 	// ReadyReplicas is a number of working replicas
 	ReadyReplicas int32 `json:"ready_replicas"`
+	// Primary is a name of the pod which accepts writes in replication mode
+	Primary string `json:"primary,omitempty"`
+	// Shards contains health of every shard in cluster mode
+	Shards []ShardStatus `json:"shards,omitempty"`
+	// Steps contains result of every step of the last reconcile in order they are run
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Steps []StepStatus `json:"steps,omitempty"`
 	// LastReconcileAt contains timestamp of the last reconcile
 	// only if something was changed
 	LastReconcileAt *metav1.Time `json:"last_reconcile_at,omitempty"`
 }
 
+type ShardStatus struct {
+	// Index of the shard, pods of the shard go one by one in StatefulSet
+	Index int32 `json:"index"`
+	// Status could be 'healthy', 'updating' or 'failed'
+	Status TypeStatus `json:"status"`
+	// Primary is a name of the pod which owns slots of the shard
+	Primary string `json:"primary,omitempty"`
+	// Slots are ranges of hash slots served by the shard, e.g. '0-5460'
+	Slots string `json:"slots,omitempty"`
+	// ReadyReplicas is a number of replicas which follow the primary
+	ReadyReplicas int32 `json:"ready_replicas"`
+}
+
+type StepStatus struct {
+	// Name of the step
+	Name string `json:"name"`
+	// State could be 'done', 'waiting', 'failed' or 'pending'
+	State TypeStepState `json:"state"`
+	// Message describes result of the step
+	Message string `json:"message,omitempty"`
+}
+
+// IsChanged compares statuses semantically, time of the last
+// reconcile is ignored, so saving it doesn't cause another write
 func (s *ValkeyStatus) IsChanged(new *ValkeyStatus) bool {
-	if s.Error != new.Error {
-		return true
-	}
-	if s.ReadyReplicas != new.ReadyReplicas {
-		return true
-	}
-	if s.Status != new.Status {
-		return true
+	prev, next := s.DeepCopy(), new.DeepCopy()
+	prev.LastReconcileAt, next.LastReconcileAt = nil, nil
+
+	return !equality.Semantic.DeepEqual(prev, next)
+}
+
+// SetStep adds or updates result of the flow step
+func (s *ValkeyStatus) SetStep(name string, state TypeStepState, message string) {
+	step := StepStatus{Name: name, State: state, Message: message}
+
+	i := slices.IndexFunc(s.Steps, func(v StepStatus) bool { return v.Name == name })
+	if i < 0 {
+		s.Steps = append(s.Steps, step)
+		return
 	}
 
-	return false
+	s.Steps[i] = step
+}
+
+// SetLastReconcileAt saves time when changed status was written
+func (s *ValkeyStatus) SetLastReconcileAt(t metav1.Time) {
+	s.LastReconcileAt = &t
+}
+
+// SetCondition adds or updates condition for observed generation,
+// transition time is kept while condition status is the same
+func (s *ValkeyStatus) SetCondition(condType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&s.Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		ObservedGeneration: s.ObservedGeneration,
+		Reason:             reason,
+		Message:            message,
+	})
 }
 
 //+kubebuilder:object:root=true
@@ -122,12 +516,17 @@ func (s *ValkeyStatus) IsChanged(new *ValkeyStatus) bool {
 //+kubebuilder:printcolumn:name="Image",type="string",JSONPath=".spec.image"
 //+kubebuilder:printcolumn:name="CPU",type="string",JSONPath=".spec.resource.cpu"
 //+kubebuilder:printcolumn:name="Memory",type="string",JSONPath=".spec.resource.memory"
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
 //+kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.status"
 //+kubebuilder:printcolumn:name="Error",type="string",JSONPath=".status.error"
 //+kubebuilder:printcolumn:name="Has volume",type="boolean",JSONPath=".spec.volume.enabled"
 //+kubebuilder:printcolumn:name="Volume size",type="string",JSONPath=".spec.volume.storage"
 //+kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".spec.replicas"
 //+kubebuilder:printcolumn:name="Ready replicas",type="integer",JSONPath=".status.ready_replicas"
+//+kubebuilder:printcolumn:name="Mode",type="string",JSONPath=".spec.mode"
+//+kubebuilder:printcolumn:name="Primary",type="string",JSONPath=".status.primary"
+//+kubebuilder:printcolumn:name="Shards",type="integer",JSONPath=".spec.cluster.shards"
 //+kubebuilder:printcolumn:name="Last reconcile",type="date",JSONPath=".status.last_reconcile_at"
 
 // Valkey is the Schema for the valkeys API
@@ -139,6 +538,28 @@ type Valkey struct {
 	Status ValkeyStatus `json:"status,omitempty"`
 }
 
+// PausedBy returns what paused reconcile of the instance, empty if it isn't paused
+func (v *Valkey) PausedBy() string {
+	switch {
+	case v.Spec.Paused:
+		return "spec.paused"
+	case v.Annotations[AnnotationPaused] == "true":
+		return "annotation " + AnnotationPaused
+	default:
+		return ""
+	}
+}
+
+// GetStatus returns status of the instance
+func (v *Valkey) GetStatus() *ValkeyStatus {
+	return &v.Status
+}
+
+// SetStatus replaces status of the instance
+func (v *Valkey) SetStatus(status *ValkeyStatus) {
+	v.Status = *status
+}
+
 //+kubebuilder:object:root=true
 
 // ValkeyList contains a list of Valkey
@@ -0,0 +1,257 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ValkeyBackupSpec defines the desired state of ValkeyBackup
+type ValkeyBackupSpec struct {
+	// ValkeyName is a name of the instance in the same namespace which data is saved
+	// +kubebuilder:validation:MinLength=1
+	ValkeyName string `json:"valkeyName"`
+
+	// Destination is where RDB file is copied to
+	Destination BackupDestination `json:"destination"`
+
+	// Image of the job which copies RDB file, it should contain sh,
+	// sha256sum and curl with AWS signature support for S3 destination
+	// +kubebuilder:default="curlimages/curl:8.10.1"
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// BackupDestination is either a volume claim or S3-compatible bucket
+// +kubebuilder:validation:XValidation:rule="has(self.pvc) != has(self.s3)",message="exactly one of pvc or s3 should be set"
+type BackupDestination struct {
+	// PVC is a volume claim in the same namespace
+	// +optional
+	PVC *PVCDestination `json:"pvc,omitempty"`
+
+	// S3 is a bucket of S3-compatible storage, e.g. MinIO
+	// +optional
+	S3 *S3Destination `json:"s3,omitempty"`
+}
+
+type PVCDestination struct {
+	// ClaimName is a name of the volume claim, it should be
+	// mountable on the node of the backed up pod
+	// +kubebuilder:validation:MinLength=1
+	ClaimName string `json:"claimName"`
+
+	// Path is a directory in the volume, root is used if empty
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+type S3Destination struct {
+	// Endpoint is URL of the storage, e.g. 'http://minio.minio:9000'
+	// +kubebuilder:validation:Pattern=`^https?://`
+	Endpoint string `json:"endpoint"`
+
+	// Bucket the RDB file is uploaded to, it should exist
+	// +kubebuilder:validation:MinLength=1
+	Bucket string `json:"bucket"`
+
+	// Prefix of the object key, name of the backup is appended to it
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Region of the bucket
+	// +kubebuilder:default=us-east-1
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// AccessKeyRef is a Secret key with access key id
+	AccessKeyRef SecretKeyRef `json:"accessKeyRef"`
+
+	// SecretKeyRef is a Secret key with secret access key
+	SecretKeyRef SecretKeyRef `json:"secretKeyRef"`
+}
+
+// FilePath returns path of RDB file of the backup in the volume
+func (d *PVCDestination) FilePath(backupName string) string {
+	return path.Join(d.Path, backupName+".rdb")
+}
+
+// ObjectKey returns key of RDB file of the backup in the bucket
+func (d *S3Destination) ObjectKey(backupName string) string {
+	return path.Join(d.Prefix, backupName+".rdb")
+}
+
+// URL returns path-style URL of RDB file of the backup,
+// it's supported by MinIO without DNS setup
+func (d *S3Destination) URL(backupName string) string {
+	return strings.TrimSuffix(d.Endpoint, "/") + "/" + d.Bucket + "/" + d.ObjectKey(backupName)
+}
+
+// TypeBackupPhase is a step the backup is at
+type TypeBackupPhase string
+
+const (
+	// TypeBackupPhasePending means that the instance isn't running yet
+	TypeBackupPhasePending TypeBackupPhase = "pending"
+	// TypeBackupPhaseSaving means that RDB file is written by BGSAVE
+	TypeBackupPhaseSaving TypeBackupPhase = "saving"
+	// TypeBackupPhaseCopying means that RDB file is copied by the job
+	TypeBackupPhaseCopying TypeBackupPhase = "copying"
+	// TypeBackupPhaseCompleted means that RDB file is in the destination
+	TypeBackupPhaseCompleted TypeBackupPhase = "completed"
+	// TypeBackupPhaseFailed means that the job failed, backup isn't retried
+	TypeBackupPhaseFailed TypeBackupPhase = "failed"
+)
+
+// IsFinished reports whether nothing is done for the backup anymore
+func (p TypeBackupPhase) IsFinished() bool {
+	return p == TypeBackupPhaseCompleted || p == TypeBackupPhaseFailed
+}
+
+// Condition types reported in ValkeyBackupStatus
+const (
+	// ConditionCompleted means that RDB file is in the destination
+	ConditionCompleted = "Completed"
+)
+
+// Condition reasons reported in ValkeyBackupStatus
+const (
+	ReasonBackupInProgress = "InProgress"
+	ReasonBackupCompleted  = "BackupCompleted"
+	ReasonBackupFailed     = "BackupFailed"
+)
+
+// ValkeyBackupStatus defines the observed state of ValkeyBackup
+type ValkeyBackupStatus struct {
+	// ObservedGeneration is a generation of the spec which status was built for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions are the latest observations of the backup state
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Phase could be 'pending', 'saving', 'copying', 'completed' or 'failed'
+	Phase TypeBackupPhase `json:"phase,omitempty"`
+	// Error will be filled if some occurs
+	Error string `json:"error,omitempty"`
+	// Pod is a name of the pod which RDB file is copied
+	Pod string `json:"pod,omitempty"`
+	// Location is where RDB file was copied to, e.g. 's3://bucket/key'
+	Location string `json:"location,omitempty"`
+	// Size of RDB file in bytes
+	Size int64 `json:"size,omitempty"`
+	// Checksum is sha256 of RDB file
+	Checksum string `json:"checksum,omitempty"`
+	// StartedAt is a time BGSAVE was requested at
+	StartedAt *metav1.Time `json:"started_at,omitempty"`
+	// CompletedAt is a time the backup was completed or failed at
+	CompletedAt *metav1.Time `json:"completed_at,omitempty"`
+	// Steps contains result of every step of the last reconcile in order they are run
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Steps []StepStatus `json:"steps,omitempty"`
+	// LastReconcileAt contains timestamp of the last reconcile
+	// only if something was changed
+	LastReconcileAt *metav1.Time `json:"last_reconcile_at,omitempty"`
+}
+
+// IsChanged compares statuses semantically, time of the last
+// reconcile is ignored, so saving it doesn't cause another write
+func (s *ValkeyBackupStatus) IsChanged(new *ValkeyBackupStatus) bool {
+	prev, next := s.DeepCopy(), new.DeepCopy()
+	prev.LastReconcileAt, next.LastReconcileAt = nil, nil
+
+	return !equality.Semantic.DeepEqual(prev, next)
+}
+
+// SetStep adds or updates result of the flow step
+func (s *ValkeyBackupStatus) SetStep(name string, state TypeStepState, message string) {
+	step := StepStatus{Name: name, State: state, Message: message}
+
+	i := slices.IndexFunc(s.Steps, func(v StepStatus) bool { return v.Name == name })
+	if i < 0 {
+		s.Steps = append(s.Steps, step)
+		return
+	}
+
+	s.Steps[i] = step
+}
+
+// SetLastReconcileAt saves time when changed status was written
+func (s *ValkeyBackupStatus) SetLastReconcileAt(t metav1.Time) {
+	s.LastReconcileAt = &t
+}
+
+// SetCondition adds or updates condition for observed generation,
+// transition time is kept while condition status is the same
+func (s *ValkeyBackupStatus) SetCondition(condType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&s.Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		ObservedGeneration: s.ObservedGeneration,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Valkey",type="string",JSONPath=".spec.valkeyName"
+//+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+//+kubebuilder:printcolumn:name="Size",type="integer",JSONPath=".status.size"
+//+kubebuilder:printcolumn:name="Location",type="string",JSONPath=".status.location"
+//+kubebuilder:printcolumn:name="Completed at",type="date",JSONPath=".status.completed_at"
+//+kubebuilder:printcolumn:name="Error",type="string",JSONPath=".status.error"
+
+// ValkeyBackup is the Schema for the valkeybackups API,
+// it copies RDB file of the instance once
+type ValkeyBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ValkeyBackupSpec   `json:"spec,omitempty"`
+	Status ValkeyBackupStatus `json:"status,omitempty"`
+}
+
+// GetStatus returns status of the backup
+func (v *ValkeyBackup) GetStatus() *ValkeyBackupStatus {
+	return &v.Status
+}
+
+// SetStatus replaces status of the backup
+func (v *ValkeyBackup) SetStatus(status *ValkeyBackupStatus) {
+	v.Status = *status
+}
+
+//+kubebuilder:object:root=true
+
+// ValkeyBackupList contains a list of ValkeyBackup
+type ValkeyBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ValkeyBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ValkeyBackup{}, &ValkeyBackupList{})
+}
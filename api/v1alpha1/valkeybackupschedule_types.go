@@ -0,0 +1,204 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ValkeyBackupScheduleSpec defines the desired state of ValkeyBackupSchedule
+type ValkeyBackupScheduleSpec struct {
+	// Schedule in cron format, e.g. '0 3 * * *', time zone of the operator is used
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Suspend stops creating new backups, retention is still enforced
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// ConcurrencyPolicy tells what to do when the previous backup isn't finished yet
+	// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+	// +kubebuilder:default=Forbid
+	// +optional
+	ConcurrencyPolicy TypeConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+
+	// Retention tells which finished backups are pruned together with their RDB files
+	// +optional
+	Retention BackupRetention `json:"retention,omitempty"`
+
+	// Template is a spec of created backups
+	Template ValkeyBackupSpec `json:"template"`
+}
+
+// TypeConcurrencyPolicy tells how backups of the schedule run concurrently
+type TypeConcurrencyPolicy string
+
+const (
+	// TypeConcurrencyPolicyAllow creates backup while the previous one is running
+	TypeConcurrencyPolicyAllow TypeConcurrencyPolicy = "Allow"
+	// TypeConcurrencyPolicyForbid skips backup while the previous one is running
+	TypeConcurrencyPolicyForbid TypeConcurrencyPolicy = "Forbid"
+	// TypeConcurrencyPolicyReplace deletes running backup and creates a new one
+	TypeConcurrencyPolicyReplace TypeConcurrencyPolicy = "Replace"
+)
+
+// BackupRetention limits finished backups of the schedule,
+// nothing is pruned if it's empty
+type BackupRetention struct {
+	// KeepLast is a number of the latest completed backups which are kept,
+	// failed backups are kept only while they are newer than them
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	KeepLast *int32 `json:"keepLast,omitempty"`
+
+	// MaxAge of kept backups, e.g. '168h'
+	// +optional
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// LabelBackupSchedule is set on backups created by the schedule,
+// value is a name of the schedule
+const LabelBackupSchedule = "database.kuberly.io/backup-schedule"
+
+// Condition types reported in ValkeyBackupScheduleStatus
+const (
+	// ConditionScheduled means that backups are created by the schedule
+	ConditionScheduled = "Scheduled"
+)
+
+// Condition reasons reported in ValkeyBackupScheduleStatus
+const (
+	ReasonScheduleActive    = "Active"
+	ReasonScheduleSuspended = "Suspended"
+)
+
+// ValkeyBackupScheduleStatus defines the observed state of ValkeyBackupSchedule
+type ValkeyBackupScheduleStatus struct {
+	// ObservedGeneration is a generation of the spec which status was built for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions are the latest observations of the schedule state
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Error will be filled if some occurs
+	Error string `json:"error,omitempty"`
+	// Active contains names of backups which aren't finished yet
+	// +optional
+	Active []string `json:"active,omitempty"`
+	// LastScheduleAt is a time the latest backup was scheduled at
+	LastScheduleAt *metav1.Time `json:"last_schedule_at,omitempty"`
+	// NextScheduleAt is a time the next backup is scheduled at
+	NextScheduleAt *metav1.Time `json:"next_schedule_at,omitempty"`
+	// LastSuccessAt is a time the latest backup was completed at
+	LastSuccessAt *metav1.Time `json:"last_success_at,omitempty"`
+	// LastFailureAt is a time the latest backup failed at
+	LastFailureAt *metav1.Time `json:"last_failure_at,omitempty"`
+	// Steps contains result of every step of the last reconcile in order they are run
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Steps []StepStatus `json:"steps,omitempty"`
+	// LastReconcileAt contains timestamp of the last reconcile
+	// only if something was changed
+	LastReconcileAt *metav1.Time `json:"last_reconcile_at,omitempty"`
+}
+
+// IsChanged compares statuses semantically, time of the last
+// reconcile is ignored, so saving it doesn't cause another write
+func (s *ValkeyBackupScheduleStatus) IsChanged(new *ValkeyBackupScheduleStatus) bool {
+	prev, next := s.DeepCopy(), new.DeepCopy()
+	prev.LastReconcileAt, next.LastReconcileAt = nil, nil
+
+	return !equality.Semantic.DeepEqual(prev, next)
+}
+
+// SetStep adds or updates result of the flow step
+func (s *ValkeyBackupScheduleStatus) SetStep(name string, state TypeStepState, message string) {
+	step := StepStatus{Name: name, State: state, Message: message}
+
+	i := slices.IndexFunc(s.Steps, func(v StepStatus) bool { return v.Name == name })
+	if i < 0 {
+		s.Steps = append(s.Steps, step)
+		return
+	}
+
+	s.Steps[i] = step
+}
+
+// SetLastReconcileAt saves time when changed status was written
+func (s *ValkeyBackupScheduleStatus) SetLastReconcileAt(t metav1.Time) {
+	s.LastReconcileAt = &t
+}
+
+// SetCondition adds or updates condition for observed generation,
+// transition time is kept while condition status is the same
+func (s *ValkeyBackupScheduleStatus) SetCondition(condType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&s.Conditions, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		ObservedGeneration: s.ObservedGeneration,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Schedule",type="string",JSONPath=".spec.schedule"
+//+kubebuilder:printcolumn:name="Suspend",type="boolean",JSONPath=".spec.suspend"
+//+kubebuilder:printcolumn:name="Last success",type="date",JSONPath=".status.last_success_at"
+//+kubebuilder:printcolumn:name="Last failure",type="date",JSONPath=".status.last_failure_at"
+//+kubebuilder:printcolumn:name="Next",type="date",JSONPath=".status.next_schedule_at"
+//+kubebuilder:printcolumn:name="Error",type="string",JSONPath=".status.error"
+
+// ValkeyBackupSchedule is the Schema for the valkeybackupschedules API,
+// it creates ValkeyBackup by cron schedule and prunes old ones
+type ValkeyBackupSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ValkeyBackupScheduleSpec   `json:"spec,omitempty"`
+	Status ValkeyBackupScheduleStatus `json:"status,omitempty"`
+}
+
+// GetStatus returns status of the schedule
+func (v *ValkeyBackupSchedule) GetStatus() *ValkeyBackupScheduleStatus {
+	return &v.Status
+}
+
+// SetStatus replaces status of the schedule
+func (v *ValkeyBackupSchedule) SetStatus(status *ValkeyBackupScheduleStatus) {
+	v.Status = *status
+}
+
+//+kubebuilder:object:root=true
+
+// ValkeyBackupScheduleList contains a list of ValkeyBackupSchedule
+type ValkeyBackupScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ValkeyBackupSchedule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ValkeyBackupSchedule{}, &ValkeyBackupScheduleList{})
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"strconv"
 
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -32,6 +33,25 @@ var CreateCmd = &cobra.Command{
 			log.Fatal(err)
 		}
 
+		mode := cmd.Flag("mode").Value.String()
+
+		var cluster *v1alpha1.Cluster
+		if v1alpha1.TypeMode(mode) == v1alpha1.TypeModeCluster {
+			shards, err := strconv.Atoi(cmd.Flag("shards").Value.String())
+			if err != nil {
+				log.Fatal(err)
+			}
+			replicasPerShard, err := strconv.Atoi(cmd.Flag("replicas_per_shard").Value.String())
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			cluster = &v1alpha1.Cluster{
+				Shards:           int32(shards),
+				ReplicasPerShard: int32(replicasPerShard),
+			}
+		}
+
 		dbUser := cmd.Flag("user").Value.String()
 		dbPass := cmd.Flag("pass").Value.String()
 
@@ -58,7 +78,9 @@ var CreateCmd = &cobra.Command{
 			},
 			Spec: v1alpha1.ValkeySpec{
 				Image:    image,
-				Replicas: int32(replicas),
+				Replicas: lo.ToPtr(int32(replicas)),
+				Mode:     v1alpha1.TypeMode(mode),
+				Cluster:  cluster,
 				User:     dbUser,
 				Password: dbPass,
 				Volume: v1alpha1.Volume{
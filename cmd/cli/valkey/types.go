@@ -11,6 +11,9 @@ func init() {
 	CreateCmd.Flags().String("user", "root", "admin user")
 	CreateCmd.Flags().String("pass", "root", "admin password")
 	CreateCmd.Flags().String("replicas", "1", "number of replicas")
+	CreateCmd.Flags().String("mode", "standalone", "standalone, replication or cluster")
+	CreateCmd.Flags().String("shards", "3", "number of shards in cluster mode")
+	CreateCmd.Flags().String("replicas_per_shard", "1", "number of replicas of every shard in cluster mode")
 	CreateCmd.Flags().String("volume_enabled", "true", "should have persistent volume")
 	CreateCmd.Flags().String("cpu", "200m", "resource cpu")
 	CreateCmd.Flags().String("memory", "512Mi", "resource memory")
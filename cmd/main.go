@@ -20,6 +20,7 @@ import (
 	"crypto/tls"
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -37,7 +38,12 @@ import (
 	alpha1api "github.com/uagolang/k8s-operator/api/v1alpha1"
 	"github.com/uagolang/k8s-operator/internal/controller"
 	"github.com/uagolang/k8s-operator/internal/controller/flows/valkey"
+	"github.com/uagolang/k8s-operator/internal/controller/flows/valkeybackup"
+	"github.com/uagolang/k8s-operator/internal/controller/flows/valkeybackupschedule"
+	"github.com/uagolang/k8s-operator/internal/lib/valkeyclient"
+	backupsvc "github.com/uagolang/k8s-operator/internal/services/backup"
 	valkeysvc "github.com/uagolang/k8s-operator/internal/services/valkey"
+	webhookv1alpha1 "github.com/uagolang/k8s-operator/internal/webhook/v1alpha1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -59,6 +65,7 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var resyncPeriod time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -68,11 +75,28 @@ func main() {
 		"If set the metrics endpoint is served securely")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.DurationVar(&resyncPeriod, "resync-period", 10*time.Minute,
+		"How often healthy Valkeys are reconciled without events, 0 disables periodic resync")
+	valkeyDefaults := webhookv1alpha1.NewValkeyDefaults()
+	flag.StringVar(&valkeyDefaults.Image, "default-image", valkeyDefaults.Image,
+		"Image of Valkey used when it's omitted in the spec")
+	var defaultReplicas int
+	flag.IntVar(&defaultReplicas, "default-replicas", int(valkeyDefaults.Replicas),
+		"Replicas count used when it's omitted in the spec")
+	flag.StringVar(&valkeyDefaults.CPU, "default-cpu", valkeyDefaults.CPU,
+		"CPU requirements used when they're omitted in the spec")
+	flag.StringVar(&valkeyDefaults.Memory, "default-memory", valkeyDefaults.Memory,
+		"Memory requirements used when they're omitted in the spec")
+	flag.StringVar(&valkeyDefaults.Storage, "default-storage", valkeyDefaults.Storage,
+		"Storage requirements used when they're omitted in the spec")
+	flag.StringVar(&valkeyDefaults.VolumeStorage, "default-volume-storage", valkeyDefaults.VolumeStorage,
+		"Persistent volume size used when it's omitted in the spec")
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
+	valkeyDefaults.Replicas = int32(defaultReplicas)
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
@@ -127,17 +151,56 @@ func main() {
 	k8sClient := mgr.GetClient()
 	flow := valkey.NewFlow(
 		valkey.WithK8sClient(k8sClient),
-		valkey.WithValkeySvc(valkeysvc.NewValkeyService(valkeysvc.WithK8sClient(k8sClient))),
+		valkey.WithValkeySvc(valkeysvc.NewValkeyService(
+			valkeysvc.WithK8sClient(k8sClient),
+			valkeysvc.WithValkeyClient(valkeyclient.New()),
+		)),
+		// the same defaults are applied when webhooks are disabled
+		valkey.WithDefaulter(&webhookv1alpha1.ValkeyDefaulter{Defaults: valkeyDefaults}),
 	)
 
 	if err = (&controller.ValkeyReconciler{
+		Client:       k8sClient,
+		Scheme:       mgr.GetScheme(),
+		Flow:         flow,
+		ResyncPeriod: resyncPeriod,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Valkey")
+		os.Exit(1)
+	}
+	backupSvc := backupsvc.NewBackupService(
+		backupsvc.WithK8sClient(k8sClient),
+		backupsvc.WithValkeyClient(valkeyclient.New()),
+	)
+	if err = (&controller.ValkeyBackupReconciler{
 		Client: k8sClient,
 		Scheme: mgr.GetScheme(),
-		Flow:   flow,
+		Flow: valkeybackup.NewFlow(
+			valkeybackup.WithK8sClient(k8sClient),
+			valkeybackup.WithBackupSvc(backupSvc),
+		),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Valkey")
+		setupLog.Error(err, "unable to create controller", "controller", "ValkeyBackup")
 		os.Exit(1)
 	}
+	if err = (&controller.ValkeyBackupScheduleReconciler{
+		Client: k8sClient,
+		Scheme: mgr.GetScheme(),
+		Flow: valkeybackupschedule.NewFlow(
+			valkeybackupschedule.WithK8sClient(k8sClient),
+			valkeybackupschedule.WithBackupSvc(backupSvc),
+		),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ValkeyBackupSchedule")
+		os.Exit(1)
+	}
+	// webhooks need serving certificates, disable them to run manager locally
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookv1alpha1.SetupValkeyWebhookWithManager(mgr, valkeyDefaults); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Valkey")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/uagolang/k8s-operator/internal/controller/flows"
+)
+
+// BackoffDelay is a delay after the first failure and its limit
+type BackoffDelay struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// defaultBackoffDelays are tuned per error class: transient errors are
+// retried fast, forbidden requests won't pass until RBAC or quota is changed
+var defaultBackoffDelays = map[flows.ErrorClass]BackoffDelay{
+	flows.ErrorClassTransient:  {Base: time.Second, Max: 5 * time.Minute},
+	flows.ErrorClassDependency: {Base: 5 * time.Second, Max: 2 * time.Minute},
+	flows.ErrorClassForbidden:  {Base: 30 * time.Second, Max: 30 * time.Minute},
+}
+
+// Backoff delays reconcile of objects which flow failed,
+// delay is doubled on every consecutive failure of the object.
+// Zero value uses defaultBackoffDelays
+type Backoff struct {
+	// Delays by error class
+	Delays map[flows.ErrorClass]BackoffDelay
+
+	mu       sync.Mutex
+	failures map[types.NamespacedName]int
+}
+
+// Next counts failure of the object and returns when it should be reconciled again
+func (b *Backoff) Next(key types.NamespacedName, class flows.ErrorClass) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures == nil {
+		b.failures = make(map[types.NamespacedName]int)
+	}
+	attempt := b.failures[key]
+	b.failures[key] = attempt + 1
+
+	delays := b.Delays
+	if delays == nil {
+		delays = defaultBackoffDelays
+	}
+	delay, ok := delays[class]
+	if !ok {
+		delay = defaultBackoffDelays[flows.ErrorClassTransient]
+	}
+
+	res := delay.Base
+	for i := 0; i < attempt && res < delay.Max; i++ {
+		res *= 2
+	}
+
+	return min(res, delay.Max)
+}
+
+// Reset forgets failures of the object after successful reconcile
+func (b *Backoff) Reset(key types.NamespacedName) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.failures, key)
+}
@@ -0,0 +1,69 @@
+package flows
+
+import (
+	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/uagolang/k8s-operator/internal/lib/validator"
+)
+
+// ErrorClass tells how flow error should be retried
+type ErrorClass string
+
+const (
+	// ErrorClassValidation is returned for spec which can't be applied,
+	// it isn't retried until the object is changed
+	ErrorClassValidation ErrorClass = "Validation"
+	// ErrorClassForbidden is returned when API server rejects a request
+	// because of RBAC or exceeded quota, it's retried slowly
+	ErrorClassForbidden ErrorClass = "Forbidden"
+	// ErrorClassDependency is returned when an object the flow depends on
+	// doesn't exist yet, e.g. referenced Secret
+	ErrorClassDependency ErrorClass = "DependencyNotReady"
+	// ErrorClassTransient is returned for any other error, e.g. conflict
+	// or unavailable API server, it's retried with exponential backoff
+	ErrorClassTransient ErrorClass = "Transient"
+)
+
+// Error is a flow error with its class
+type Error struct {
+	Class ErrorClass
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError marks error caused by invalid spec
+func ValidationError(err error) error {
+	return &Error{Class: ErrorClassValidation, Err: err}
+}
+
+// DependencyError marks error caused by missing object the flow depends on
+func DependencyError(err error) error {
+	return &Error{Class: ErrorClassDependency, Err: err}
+}
+
+// Classify returns class of the flow error, errors which
+// weren't marked by the flow are classified by their origin
+func Classify(err error) ErrorClass {
+	var flowErr *Error
+	switch {
+	case errors.As(err, &flowErr):
+		return flowErr.Class
+	case len(validator.GetErrors(err)) > 0,
+		k8serrors.IsInvalid(err),
+		k8serrors.IsBadRequest(err):
+		return ErrorClassValidation
+	case k8serrors.IsForbidden(err):
+		// exceeded resource quota is reported as forbidden too
+		return ErrorClassForbidden
+	default:
+		return ErrorClassTransient
+	}
+}
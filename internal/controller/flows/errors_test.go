@@ -0,0 +1,60 @@
+package flows_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/uagolang/k8s-operator/internal/controller/flows"
+	"github.com/uagolang/k8s-operator/internal/lib/validator"
+)
+
+func TestClassify(t *testing.T) {
+	resource := schema.GroupResource{Resource: "statefulsets"}
+	mockErr := errors.New("mock error")
+
+	tests := []struct {
+		name string
+		err  error
+		want flows.ErrorClass
+	}{
+		{
+			name: "marked by flow",
+			err:  errors.Wrap(flows.DependencyError(mockErr), "resources"),
+			want: flows.ErrorClassDependency,
+		},
+		{
+			name: "request validation",
+			err:  validator.Errors{{Field: "image", Message: "image is a required field"}},
+			want: flows.ErrorClassValidation,
+		},
+		{
+			name: "invalid object",
+			err:  k8serrors.NewInvalid(schema.GroupKind{Kind: "StatefulSet"}, "valkey", nil),
+			want: flows.ErrorClassValidation,
+		},
+		{
+			name: "exceeded quota",
+			err:  k8serrors.NewForbidden(resource, "valkey", errors.New("exceeded quota")),
+			want: flows.ErrorClassForbidden,
+		},
+		{
+			name: "conflict",
+			err:  k8serrors.NewConflict(resource, "valkey", mockErr),
+			want: flows.ErrorClassTransient,
+		},
+		{
+			name: "unknown",
+			err:  mockErr,
+			want: flows.ErrorClassTransient,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, flows.Classify(tt.err))
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package flows
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+)
+
+// StepStatus is a status which keeps result of every step
+type StepStatus interface {
+	SetStep(name string, state v1alpha1.TypeStepState, message string)
+}
+
+// Result is returned by step which finished without error
+type Result struct {
+	// Wait stops the flow until the next reconcile,
+	// e.g. when pods aren't ready yet
+	Wait bool
+	// Message describes result of the step
+	Message string
+}
+
+// Done returns result of the step which has nothing more to do
+func Done(message string) Result {
+	return Result{Message: message}
+}
+
+// Wait returns result of the step which waits for child objects
+func Wait(message string) Result {
+	return Result{Wait: true, Message: message}
+}
+
+// Step is a named part of the flow, it reads previous steps results
+// from the status and writes its own there
+type Step[T client.Object, S StepStatus] struct {
+	Name string
+	Run  func(ctx context.Context, item T, status S) (Result, error)
+}
+
+// Steps are run one by one until one of them fails or waits,
+// steps which weren't run are reported as pending
+type Steps[T client.Object, S StepStatus] []Step[T, S]
+
+// Run returns true when every step was done
+func (s Steps[T, S]) Run(ctx context.Context, item T, status S) (bool, error) {
+	for i, step := range s {
+		res, err := step.Run(ctx, item, status)
+		if err != nil {
+			status.SetStep(step.Name, v1alpha1.TypeStepStateFailed, err.Error())
+			s[i+1:].setPending(status)
+			return false, &StepError{Step: step.Name, Err: err}
+		}
+
+		if res.Wait {
+			status.SetStep(step.Name, v1alpha1.TypeStepStateWaiting, res.Message)
+			s[i+1:].setPending(status)
+			return false, nil
+		}
+
+		status.SetStep(step.Name, v1alpha1.TypeStepStateDone, res.Message)
+	}
+
+	return true, nil
+}
+
+func (s Steps[T, S]) setPending(status S) {
+	for _, step := range s {
+		status.SetStep(step.Name, v1alpha1.TypeStepStatePending, "")
+	}
+}
+
+// StepError is returned by Steps when one of them fails,
+// so failed step can be reported together with the error
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return e.Step + ": " + e.Err.Error()
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,90 @@
+package flows_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/controller/flows"
+)
+
+func TestSteps_Run(t *testing.T) {
+	ctx := context.Background()
+	mockErr := errors.New("mock error")
+
+	step := func(name string, res flows.Result, err error) flows.Step[*v1alpha1.Valkey, *v1alpha1.ValkeyStatus] {
+		return flows.Step[*v1alpha1.Valkey, *v1alpha1.ValkeyStatus]{
+			Name: name,
+			Run: func(context.Context, *v1alpha1.Valkey, *v1alpha1.ValkeyStatus) (flows.Result, error) {
+				return res, err
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		steps    flows.Steps[*v1alpha1.Valkey, *v1alpha1.ValkeyStatus]
+		wantDone bool
+		wantErr  error
+		want     []v1alpha1.StepStatus
+	}{
+		{
+			name: "every step is done",
+			steps: flows.Steps[*v1alpha1.Valkey, *v1alpha1.ValkeyStatus]{
+				step("first", flows.Done("ok"), nil),
+				step("second", flows.Done(""), nil),
+			},
+			wantDone: true,
+			want: []v1alpha1.StepStatus{
+				{Name: "first", State: v1alpha1.TypeStepStateDone, Message: "ok"},
+				{Name: "second", State: v1alpha1.TypeStepStateDone},
+			},
+		},
+		{
+			name: "waiting step stops the flow",
+			steps: flows.Steps[*v1alpha1.Valkey, *v1alpha1.ValkeyStatus]{
+				step("first", flows.Wait("pods are started"), nil),
+				step("second", flows.Done(""), nil),
+			},
+			want: []v1alpha1.StepStatus{
+				{Name: "first", State: v1alpha1.TypeStepStateWaiting, Message: "pods are started"},
+				{Name: "second", State: v1alpha1.TypeStepStatePending},
+			},
+		},
+		{
+			name: "failed step stops the flow",
+			steps: flows.Steps[*v1alpha1.Valkey, *v1alpha1.ValkeyStatus]{
+				step("first", flows.Done(""), nil),
+				step("second", flows.Result{}, mockErr),
+				step("third", flows.Done(""), nil),
+			},
+			wantErr: mockErr,
+			want: []v1alpha1.StepStatus{
+				{Name: "first", State: v1alpha1.TypeStepStateDone},
+				{Name: "second", State: v1alpha1.TypeStepStateFailed, Message: mockErr.Error()},
+				{Name: "third", State: v1alpha1.TypeStepStatePending},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := &v1alpha1.ValkeyStatus{}
+
+			done, err := tt.steps.Run(ctx, &v1alpha1.Valkey{}, status)
+			require.Equal(t, tt.wantDone, done)
+			require.Equal(t, tt.want, status.Steps)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, tt.wantErr)
+			var stepErr *flows.StepError
+			require.ErrorAs(t, err, &stepErr)
+			require.Equal(t, "second", stepErr.Step)
+		})
+	}
+}
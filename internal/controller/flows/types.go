@@ -2,18 +2,15 @@ package flows
 
 import (
 	"context"
-	"errors"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-var (
-	ErrInvalidInputType  = errors.New("invalid input type")
-	ErrInvalidOutputType = errors.New("invalid output type")
-)
-
-type Flow interface {
-	Run(ctx context.Context, item any) (status any, finalizers []string, err error)
+// Flow brings child objects of the item to the desired state
+// and returns status and finalizers which should be saved on it
+type Flow[T client.Object, S any] interface {
+	Run(ctx context.Context, item T) (status S, finalizers []string, err error)
 }
 
 var FakeComponents = []runtime.Object{}
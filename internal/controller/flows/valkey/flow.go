@@ -2,9 +2,17 @@ package valkey
 
 import (
 	"context"
+	"slices"
+	"strings"
 
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"github.com/uagolang/k8s-operator/api/v1alpha1"
 	"github.com/uagolang/k8s-operator/internal/controller/flows"
@@ -14,16 +22,29 @@ import (
 type FlowImpl struct {
 	k8sClient client.Client
 	valkeySvc valkeysvc.Service
+	defaulter webhook.CustomDefaulter
+
+	steps flows.Steps[*v1alpha1.Valkey, *v1alpha1.ValkeyStatus]
 }
 
 type ImplOption func(r *FlowImpl)
 
-func NewFlow(opts ...ImplOption) flows.Flow {
+func NewFlow(opts ...ImplOption) flows.Flow[*v1alpha1.Valkey, *v1alpha1.ValkeyStatus] {
 	res := new(FlowImpl)
 	for _, opt := range opts {
 		opt(res)
 	}
 
+	res.steps = flows.Steps[*v1alpha1.Valkey, *v1alpha1.ValkeyStatus]{
+		{Name: StepResources, Run: res.ensureResources},
+		{Name: StepStorage, Run: res.ensureStorage},
+		{Name: StepPods, Run: res.ensurePods},
+		{Name: StepTLS, Run: res.ensureCertificates},
+		{Name: StepConfig, Run: res.ensureConfig},
+		{Name: StepTopology, Run: res.ensureTopology},
+		{Name: StepHealth, Run: res.verifyHealth},
+	}
+
 	return res
 }
 
@@ -39,81 +60,92 @@ func WithValkeySvc(v valkeysvc.Service) ImplOption {
 	}
 }
 
-func (r *FlowImpl) Run(ctx context.Context, input any) (any, []string, error) {
-	item, ok := input.(v1alpha1.Valkey)
-	if !ok {
-		return nil, nil, flows.ErrInvalidInputType
+// WithDefaulter fills omitted fields before the flow is run,
+// so instances are reconciled when defaulting webhook is disabled
+func WithDefaulter(v webhook.CustomDefaulter) ImplOption {
+	return func(r *FlowImpl) {
+		r.defaulter = v
 	}
+}
 
+func (r *FlowImpl) Run(ctx context.Context, item *v1alpha1.Valkey) (*v1alpha1.ValkeyStatus, []string, error) {
 	logger := log.FromContext(ctx).WithValues("flow", "valkey", "crd_name", item.Name, "finalizers", len(item.Finalizers))
-	log.IntoContext(ctx, logger)
+	ctx = log.IntoContext(ctx, logger)
+
+	if r.defaulter != nil {
+		// spec stored by webhook is already defaulted, copy is defaulted
+		// in memory, so stored object isn't changed by the flow
+		item = item.DeepCopy()
+		if err := r.defaulter.Default(ctx, item); err != nil {
+			return nil, nil, err
+		}
+	}
 
-	res := new(v1alpha1.ValkeyStatus)
+	res := &v1alpha1.ValkeyStatus{
+		ObservedGeneration: item.Generation,
+		Phase:              item.Status.Phase,
+		Primary:            item.Status.Primary,
+		// previous conditions keep their transition time
+		Conditions: slices.Clone(item.Status.Conditions),
+		Steps:      slices.Clone(item.Status.Steps),
+	}
+
+	if meta.FindStatusCondition(res.Conditions, v1alpha1.ConditionPaused) != nil {
+		res.SetCondition(v1alpha1.ConditionPaused, metav1.ConditionFalse, v1alpha1.ReasonResumed, "reconcile is resumed")
+	}
 
 	if !item.DeletionTimestamp.IsZero() { // should be deleted
-		if len(item.Finalizers) > 0 {
+		res.Phase = v1alpha1.TypePhaseDeleting
+		if controllerutil.ContainsFinalizer(item, Finalizer) {
 			err := r.valkeySvc.Delete(ctx, &valkeysvc.DeleteRequest{
-				Name:      item.Name,
-				Namespace: item.Namespace,
+				Name:           item.Name,
+				Namespace:      item.Namespace,
+				UID:            item.UID,
+				DeletionPolicy: item.Spec.DeletionPolicy,
 			})
+			if errors.Is(err, valkeysvc.ErrSnapshotNotReady) {
+				// finalizer keeps claims until snapshots are taken
+				res.SetCondition(v1alpha1.ConditionReady, metav1.ConditionFalse, v1alpha1.ReasonDependencyNotReady, err.Error())
+				return res, item.Finalizers, nil
+			}
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, classify(err)
 			}
 		}
 
 		logger.Info("valkey resources were successfully deleted")
 
-		return res, []string{}, nil
-	}
-
-	if len(item.Finalizers) == 0 { // save finalizers
-		err := r.valkeySvc.Create(ctx, &valkeysvc.CreateRequest{
-			CrdName:   item.Name,
-			Namespace: item.Namespace,
-			Image:     item.Spec.Image,
-			User:      item.Spec.User,
-			Password:  item.Spec.Password,
-			Replicas:  item.Spec.Replicas,
-			Volume:    item.Spec.Volume,
-			Resource:  item.Spec.Resource,
-		})
-		if err != nil {
-			return nil, nil, err
-		}
-
-		res.Status = v1alpha1.TypeStatusUpdating
-
-		return res, []string{Finalizer}, nil
+		return res, lo.Without(item.Finalizers, Finalizer), nil
 	}
 
-	err := r.valkeySvc.Update(ctx, &valkeysvc.UpdateRequest{
-		CrdName:   item.Name,
-		Namespace: item.Namespace,
-		Image:     &item.Spec.Image,
-		User:      &item.Spec.User,
-		Password:  &item.Spec.Password,
-		Replicas:  &item.Spec.Replicas,
-		Volume:    &item.Spec.Volume,
-		Resource:  &item.Spec.Resource,
-	})
-	if err != nil {
+	if _, err := r.steps.Run(ctx, item, res); err != nil {
 		return nil, nil, err
 	}
 
-	ready, readyReplicas, err := r.valkeySvc.IsReady(ctx, &valkeysvc.IsReadyRequest{
-		Name:      item.Name,
-		Namespace: item.Namespace,
-	})
-	if err != nil {
-		return nil, nil, err
+	// finalizer is saved after the first step created resources,
+	// finalizers of others are kept
+	return res, lo.Union(item.Finalizers, []string{Finalizer}), nil
+}
+
+// setDriftCondition reports objects where manual changes were reverted,
+// reported drift is kept until the spec is changed, so it isn't lost
+// on the reconcile triggered by the revert itself
+func setDriftCondition(res *v1alpha1.ValkeyStatus, drifted []string, generation int64) {
+	if len(drifted) > 0 {
+		res.SetCondition(v1alpha1.ConditionDriftDetected, metav1.ConditionTrue, v1alpha1.ReasonDriftCorrected,
+			"manual changes were reverted: "+strings.Join(drifted, ", "))
+		return
 	}
-	if !ready || readyReplicas == 0 {
-		res.Status = v1alpha1.TypeStatusStopped
-		return res, []string{}, nil
+
+	cond := meta.FindStatusCondition(res.Conditions, v1alpha1.ConditionDriftDetected)
+	if cond != nil && cond.Status == metav1.ConditionTrue && cond.ObservedGeneration == generation {
+		return
 	}
 
-	res.ReadyReplicas = readyReplicas
-	res.Status = v1alpha1.TypeStatusHealthy
+	res.SetCondition(v1alpha1.ConditionDriftDetected, metav1.ConditionFalse, v1alpha1.ReasonInSync, "managed objects match desired state")
+}
 
-	return res, item.Finalizers, nil
+// ownerReference makes Valkey the controller of its child objects
+func ownerReference(item *v1alpha1.Valkey) *metav1.OwnerReference {
+	return metav1.NewControllerRef(item, v1alpha1.GroupVersion.WithKind("Valkey"))
 }
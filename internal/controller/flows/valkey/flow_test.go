@@ -3,16 +3,25 @@ package valkey_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
 
 	databasev1alpha1 "github.com/uagolang/k8s-operator/api/v1alpha1"
 	"github.com/uagolang/k8s-operator/internal/controller/flows"
 	"github.com/uagolang/k8s-operator/internal/controller/flows/valkey"
+	valkeysvc "github.com/uagolang/k8s-operator/internal/services/valkey"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
+	webhookv1alpha1 "github.com/uagolang/k8s-operator/internal/webhook/v1alpha1"
 	"github.com/uagolang/k8s-operator/mocks"
 )
 
@@ -38,7 +47,7 @@ func TestFlowRun(t *testing.T) {
 	t.Run("create resources error", func(t *testing.T) {
 		mockValkeySvc.EXPECT().Create(gomock.Any(), gomock.Any()).Return(mockErr)
 
-		status, finalizers, err := flow.Run(ctx, databasev1alpha1.Valkey{
+		status, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      resourceName,
 				Namespace: defaultNamespace,
@@ -46,35 +55,55 @@ func TestFlowRun(t *testing.T) {
 		})
 		require.Nil(t, status)
 		require.Nil(t, finalizers)
-		require.Error(t, err)
+		require.ErrorIs(t, err, mockErr)
+
+		var stepErr *flows.StepError
+		require.ErrorAs(t, err, &stepErr)
+		require.Equal(t, valkey.StepResources, stepErr.Step)
 	})
 
 	t.Run("create resources with finalizers", func(t *testing.T) {
-		mockValkeySvc.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+		mockValkeySvc.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, i *valkeysvc.CreateRequest) error {
+				// child objects are owned by CRD and removed by garbage collector
+				require.Equal(t, "Valkey", i.Owner.Kind)
+				require.Equal(t, types.UID("valkey-uid"), i.Owner.UID)
+				require.True(t, *i.Owner.Controller)
+				return nil
+			})
 
-		_, finalizers, err := flow.Run(ctx, databasev1alpha1.Valkey{
+		status, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      resourceName,
 				Namespace: defaultNamespace,
+				UID:       "valkey-uid",
 			},
 		})
 		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.TypePhaseCreating, status.Phase)
 		require.Len(t, finalizers, 1)
 		require.Equal(t, finalizers[0], valkey.Finalizer)
 	})
 
-	t.Run("invalid resource type", func(t *testing.T) {
-		status, finalizers, err := flow.Run(ctx, &databasev1alpha1.ValkeyStatus{})
-		require.Nil(t, status)
-		require.Nil(t, finalizers)
-		require.Error(t, err)
-		require.Equal(t, flows.ErrInvalidInputType, err)
+	t.Run("create resources with finalizers of others", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+
+		status, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{"other"},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.TypePhaseCreating, status.Phase)
+		require.Equal(t, []string{"other", valkey.Finalizer}, finalizers)
 	})
 
 	t.Run("update resources error", func(t *testing.T) {
-		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(mockErr)
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, mockErr)
 
-		status, finalizers, err := flow.Run(ctx, databasev1alpha1.Valkey{
+		status, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:       resourceName,
 				Namespace:  defaultNamespace,
@@ -86,11 +115,59 @@ func TestFlowRun(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("waits for migration from deployment", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).
+			Return(nil, fmt.Errorf("deployment test-resource is scaled down: %w", valkeysvc.ErrMigrationInProgress))
+
+		status, _, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.TypeStepStateWaiting, status.Steps[0].State)
+	})
+
+	t.Run("missing password secret is dependency error", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, valkeysvc.ErrPasswordSecretNotFound)
+
+		_, _, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+		})
+		require.ErrorIs(t, err, valkeysvc.ErrPasswordSecretNotFound)
+		require.Equal(t, flows.ErrorClassDependency, flows.Classify(err))
+	})
+
+	t.Run("restore source is passed on create", func(t *testing.T) {
+		restoreFrom := &databasev1alpha1.RestoreSource{BackupName: "nightly"}
+		mockValkeySvc.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, i *valkeysvc.CreateRequest) error {
+				require.Equal(t, restoreFrom, i.RestoreFrom)
+				return fmt.Errorf("backup nightly is copying: %w", valkeysvc.ErrRestoreSourceNotReady)
+			})
+
+		_, _, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      resourceName,
+				Namespace: defaultNamespace,
+			},
+			Spec: databasev1alpha1.ValkeySpec{RestoreFrom: restoreFrom},
+		})
+		require.ErrorIs(t, err, valkeysvc.ErrRestoreSourceNotReady)
+		require.Equal(t, flows.ErrorClassDependency, flows.Classify(err))
+	})
+
 	t.Run("healthcheck error", func(t *testing.T) {
-		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, nil)
 		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(false, int32(0), mockErr)
 
-		status, finalizers, err := flow.Run(ctx, databasev1alpha1.Valkey{
+		status, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:       resourceName,
 				Namespace:  defaultNamespace,
@@ -103,45 +180,531 @@ func TestFlowRun(t *testing.T) {
 	})
 
 	t.Run("not ready", func(t *testing.T) {
-		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, nil)
 		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(false, int32(0), nil)
 
-		status, finalizers, err := flow.Run(ctx, databasev1alpha1.Valkey{
+		status, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:       resourceName,
 				Namespace:  defaultNamespace,
 				Finalizers: []string{valkey.Finalizer},
 			},
+			Status: databasev1alpha1.ValkeyStatus{Phase: databasev1alpha1.TypePhaseCreating},
 		})
-		require.NotNil(t, status)
-		require.Len(t, finalizers, 0)
 		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.TypePhaseProvisioning, status.Phase)
+		// finalizer is kept while pods are started
+		require.Equal(t, []string{valkey.Finalizer}, finalizers)
+		require.Equal(t, []databasev1alpha1.StepStatus{
+			{Name: valkey.StepResources, State: databasev1alpha1.TypeStepStateDone, Message: "resources are up to date"},
+			{Name: valkey.StepStorage, State: databasev1alpha1.TypeStepStateDone, Message: "persistent volume is disabled"},
+			{Name: valkey.StepPods, State: databasev1alpha1.TypeStepStateWaiting, Message: "0 of 1 replicas are ready"},
+			{Name: valkey.StepTLS, State: databasev1alpha1.TypeStepStatePending},
+			{Name: valkey.StepConfig, State: databasev1alpha1.TypeStepStatePending},
+			{Name: valkey.StepTopology, State: databasev1alpha1.TypeStepStatePending},
+			{Name: valkey.StepHealth, State: databasev1alpha1.TypeStepStatePending},
+		}, status.Steps)
 	})
 
 	t.Run("success reconcile", func(t *testing.T) {
-		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, nil)
 		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(1), nil)
 
-		status, finalizers, err := flow.Run(ctx, databasev1alpha1.Valkey{
+		st, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:       resourceName,
 				Namespace:  defaultNamespace,
 				Finalizers: []string{valkey.Finalizer},
+				Generation: 2,
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				Replicas: lo.ToPtr(int32(1)),
 			},
 		})
-		require.Equal(t, &databasev1alpha1.ValkeyStatus{
-			Status:        databasev1alpha1.TypeStatusHealthy,
-			ReadyReplicas: 1,
-		}, status)
+		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.TypeStatusHealthy, st.Status)
+		require.Equal(t, databasev1alpha1.TypePhaseRunning, st.Phase)
+		require.Equal(t, int32(1), st.ReadyReplicas)
+		require.Equal(t, int64(2), st.ObservedGeneration)
+		require.True(t, meta.IsStatusConditionTrue(st.Conditions, databasev1alpha1.ConditionReady))
+		require.True(t, meta.IsStatusConditionFalse(st.Conditions, databasev1alpha1.ConditionProgressing))
+		require.True(t, meta.IsStatusConditionFalse(st.Conditions, databasev1alpha1.ConditionDegraded))
+		require.True(t, meta.IsStatusConditionTrue(st.Conditions, databasev1alpha1.ConditionSecretReady))
+		require.True(t, meta.IsStatusConditionTrue(st.Conditions, databasev1alpha1.ConditionStorageReady))
+		require.True(t, meta.IsStatusConditionFalse(st.Conditions, databasev1alpha1.ConditionDriftDetected))
+		require.Equal(t, int64(2), meta.FindStatusCondition(st.Conditions, databasev1alpha1.ConditionReady).ObservedGeneration)
+		require.True(t, lo.EveryBy(st.Steps, func(s databasev1alpha1.StepStatus) bool {
+			return s.State == databasev1alpha1.TypeStepStateDone
+		}))
+		require.Len(t, st.Steps, 7)
+		require.Nil(t, meta.FindStatusCondition(st.Conditions, databasev1alpha1.ConditionCertificateReady))
 		require.Len(t, finalizers, 1)
 		require.Equal(t, finalizers[0], valkey.Finalizer)
+	})
+
+	t.Run("reverted drift is reported", func(t *testing.T) {
+		item := &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+				Generation: 2,
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				Replicas: lo.ToPtr(int32(1)),
+			},
+		}
+
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return([]string{"StatefulSet/" + resourceName}, nil)
+		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(1), nil)
+
+		st, _, err := flow.Run(ctx, item)
+		require.NoError(t, err)
+		cond := meta.FindStatusCondition(st.Conditions, databasev1alpha1.ConditionDriftDetected)
+		require.Equal(t, metav1.ConditionTrue, cond.Status)
+		require.Equal(t, databasev1alpha1.ReasonDriftCorrected, cond.Reason)
+		require.Contains(t, cond.Message, "StatefulSet/"+resourceName)
+
+		// drift is kept on reconcile triggered by the revert
+		item.Status = *st
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, nil)
+		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(1), nil)
+
+		st, _, err = flow.Run(ctx, item)
+		require.NoError(t, err)
+		require.True(t, meta.IsStatusConditionTrue(st.Conditions, databasev1alpha1.ConditionDriftDetected))
+
+		// and cleared when spec is changed
+		item.Generation = 3
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, nil)
+		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(1), nil)
+
+		st, _, err = flow.Run(ctx, item)
+		require.NoError(t, err)
+		require.True(t, meta.IsStatusConditionFalse(st.Conditions, databasev1alpha1.ConditionDriftDetected))
+	})
+
+	t.Run("resumed after pause", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, nil)
+		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(1), nil)
+
+		st, _, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				Replicas: lo.ToPtr(int32(1)),
+			},
+			Status: databasev1alpha1.ValkeyStatus{
+				Conditions: []metav1.Condition{{
+					Type:   databasev1alpha1.ConditionPaused,
+					Status: metav1.ConditionTrue,
+					Reason: databasev1alpha1.ReasonPaused,
+				}},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.ReasonResumed,
+			meta.FindStatusCondition(st.Conditions, databasev1alpha1.ConditionPaused).Reason)
+		require.True(t, meta.IsStatusConditionFalse(st.Conditions, databasev1alpha1.ConditionPaused))
+	})
+
+	t.Run("storage is not bound", func(t *testing.T) {
+		transitionTime := metav1.NewTime(time.Now().Add(-time.Hour))
+
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, nil)
+		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(1), nil)
+		mockValkeySvc.EXPECT().ResizeStorage(gomock.Any(), gomock.Any()).Return(false, nil)
+		mockValkeySvc.EXPECT().IsStorageReady(gomock.Any(), &valkeysvc.IsReadyRequest{
+			Name:      resourceName,
+			Namespace: defaultNamespace,
+		}).Return(false, nil)
+
+		st, _, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				Replicas: lo.ToPtr(int32(2)),
+				Volume:   databasev1alpha1.Volume{Enabled: true},
+			},
+			Status: databasev1alpha1.ValkeyStatus{
+				Conditions: []metav1.Condition{{
+					Type:               databasev1alpha1.ConditionReady,
+					Status:             metav1.ConditionTrue,
+					Reason:             databasev1alpha1.ReasonReconciled,
+					LastTransitionTime: transitionTime,
+				}},
+			},
+		})
+		require.NoError(t, err)
+		require.True(t, meta.IsStatusConditionFalse(st.Conditions, databasev1alpha1.ConditionStorageReady))
+		require.Equal(t, databasev1alpha1.TypePhaseProvisioning, st.Phase)
+		require.True(t, meta.IsStatusConditionTrue(st.Conditions, databasev1alpha1.ConditionDegraded))
+		require.True(t, meta.IsStatusConditionTrue(st.Conditions, databasev1alpha1.ConditionProgressing))
+		// ready condition didn't change its status
+		require.Equal(t, transitionTime, meta.FindStatusCondition(st.Conditions, databasev1alpha1.ConditionReady).LastTransitionTime)
+	})
+
+	t.Run("storage is resized", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, nil)
+		mockValkeySvc.EXPECT().ResizeStorage(gomock.Any(), &valkeysvc.ResizeStorageRequest{
+			Name:      resourceName,
+			Namespace: defaultNamespace,
+			Storage:   "2Gi",
+		}).Return(true, nil)
+		mockValkeySvc.EXPECT().IsStorageReady(gomock.Any(), gomock.Any()).Return(true, nil)
+		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(1), nil)
+
+		st, _, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				Replicas: lo.ToPtr(int32(1)),
+				Volume:   databasev1alpha1.Volume{Enabled: true, Storage: "2Gi"},
+				Resource: databasev1alpha1.Resource{Storage: "1Gi"},
+			},
+		})
+		require.NoError(t, err)
+		require.True(t, meta.IsStatusConditionTrue(st.Conditions, databasev1alpha1.ConditionStorageResizing))
+		// instance keeps running while claims are expanded
+		require.Equal(t, databasev1alpha1.TypePhaseRunning, st.Phase)
+	})
+
+	t.Run("storage can't be resized", func(t *testing.T) {
+		for err, reason := range map[error]string{
+			valkeysvc.ErrStorageShrink:         databasev1alpha1.ReasonShrinkNotSupported,
+			valkeysvc.ErrExpansionNotSupported: databasev1alpha1.ReasonExpansionNotSupported,
+		} {
+			mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, nil)
+			mockValkeySvc.EXPECT().ResizeStorage(gomock.Any(), gomock.Any()).Return(false, fmt.Errorf("data-valkey-0: %w", err))
+			mockValkeySvc.EXPECT().IsStorageReady(gomock.Any(), gomock.Any()).Return(true, nil)
+			mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(1), nil)
+
+			st, _, flowErr := flow.Run(ctx, &databasev1alpha1.Valkey{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       resourceName,
+					Namespace:  defaultNamespace,
+					Finalizers: []string{valkey.Finalizer},
+				},
+				Spec: databasev1alpha1.ValkeySpec{
+					Replicas: lo.ToPtr(int32(1)),
+					Volume:   databasev1alpha1.Volume{Enabled: true, Storage: "1Gi"},
+				},
+			})
+			require.NoError(t, flowErr)
+			require.True(t, meta.IsStatusConditionFalse(st.Conditions, databasev1alpha1.ConditionStorageResizing))
+			require.Equal(t, reason, meta.FindStatusCondition(st.Conditions, databasev1alpha1.ConditionStorageResizing).Reason)
+		}
+	})
+
+	t.Run("resize storage error", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, nil)
+		mockValkeySvc.EXPECT().ResizeStorage(gomock.Any(), gomock.Any()).Return(false, mockErr)
+
+		_, _, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				Volume: databasev1alpha1.Volume{Enabled: true, Storage: "1Gi"},
+			},
+		})
+		require.ErrorIs(t, err, mockErr)
+	})
+
+	t.Run("success reconcile in replication mode", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, req *valkeysvc.UpdateRequest) ([]string, error) {
+				require.Equal(t, "test-resource-1", req.Primary)
+				return nil, nil
+			})
+		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(2), nil)
+		mockValkeySvc.EXPECT().SyncReplication(gomock.Any(), &valkeysvc.SyncReplicationRequest{
+			CrdName:   resourceName,
+			Namespace: defaultNamespace,
+			Primary:   "test-resource-1",
+		}).Return("test-resource-1", nil)
+
+		st, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				Mode: databasev1alpha1.TypeModeReplication,
+			},
+			Status: databasev1alpha1.ValkeyStatus{
+				Primary: "test-resource-1",
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.TypeStatusHealthy, st.Status)
+		require.Equal(t, int32(2), st.ReadyReplicas)
+		require.Equal(t, "test-resource-1", st.Primary)
+		require.True(t, meta.IsStatusConditionTrue(st.Conditions, databasev1alpha1.ConditionReady))
+		require.Len(t, finalizers, 1)
+	})
+
+	t.Run("sync replication error", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, nil)
+		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(2), nil)
+		mockValkeySvc.EXPECT().SyncReplication(gomock.Any(), gomock.Any()).Return("", mockErr)
+
+		status, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				Mode: databasev1alpha1.TypeModeReplication,
+			},
+		})
+		require.Nil(t, status)
+		require.Nil(t, finalizers)
+		require.Error(t, err)
+	})
+
+	t.Run("rebalancing in cluster mode", func(t *testing.T) {
+		shards := []databasev1alpha1.ShardStatus{
+			{Index: 0, Status: databasev1alpha1.TypeStatusHealthy, Primary: "test-resource-0", Slots: "0-8191"},
+			{Index: 1, Status: databasev1alpha1.TypeStatusUpdating, Primary: "test-resource-1", Slots: "8192-16383"},
+		}
+
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, nil)
+		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(3), nil)
+		mockValkeySvc.EXPECT().SyncCluster(gomock.Any(), &valkeysvc.SyncClusterRequest{
+			CrdName:   resourceName,
+			Namespace: defaultNamespace,
+			Shards:    3,
+		}).Return(shards, nil)
+
+		st, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				Mode:    databasev1alpha1.TypeModeCluster,
+				Cluster: &databasev1alpha1.Cluster{Shards: 3},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.TypeStatusUpdating, st.Status)
+		require.Equal(t, databasev1alpha1.TypePhaseConfiguring, st.Phase)
+		require.Equal(t, int32(3), st.ReadyReplicas)
+		require.Equal(t, shards, st.Shards)
+		require.True(t, meta.IsStatusConditionTrue(st.Conditions, databasev1alpha1.ConditionProgressing))
+		require.Equal(t, databasev1alpha1.ReasonRebalancing,
+			meta.FindStatusCondition(st.Conditions, databasev1alpha1.ConditionProgressing).Reason)
+		require.Len(t, finalizers, 1)
+	})
+
+	t.Run("sync cluster error", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, nil)
+		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(3), nil)
+		mockValkeySvc.EXPECT().SyncCluster(gomock.Any(), gomock.Any()).Return(nil, mockErr)
+
+		status, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				Mode:    databasev1alpha1.TypeModeCluster,
+				Cluster: &databasev1alpha1.Cluster{Shards: 3},
+			},
+		})
+		require.Nil(t, status)
+		require.Nil(t, finalizers)
+		require.Error(t, err)
+	})
+
+	t.Run("tls is passed and certificate is reloading", func(t *testing.T) {
+		tls := &databasev1alpha1.TLS{Enabled: true, SecretName: "certs"}
+
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, req *valkeysvc.UpdateRequest) ([]string, error) {
+				require.Equal(t, tls, req.TLS)
+				return nil, nil
+			})
+		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(1), nil)
+		mockValkeySvc.EXPECT().ReloadTLS(gomock.Any(), &valkeysvc.ReloadTLSRequest{
+			CrdName:   resourceName,
+			Namespace: defaultNamespace,
+		}).Return(false, nil)
+
+		st, _, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				Replicas: lo.ToPtr(int32(1)),
+				TLS:      tls,
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.TypeStatusHealthy, st.Status)
+		require.True(t, meta.IsStatusConditionFalse(st.Conditions, databasev1alpha1.ConditionCertificateReady))
+		require.Equal(t, databasev1alpha1.ReasonCertificateReloading,
+			meta.FindStatusCondition(st.Conditions, databasev1alpha1.ConditionCertificateReady).Reason)
+	})
+
+	t.Run("certificate is loaded", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, nil)
+		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(1), nil)
+		mockValkeySvc.EXPECT().ReloadTLS(gomock.Any(), gomock.Any()).Return(true, nil)
+
+		st, _, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				Replicas: lo.ToPtr(int32(1)),
+				TLS:      &databasev1alpha1.TLS{Enabled: true, SecretName: "certs"},
+			},
+		})
 		require.NoError(t, err)
+		require.True(t, meta.IsStatusConditionTrue(st.Conditions, databasev1alpha1.ConditionCertificateReady))
+	})
+
+	t.Run("missing tls secret is dependency error", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil, nil)
+		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(1), nil)
+		mockValkeySvc.EXPECT().ReloadTLS(gomock.Any(), gomock.Any()).
+			Return(false, fmt.Errorf("default/certs: %w", valkeysvc.ErrTLSSecretNotFound))
+
+		_, _, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				Replicas: lo.ToPtr(int32(1)),
+				TLS:      &databasev1alpha1.TLS{Enabled: true, SecretName: "certs"},
+			},
+		})
+		require.ErrorIs(t, err, valkeysvc.ErrTLSSecretNotFound)
+		require.Equal(t, flows.ErrorClassDependency, flows.Classify(err))
+	})
+
+	t.Run("config is passed and applied", func(t *testing.T) {
+		config := map[string]string{"maxmemory-policy": "allkeys-lru"}
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, req *valkeysvc.UpdateRequest) ([]string, error) {
+				require.Equal(t, config, req.Config)
+				return nil, nil
+			})
+		mockValkeySvc.EXPECT().IsReady(gomock.Any(), gomock.Any()).Return(true, int32(1), nil)
+		mockValkeySvc.EXPECT().ApplyConfig(gomock.Any(), &valkeysvc.ApplyConfigRequest{
+			CrdName:   resourceName,
+			Namespace: defaultNamespace,
+			Config:    config,
+		}).Return(nil)
+
+		st, _, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				Replicas: lo.ToPtr(int32(1)),
+				Config:   config,
+			},
+		})
+		require.NoError(t, err)
+		step, ok := lo.Find(st.Steps, func(v databasev1alpha1.StepStatus) bool { return v.Name == valkey.StepConfig })
+		require.True(t, ok)
+		require.Equal(t, databasev1alpha1.TypeStepStateDone, step.State)
+	})
+
+	t.Run("invalid config is validation error", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).
+			Return(nil, fmt.Errorf("parameter port isn't supported: %w", render.ErrInvalidConfig))
+
+		_, _, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				Replicas: lo.ToPtr(int32(1)),
+				Config:   map[string]string{"port": "6390"},
+			},
+		})
+		require.ErrorIs(t, err, render.ErrInvalidConfig)
+		require.Equal(t, flows.ErrorClassValidation, flows.Classify(err))
+	})
+
+	t.Run("invalid quantity is validation error", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Update(gomock.Any(), gomock.Any()).
+			Return(nil, fmt.Errorf("invalid cpu \"\": %w", render.ErrInvalidQuantity))
+
+		_, _, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       resourceName,
+				Namespace:  defaultNamespace,
+				Finalizers: []string{valkey.Finalizer},
+			},
+			Spec: databasev1alpha1.ValkeySpec{Replicas: lo.ToPtr(int32(1))},
+		})
+		require.ErrorIs(t, err, render.ErrInvalidQuantity)
+		require.Equal(t, flows.ErrorClassValidation, flows.Classify(err))
+	})
+
+	t.Run("sample is defaulted without webhooks", func(t *testing.T) {
+		data, err := os.ReadFile("../../../../config/samples/database_v1alpha1_valkey.yaml")
+		require.NoError(t, err)
+		item := new(databasev1alpha1.Valkey)
+		require.NoError(t, yaml.Unmarshal(data, item))
+		item.Namespace = defaultNamespace
+
+		defaults := webhookv1alpha1.NewValkeyDefaults()
+		mockValkeySvc.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, req *valkeysvc.CreateRequest) error {
+				require.Equal(t, defaults.Image, req.Image)
+				require.Equal(t, lo.ToPtr(defaults.Replicas), req.Replicas)
+				require.Equal(t, defaults.CPU, req.Resource.CPU)
+				require.Equal(t, defaults.Memory, req.Resource.Memory)
+				require.Equal(t, defaults.VolumeStorage, req.Volume.Storage)
+				return mockErr
+			})
+
+		flow := valkey.NewFlow(
+			valkey.WithK8sClient(mockK8sClient),
+			valkey.WithValkeySvc(mockValkeySvc),
+			valkey.WithDefaulter(&webhookv1alpha1.ValkeyDefaulter{Defaults: defaults}),
+		)
+		_, _, err = flow.Run(ctx, item)
+		require.ErrorIs(t, err, mockErr)
+		// stored object isn't changed
+		require.Empty(t, item.Spec.Image)
 	})
 
 	t.Run("delete resource", func(t *testing.T) {
 		mockValkeySvc.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(nil)
 
-		status, finalizers, err := flow.Run(ctx, databasev1alpha1.Valkey{
+		status, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:              resourceName,
 				Namespace:         defaultNamespace,
@@ -149,15 +712,105 @@ func TestFlowRun(t *testing.T) {
 				DeletionTimestamp: &metav1.Time{Time: time.Now()},
 			},
 		})
-		require.NotNil(t, status)
+		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.TypePhaseDeleting, status.Phase)
 		require.Len(t, finalizers, 0)
+	})
+
+	t.Run("delete resource with deletion policy", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Delete(gomock.Any(), &valkeysvc.DeleteRequest{
+			Name:           resourceName,
+			Namespace:      defaultNamespace,
+			UID:            "valkey-uid",
+			DeletionPolicy: databasev1alpha1.TypeDeletionPolicyRetain,
+		}).Return(nil)
+
+		_, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              resourceName,
+				Namespace:         defaultNamespace,
+				UID:               "valkey-uid",
+				Finalizers:        []string{valkey.Finalizer},
+				DeletionTimestamp: &metav1.Time{Time: time.Now()},
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				DeletionPolicy: databasev1alpha1.TypeDeletionPolicyRetain,
+			},
+		})
 		require.NoError(t, err)
+		require.Len(t, finalizers, 0)
+	})
+
+	t.Run("finalizers of others are kept", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(nil)
+
+		_, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              resourceName,
+				Namespace:         defaultNamespace,
+				Finalizers:        []string{"other", valkey.Finalizer},
+				DeletionTimestamp: &metav1.Time{Time: time.Now()},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"other"}, finalizers)
+	})
+
+	t.Run("resources aren't deleted without own finalizer", func(t *testing.T) {
+		_, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              resourceName,
+				Namespace:         defaultNamespace,
+				Finalizers:        []string{"other"},
+				DeletionTimestamp: &metav1.Time{Time: time.Now()},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"other"}, finalizers)
+	})
+
+	t.Run("deletion waits for snapshots", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Delete(gomock.Any(), gomock.Any()).
+			Return(fmt.Errorf("waiting for data-valkey-0: %w", valkeysvc.ErrSnapshotNotReady))
+
+		status, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              resourceName,
+				Namespace:         defaultNamespace,
+				Finalizers:        []string{valkey.Finalizer},
+				DeletionTimestamp: &metav1.Time{Time: time.Now()},
+			},
+			Spec: databasev1alpha1.ValkeySpec{
+				DeletionPolicy: databasev1alpha1.TypeDeletionPolicySnapshot,
+			},
+		})
+		require.NoError(t, err)
+		// claims are kept by finalizer until snapshots are ready
+		require.Equal(t, []string{valkey.Finalizer}, finalizers)
+		require.Equal(t, databasev1alpha1.TypePhaseDeleting, status.Phase)
+		cond := meta.FindStatusCondition(status.Conditions, databasev1alpha1.ConditionReady)
+		require.Equal(t, databasev1alpha1.ReasonDependencyNotReady, cond.Reason)
+	})
+
+	t.Run("failed snapshot is dependency error", func(t *testing.T) {
+		mockValkeySvc.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(valkeysvc.ErrSnapshotFailed)
+
+		_, _, err := flow.Run(ctx, &databasev1alpha1.Valkey{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              resourceName,
+				Namespace:         defaultNamespace,
+				Finalizers:        []string{valkey.Finalizer},
+				DeletionTimestamp: &metav1.Time{Time: time.Now()},
+			},
+		})
+		require.ErrorIs(t, err, valkeysvc.ErrSnapshotFailed)
+		require.Equal(t, flows.ErrorClassDependency, flows.Classify(err))
 	})
 
 	t.Run("delete resource error", func(t *testing.T) {
 		mockValkeySvc.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(mockErr)
 
-		status, finalizers, err := flow.Run(ctx, databasev1alpha1.Valkey{
+		status, finalizers, err := flow.Run(ctx, &databasev1alpha1.Valkey{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:              resourceName,
 				Namespace:         defaultNamespace,
@@ -0,0 +1,304 @@
+package valkey
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/controller/flows"
+	valkeysvc "github.com/uagolang/k8s-operator/internal/services/valkey"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
+)
+
+// ensureResources creates child objects on the first reconcile
+// and brings them to the desired state on the next ones
+func (r *FlowImpl) ensureResources(ctx context.Context, item *v1alpha1.Valkey, res *v1alpha1.ValkeyStatus) (flows.Result, error) {
+	if !controllerutil.ContainsFinalizer(item, Finalizer) {
+		err := r.valkeySvc.Create(ctx, &valkeysvc.CreateRequest{
+			CrdName:           item.Name,
+			Namespace:         item.Namespace,
+			Image:             item.Spec.Image,
+			User:              item.Spec.User,
+			Password:          item.Spec.Password,
+			PasswordSecretRef: item.Spec.PasswordSecretRef(),
+			Replicas:          item.Spec.Replicas,
+			Mode:              item.Spec.Mode,
+			Sentinel:          item.Spec.Sentinel,
+			Cluster:           item.Spec.Cluster,
+			Volume:            item.Spec.Volume,
+			Resource:          item.Spec.Resource,
+			TLS:               item.Spec.TLS,
+			Config:            item.Spec.Config,
+			RestoreFrom:       item.Spec.RestoreFrom,
+			Owner:             ownerReference(item),
+		})
+		if err != nil {
+			return flows.Result{}, classify(err)
+		}
+
+		// pods aren't waited for, the next reconcile is triggered by their events
+		res.Phase = v1alpha1.TypePhaseCreating
+		res.Status = v1alpha1.TypeStatusUpdating
+		res.SetCondition(v1alpha1.ConditionProgressing, metav1.ConditionTrue, v1alpha1.ReasonCreating, "resources are created")
+		res.SetCondition(v1alpha1.ConditionReady, metav1.ConditionFalse, v1alpha1.ReasonCreating, "resources are created")
+
+		return flows.Wait("resources are created"), nil
+	}
+
+	drifted, err := r.valkeySvc.Update(ctx, &valkeysvc.UpdateRequest{
+		CrdName:           item.Name,
+		Namespace:         item.Namespace,
+		Image:             &item.Spec.Image,
+		User:              &item.Spec.User,
+		Password:          &item.Spec.Password,
+		PasswordSecretRef: item.Spec.PasswordSecretRef(),
+		Replicas:          item.Spec.Replicas,
+		Mode:              &item.Spec.Mode,
+		Sentinel:          item.Spec.Sentinel,
+		Cluster:           item.Spec.Cluster,
+		Volume:            &item.Spec.Volume,
+		Resource:          &item.Spec.Resource,
+		TLS:               item.Spec.TLS,
+		Config:            item.Spec.Config,
+		Primary:           item.Status.Primary,
+		Owner:             ownerReference(item),
+	})
+	if errors.Is(err, valkeysvc.ErrMigrationInProgress) {
+		// Deployment events aren't watched, progress is checked by requeue
+		return flows.Wait(err.Error()), nil
+	}
+	if err != nil {
+		return flows.Result{}, classify(err)
+	}
+
+	setDriftCondition(res, drifted, item.Generation)
+	res.SetCondition(v1alpha1.ConditionSecretReady, metav1.ConditionTrue, v1alpha1.ReasonSecretFound, "password secret exists")
+
+	return flows.Done("resources are up to date"), nil
+}
+
+// ensureStorage reports whether volume claims are bound,
+// pods are checked anyway because claims could be bound lazily
+func (r *FlowImpl) ensureStorage(ctx context.Context, item *v1alpha1.Valkey, res *v1alpha1.ValkeyStatus) (flows.Result, error) {
+	if !item.Spec.Volume.Enabled {
+		res.SetCondition(v1alpha1.ConditionStorageReady, metav1.ConditionTrue, v1alpha1.ReasonStorageNotRequired, "persistent volume is disabled")
+		return flows.Done("persistent volume is disabled"), nil
+	}
+
+	err := r.resizeStorage(ctx, item, res)
+	if err != nil {
+		return flows.Result{}, err
+	}
+
+	storageReady, err := r.valkeySvc.IsStorageReady(ctx, &valkeysvc.IsReadyRequest{
+		Name:      item.Name,
+		Namespace: item.Namespace,
+	})
+	if err != nil {
+		return flows.Result{}, err
+	}
+
+	if !storageReady {
+		res.SetCondition(v1alpha1.ConditionStorageReady, metav1.ConditionFalse, v1alpha1.ReasonClaimsPending, "volume claims are not bound")
+		return flows.Done("volume claims are not bound"), nil
+	}
+
+	res.SetCondition(v1alpha1.ConditionStorageReady, metav1.ConditionTrue, v1alpha1.ReasonClaimsBound, "volume claims are bound")
+
+	return flows.Done("volume claims are bound"), nil
+}
+
+// resizeStorage expands volume claims when requested storage grows, claims
+// which can't be expanded are reported without failing the flow, so the
+// instance keeps running with the current size
+func (r *FlowImpl) resizeStorage(ctx context.Context, item *v1alpha1.Valkey, res *v1alpha1.ValkeyStatus) error {
+	storage := item.Spec.ClaimStorage()
+	resizing, err := r.valkeySvc.ResizeStorage(ctx, &valkeysvc.ResizeStorageRequest{
+		Name:      item.Name,
+		Namespace: item.Namespace,
+		Storage:   storage,
+	})
+	switch {
+	case errors.Is(err, valkeysvc.ErrStorageShrink):
+		res.SetCondition(v1alpha1.ConditionStorageResizing, metav1.ConditionFalse, v1alpha1.ReasonShrinkNotSupported, err.Error())
+	case errors.Is(err, valkeysvc.ErrExpansionNotSupported):
+		res.SetCondition(v1alpha1.ConditionStorageResizing, metav1.ConditionFalse, v1alpha1.ReasonExpansionNotSupported, err.Error())
+	case err != nil:
+		return err
+	case resizing:
+		res.SetCondition(v1alpha1.ConditionStorageResizing, metav1.ConditionTrue, v1alpha1.ReasonResizing, "volume claims are expanded to "+storage)
+	default:
+		res.SetCondition(v1alpha1.ConditionStorageResizing, metav1.ConditionFalse, v1alpha1.ReasonResized, "volume claims have requested size")
+	}
+
+	return nil
+}
+
+// ensurePods waits until at least one pod accepts connections
+func (r *FlowImpl) ensurePods(ctx context.Context, item *v1alpha1.Valkey, res *v1alpha1.ValkeyStatus) (flows.Result, error) {
+	ready, readyReplicas, err := r.valkeySvc.IsReady(ctx, &valkeysvc.IsReadyRequest{
+		Name:      item.Name,
+		Namespace: item.Namespace,
+	})
+	if err != nil {
+		return flows.Result{}, err
+	}
+
+	res.ReadyReplicas = readyReplicas
+	replicasMessage := fmt.Sprintf("%d of %d replicas are ready", readyReplicas, item.Spec.PodsCount())
+
+	if !ready || readyReplicas == 0 {
+		res.Phase = v1alpha1.TypePhaseProvisioning
+		res.Status = v1alpha1.TypeStatusStopped
+		res.SetCondition(v1alpha1.ConditionReady, metav1.ConditionFalse, v1alpha1.ReasonReplicasNotReady, replicasMessage)
+		res.SetCondition(v1alpha1.ConditionProgressing, metav1.ConditionTrue, v1alpha1.ReasonReplicasNotReady, replicasMessage)
+		res.SetCondition(v1alpha1.ConditionDegraded, metav1.ConditionTrue, v1alpha1.ReasonReplicasNotReady, replicasMessage)
+
+		return flows.Wait(replicasMessage), nil
+	}
+
+	return flows.Done(replicasMessage), nil
+}
+
+// ensureCertificates reloads renewed certificates without restart of pods,
+// pods which don't serve the current certificate yet are checked again
+func (r *FlowImpl) ensureCertificates(ctx context.Context, item *v1alpha1.Valkey, res *v1alpha1.ValkeyStatus) (flows.Result, error) {
+	if !item.Spec.TLSEnabled() {
+		meta.RemoveStatusCondition(&res.Conditions, v1alpha1.ConditionCertificateReady)
+		return flows.Done("tls is disabled"), nil
+	}
+
+	loaded, err := r.valkeySvc.ReloadTLS(ctx, &valkeysvc.ReloadTLSRequest{
+		CrdName:   item.Name,
+		Namespace: item.Namespace,
+	})
+	if err != nil {
+		return flows.Result{}, classify(err)
+	}
+
+	if !loaded {
+		res.SetCondition(v1alpha1.ConditionCertificateReady, metav1.ConditionFalse, v1alpha1.ReasonCertificateReloading, "certificate is being reloaded")
+		return flows.Done("certificate is being reloaded"), nil
+	}
+
+	res.SetCondition(v1alpha1.ConditionCertificateReady, metav1.ConditionTrue, v1alpha1.ReasonCertificateLoaded, "pods serve current certificate")
+
+	return flows.Done("pods serve current certificate"), nil
+}
+
+// ensureConfig applies changed dynamic parameters to running pods,
+// pods are restarted by StatefulSet when static ones are changed,
+// removed parameters keep their value until restart
+func (r *FlowImpl) ensureConfig(ctx context.Context, item *v1alpha1.Valkey, _ *v1alpha1.ValkeyStatus) (flows.Result, error) {
+	if len(item.Spec.Config) == 0 {
+		return flows.Done("config is empty"), nil
+	}
+
+	err := r.valkeySvc.ApplyConfig(ctx, &valkeysvc.ApplyConfigRequest{
+		CrdName:   item.Name,
+		Namespace: item.Namespace,
+		Config:    item.Spec.Config,
+	})
+	if err != nil {
+		return flows.Result{}, classify(err)
+	}
+
+	return flows.Done("config is applied"), nil
+}
+
+// ensureTopology configures replication or assigns cluster slots
+func (r *FlowImpl) ensureTopology(ctx context.Context, item *v1alpha1.Valkey, res *v1alpha1.ValkeyStatus) (flows.Result, error) {
+	var err error
+	switch {
+	case item.Spec.Mode == v1alpha1.TypeModeReplication:
+		res.Primary, err = r.valkeySvc.SyncReplication(ctx, &valkeysvc.SyncReplicationRequest{
+			CrdName:   item.Name,
+			Namespace: item.Namespace,
+			Primary:   item.Status.Primary,
+			Sentinel:  item.Spec.Sentinel != nil && item.Spec.Sentinel.Enabled,
+		})
+		if err != nil {
+			return flows.Result{}, err
+		}
+
+		return flows.Done(res.Primary + " is primary"), nil
+	case item.Spec.Mode == v1alpha1.TypeModeCluster && item.Spec.Cluster != nil:
+		res.Shards, err = r.valkeySvc.SyncCluster(ctx, &valkeysvc.SyncClusterRequest{
+			CrdName:          item.Name,
+			Namespace:        item.Namespace,
+			Shards:           item.Spec.Cluster.Shards,
+			ReplicasPerShard: item.Spec.Cluster.ReplicasPerShard,
+		})
+		if err != nil {
+			return flows.Result{}, err
+		}
+
+		return flows.Done(fmt.Sprintf("%d shards are assigned", len(res.Shards))), nil
+	}
+
+	res.Primary = ""
+
+	return flows.Done("topology is not required"), nil
+}
+
+// verifyHealth reports whether instance matches the spec
+func (r *FlowImpl) verifyHealth(_ context.Context, item *v1alpha1.Valkey, res *v1alpha1.ValkeyStatus) (flows.Result, error) {
+	desired := item.Spec.PodsCount()
+	replicasMessage := fmt.Sprintf("%d of %d replicas are ready", res.ReadyReplicas, desired)
+
+	res.Status = v1alpha1.TypeStatusHealthy
+	res.SetCondition(v1alpha1.ConditionReady, metav1.ConditionTrue, v1alpha1.ReasonReconciled, replicasMessage)
+
+	var message string
+	switch {
+	case lo.ContainsBy(res.Shards, func(s v1alpha1.ShardStatus) bool { return s.Status != v1alpha1.TypeStatusHealthy }):
+		// slots are assigned or moved between shards
+		message = "cluster slots are rebalanced"
+		res.Phase = v1alpha1.TypePhaseConfiguring
+		res.Status = v1alpha1.TypeStatusUpdating
+		res.SetCondition(v1alpha1.ConditionProgressing, metav1.ConditionTrue, v1alpha1.ReasonRebalancing, message)
+	case res.ReadyReplicas < desired:
+		message = replicasMessage
+		res.Phase = v1alpha1.TypePhaseProvisioning
+		res.SetCondition(v1alpha1.ConditionProgressing, metav1.ConditionTrue, v1alpha1.ReasonReplicasNotReady, message)
+	default:
+		message = "instance is running"
+		res.Phase = v1alpha1.TypePhaseRunning
+		res.SetCondition(v1alpha1.ConditionProgressing, metav1.ConditionFalse, v1alpha1.ReasonReconciled, "resources are up to date")
+	}
+
+	if res.ReadyReplicas < desired {
+		res.SetCondition(v1alpha1.ConditionDegraded, metav1.ConditionTrue, v1alpha1.ReasonReplicasNotReady, replicasMessage)
+	} else {
+		res.SetCondition(v1alpha1.ConditionDegraded, metav1.ConditionFalse, v1alpha1.ReasonReconciled, replicasMessage)
+	}
+
+	return flows.Done(message), nil
+}
+
+// classify marks errors of the service which won't be fixed by retry
+// until another object is created or ready, or the spec is changed,
+// other errors are classified by origin
+func classify(err error) error {
+	switch {
+	case errors.Is(err, valkeysvc.ErrPasswordSecretNotFound),
+		errors.Is(err, valkeysvc.ErrTLSSecretNotFound),
+		errors.Is(err, valkeysvc.ErrTLSSecretInvalid),
+		errors.Is(err, valkeysvc.ErrRestoreSourceNotFound),
+		errors.Is(err, valkeysvc.ErrRestoreSourceNotReady),
+		errors.Is(err, valkeysvc.ErrSnapshotFailed):
+		return flows.DependencyError(err)
+	case errors.Is(err, valkeysvc.ErrRestoreNotSupported),
+		errors.Is(err, render.ErrInvalidConfig),
+		errors.Is(err, render.ErrInvalidQuantity):
+		return flows.ValidationError(err)
+	}
+
+	return err
+}
@@ -3,3 +3,14 @@ package valkey
 const (
 	Finalizer = "valkey/kuberly.io"
 )
+
+// Steps of the flow in order they are run
+const (
+	StepResources = "resources"
+	StepStorage   = "storage"
+	StepPods      = "pods"
+	StepTLS       = "tls"
+	StepConfig    = "config"
+	StepTopology  = "topology"
+	StepHealth    = "health"
+)
@@ -0,0 +1,102 @@
+package valkeybackup
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/controller/flows"
+	backupsvc "github.com/uagolang/k8s-operator/internal/services/backup"
+)
+
+type FlowImpl struct {
+	k8sClient client.Client
+	backupSvc backupsvc.Service
+
+	steps flows.Steps[*v1alpha1.ValkeyBackup, *v1alpha1.ValkeyBackupStatus]
+}
+
+type ImplOption func(r *FlowImpl)
+
+func NewFlow(opts ...ImplOption) flows.Flow[*v1alpha1.ValkeyBackup, *v1alpha1.ValkeyBackupStatus] {
+	res := new(FlowImpl)
+	for _, opt := range opts {
+		opt(res)
+	}
+
+	res.steps = flows.Steps[*v1alpha1.ValkeyBackup, *v1alpha1.ValkeyBackupStatus]{
+		{Name: StepTarget, Run: res.findTarget},
+		{Name: StepSave, Run: res.save},
+		{Name: StepCopy, Run: res.copy},
+	}
+
+	return res
+}
+
+func WithK8sClient(v client.Client) ImplOption {
+	return func(r *FlowImpl) {
+		r.k8sClient = v
+	}
+}
+
+func WithBackupSvc(v backupsvc.Service) ImplOption {
+	return func(r *FlowImpl) {
+		r.backupSvc = v
+	}
+}
+
+// Run takes the backup once, finished backup isn't changed anymore.
+// Backup has no child objects except the copy job owned by it,
+// so finalizers aren't needed
+func (r *FlowImpl) Run(ctx context.Context, item *v1alpha1.ValkeyBackup) (*v1alpha1.ValkeyBackupStatus, []string, error) {
+	logger := log.FromContext(ctx).WithValues("flow", "valkey_backup", "crd_name", item.Name)
+	ctx = log.IntoContext(ctx, logger)
+
+	if item.Status.Phase.IsFinished() {
+		return item.Status.DeepCopy(), item.Finalizers, nil
+	}
+
+	res := &v1alpha1.ValkeyBackupStatus{
+		ObservedGeneration: item.Generation,
+		Phase:              lo.CoalesceOrEmpty(item.Status.Phase, v1alpha1.TypeBackupPhasePending),
+		Pod:                item.Status.Pod,
+		StartedAt:          item.Status.StartedAt,
+		// previous conditions keep their transition time
+		Conditions: slices.Clone(item.Status.Conditions),
+		Steps:      slices.Clone(item.Status.Steps),
+	}
+
+	done, err := r.steps.Run(ctx, item, res)
+	if err != nil {
+		if !errors.Is(err, backupsvc.ErrCopyFailed) {
+			return nil, nil, err
+		}
+
+		// job is retried by its backoff limit, backup isn't retried after it
+		now := metav1.Now()
+		res.Phase = v1alpha1.TypeBackupPhaseFailed
+		res.Error = err.Error()
+		res.CompletedAt = &now
+		res.SetCondition(v1alpha1.ConditionCompleted, metav1.ConditionFalse, v1alpha1.ReasonBackupFailed, err.Error())
+		logger.Info("backup failed", "error", err.Error())
+
+		return res, item.Finalizers, nil
+	}
+
+	if !done {
+		res.SetCondition(v1alpha1.ConditionCompleted, metav1.ConditionFalse, v1alpha1.ReasonBackupInProgress, "backup is in progress")
+	}
+
+	return res, item.Finalizers, nil
+}
+
+// ownerReference makes ValkeyBackup the controller of the copy job
+func ownerReference(item *v1alpha1.ValkeyBackup) *metav1.OwnerReference {
+	return metav1.NewControllerRef(item, v1alpha1.GroupVersion.WithKind("ValkeyBackup"))
+}
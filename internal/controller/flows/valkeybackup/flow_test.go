@@ -0,0 +1,232 @@
+package valkeybackup_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	databasev1alpha1 "github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/controller/flows"
+	"github.com/uagolang/k8s-operator/internal/controller/flows/valkeybackup"
+	backupsvc "github.com/uagolang/k8s-operator/internal/services/backup"
+	"github.com/uagolang/k8s-operator/mocks"
+)
+
+func TestFlowRun(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	const (
+		resourceName     = "nightly"
+		valkeyName       = "valkey"
+		defaultNamespace = "default"
+	)
+
+	mockErr := errors.New("mock error")
+	mockK8sClient := mocks.NewMockK8sClient(ctrl)
+	mockBackupSvc := mocks.NewMockBackupService(ctrl)
+
+	flow := valkeybackup.NewFlow(
+		valkeybackup.WithK8sClient(mockK8sClient),
+		valkeybackup.WithBackupSvc(mockBackupSvc),
+	)
+
+	newBackup := func(status databasev1alpha1.ValkeyBackupStatus) *databasev1alpha1.ValkeyBackup {
+		return &databasev1alpha1.ValkeyBackup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      resourceName,
+				Namespace: defaultNamespace,
+			},
+			Spec: databasev1alpha1.ValkeyBackupSpec{
+				ValkeyName: valkeyName,
+				Image:      "curlimages/curl:8.10.1",
+				Destination: databasev1alpha1.BackupDestination{
+					PVC: &databasev1alpha1.PVCDestination{ClaimName: "backups"},
+				},
+			},
+			Status: status,
+		}
+	}
+
+	getValkey := func(spec databasev1alpha1.ValkeySpec, status databasev1alpha1.ValkeyStatus) {
+		mockK8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{
+			Name:      valkeyName,
+			Namespace: defaultNamespace,
+		}, gomock.AssignableToTypeOf(&databasev1alpha1.Valkey{})).DoAndReturn(
+			func(_ context.Context, key types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+				item := obj.(*databasev1alpha1.Valkey)
+				item.Name = key.Name
+				item.Spec = spec
+				item.Status = status
+				return nil
+			})
+	}
+
+	withVolume := databasev1alpha1.ValkeySpec{Volume: databasev1alpha1.Volume{Enabled: true}}
+	running := databasev1alpha1.ValkeyStatus{Phase: databasev1alpha1.TypePhaseRunning}
+
+	t.Run("bgsave is requested", func(t *testing.T) {
+		getValkey(withVolume, running)
+		mockBackupSvc.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, req *backupsvc.SaveRequest) (bool, error) {
+				require.Equal(t, "valkey-0", req.Pod)
+				require.False(t, req.Since.IsZero())
+				return false, nil
+			})
+
+		st, finalizers, err := flow.Run(ctx, newBackup(databasev1alpha1.ValkeyBackupStatus{}))
+		require.NoError(t, err)
+		require.Empty(t, finalizers)
+		require.Equal(t, databasev1alpha1.TypeBackupPhaseSaving, st.Phase)
+		require.Equal(t, "valkey-0", st.Pod)
+		require.NotNil(t, st.StartedAt)
+		require.True(t, meta.IsStatusConditionFalse(st.Conditions, databasev1alpha1.ConditionCompleted))
+		require.Equal(t, []databasev1alpha1.StepStatus{
+			{Name: valkeybackup.StepTarget, State: databasev1alpha1.TypeStepStateDone, Message: "valkey-0 is backed up"},
+			{Name: valkeybackup.StepSave, State: databasev1alpha1.TypeStepStateWaiting, Message: "RDB file is being saved"},
+			{Name: valkeybackup.StepCopy, State: databasev1alpha1.TypeStepStatePending},
+		}, st.Steps)
+	})
+
+	t.Run("primary is backed up in replication mode", func(t *testing.T) {
+		getValkey(withVolume, databasev1alpha1.ValkeyStatus{
+			Phase:   databasev1alpha1.TypePhaseRunning,
+			Primary: "valkey-2",
+		})
+		mockBackupSvc.EXPECT().Save(gomock.Any(), gomock.Any()).Return(false, nil)
+
+		st, _, err := flow.Run(ctx, newBackup(databasev1alpha1.ValkeyBackupStatus{}))
+		require.NoError(t, err)
+		require.Equal(t, "valkey-2", st.Pod)
+	})
+
+	t.Run("instance is not running", func(t *testing.T) {
+		getValkey(withVolume, databasev1alpha1.ValkeyStatus{Phase: databasev1alpha1.TypePhaseProvisioning})
+
+		st, _, err := flow.Run(ctx, newBackup(databasev1alpha1.ValkeyBackupStatus{}))
+		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.TypeBackupPhasePending, st.Phase)
+		require.Empty(t, st.Pod)
+	})
+
+	t.Run("instance not found", func(t *testing.T) {
+		mockK8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(k8serrors.NewNotFound(schema.GroupResource{Group: "database.kuberly.io", Resource: "valkeys"}, valkeyName))
+
+		_, _, err := flow.Run(ctx, newBackup(databasev1alpha1.ValkeyBackupStatus{}))
+		require.Equal(t, flows.ErrorClassDependency, flows.Classify(err))
+	})
+
+	t.Run("instance can't be backed up", func(t *testing.T) {
+		for _, spec := range []databasev1alpha1.ValkeySpec{
+			{},
+			{Mode: databasev1alpha1.TypeModeCluster, Volume: databasev1alpha1.Volume{Enabled: true}},
+		} {
+			getValkey(spec, running)
+
+			_, _, err := flow.Run(ctx, newBackup(databasev1alpha1.ValkeyBackupStatus{}))
+			require.Equal(t, flows.ErrorClassValidation, flows.Classify(err))
+		}
+	})
+
+	t.Run("copy job is created", func(t *testing.T) {
+		startedAt := metav1.NewTime(time.Now().Add(-time.Minute))
+
+		mockBackupSvc.EXPECT().Save(gomock.Any(), &backupsvc.SaveRequest{
+			Pod:       "valkey-0",
+			Namespace: defaultNamespace,
+			Since:     startedAt.Time,
+		}).Return(true, nil)
+		mockBackupSvc.EXPECT().Copy(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, req *backupsvc.CopyRequest) (*backupsvc.CopyResult, error) {
+				require.Equal(t, resourceName, req.Name)
+				require.Equal(t, "valkey-0", req.Pod)
+				require.Equal(t, "ValkeyBackup", req.Owner.Kind)
+				require.Equal(t, "backups", req.Destination.PVC.ClaimName)
+				return nil, nil
+			})
+
+		st, _, err := flow.Run(ctx, newBackup(databasev1alpha1.ValkeyBackupStatus{
+			Phase:     databasev1alpha1.TypeBackupPhaseSaving,
+			Pod:       "valkey-0",
+			StartedAt: &startedAt,
+		}))
+		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.TypeBackupPhaseCopying, st.Phase)
+		require.Equal(t, &startedAt, st.StartedAt)
+	})
+
+	t.Run("backup is completed", func(t *testing.T) {
+		startedAt := metav1.NewTime(time.Now().Add(-time.Minute))
+
+		// RDB file isn't checked again while it's copied
+		mockBackupSvc.EXPECT().Copy(gomock.Any(), gomock.Any()).Return(&backupsvc.CopyResult{
+			Location: "pvc://backups/nightly.rdb",
+			Size:     1024,
+			Checksum: "abc",
+		}, nil)
+
+		st, _, err := flow.Run(ctx, newBackup(databasev1alpha1.ValkeyBackupStatus{
+			Phase:     databasev1alpha1.TypeBackupPhaseCopying,
+			Pod:       "valkey-0",
+			StartedAt: &startedAt,
+		}))
+		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.TypeBackupPhaseCompleted, st.Phase)
+		require.Equal(t, "pvc://backups/nightly.rdb", st.Location)
+		require.Equal(t, int64(1024), st.Size)
+		require.Equal(t, "abc", st.Checksum)
+		require.NotNil(t, st.CompletedAt)
+		require.True(t, meta.IsStatusConditionTrue(st.Conditions, databasev1alpha1.ConditionCompleted))
+	})
+
+	t.Run("copy job failed", func(t *testing.T) {
+		mockBackupSvc.EXPECT().Copy(gomock.Any(), gomock.Any()).Return(nil, backupsvc.ErrCopyFailed)
+
+		st, _, err := flow.Run(ctx, newBackup(databasev1alpha1.ValkeyBackupStatus{
+			Phase: databasev1alpha1.TypeBackupPhaseCopying,
+			Pod:   "valkey-0",
+		}))
+		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.TypeBackupPhaseFailed, st.Phase)
+		require.NotEmpty(t, st.Error)
+		require.NotNil(t, st.CompletedAt)
+		require.Equal(t, databasev1alpha1.ReasonBackupFailed,
+			meta.FindStatusCondition(st.Conditions, databasev1alpha1.ConditionCompleted).Reason)
+	})
+
+	t.Run("save error", func(t *testing.T) {
+		mockBackupSvc.EXPECT().Save(gomock.Any(), gomock.Any()).Return(false, mockErr)
+
+		st, _, err := flow.Run(ctx, newBackup(databasev1alpha1.ValkeyBackupStatus{Pod: "valkey-0"}))
+		require.Nil(t, st)
+		require.ErrorIs(t, err, mockErr)
+
+		var stepErr *flows.StepError
+		require.ErrorAs(t, err, &stepErr)
+		require.Equal(t, valkeybackup.StepSave, stepErr.Step)
+	})
+
+	t.Run("finished backup isn't changed", func(t *testing.T) {
+		status := databasev1alpha1.ValkeyBackupStatus{
+			Phase:    databasev1alpha1.TypeBackupPhaseCompleted,
+			Location: "pvc://backups/nightly.rdb",
+		}
+
+		// nothing is expected to be called
+		st, _, err := flow.Run(ctx, newBackup(status))
+		require.NoError(t, err)
+		require.Equal(t, &status, st)
+	})
+}
@@ -0,0 +1,119 @@
+package valkeybackup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/controller/flows"
+	backupsvc "github.com/uagolang/k8s-operator/internal/services/backup"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
+)
+
+// findTarget chooses the pod which RDB file is copied, it's kept
+// for the backup, so failover doesn't mix files of different pods
+func (r *FlowImpl) findTarget(ctx context.Context, item *v1alpha1.ValkeyBackup, res *v1alpha1.ValkeyBackupStatus) (flows.Result, error) {
+	if res.Pod != "" {
+		return flows.Done(res.Pod + " is backed up"), nil
+	}
+
+	target := new(v1alpha1.Valkey)
+	err := r.k8sClient.Get(ctx, types.NamespacedName{
+		Name:      item.Spec.ValkeyName,
+		Namespace: item.Namespace,
+	}, target)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return flows.Result{}, flows.DependencyError(err)
+		}
+
+		return flows.Result{}, err
+	}
+
+	switch {
+	case target.Spec.Mode == v1alpha1.TypeModeCluster:
+		// every shard has its own RDB file
+		return flows.Result{}, flows.ValidationError(errors.New("backup of cluster mode isn't supported"))
+	case !target.Spec.Volume.Enabled:
+		return flows.Result{}, flows.ValidationError(errors.New("instance without persistent volume can't be backed up"))
+	case target.Status.Phase != v1alpha1.TypePhaseRunning:
+		res.Phase = v1alpha1.TypeBackupPhasePending
+		return flows.Wait("instance is not running"), nil
+	}
+
+	res.Pod = target.Status.Primary
+	if res.Pod == "" {
+		res.Pod = render.PodName(target.Name, 0)
+	}
+
+	return flows.Done(res.Pod + " is backed up"), nil
+}
+
+// save waits until RDB file written after the backup was started is on the disk
+func (r *FlowImpl) save(ctx context.Context, item *v1alpha1.ValkeyBackup, res *v1alpha1.ValkeyBackupStatus) (flows.Result, error) {
+	if res.Phase == v1alpha1.TypeBackupPhaseCopying {
+		return flows.Done("RDB file is saved"), nil
+	}
+
+	if res.StartedAt == nil {
+		now := metav1.Now()
+		res.StartedAt = &now
+	}
+
+	saved, err := r.backupSvc.Save(ctx, &backupsvc.SaveRequest{
+		Pod:       res.Pod,
+		Namespace: item.Namespace,
+		Since:     res.StartedAt.Time,
+	})
+	if err != nil {
+		return flows.Result{}, err
+	}
+
+	if !saved {
+		res.Phase = v1alpha1.TypeBackupPhaseSaving
+		return flows.Wait("RDB file is being saved"), nil
+	}
+
+	return flows.Done("RDB file is saved"), nil
+}
+
+// copy waits until the job copies RDB file to the destination
+func (r *FlowImpl) copy(ctx context.Context, item *v1alpha1.ValkeyBackup, res *v1alpha1.ValkeyBackupStatus) (flows.Result, error) {
+	copied, err := r.backupSvc.Copy(ctx, &backupsvc.CopyRequest{
+		Name:        item.Name,
+		Namespace:   item.Namespace,
+		Pod:         res.Pod,
+		Image:       item.Spec.Image,
+		Destination: item.Spec.Destination,
+		Owner:       ownerReference(item),
+	})
+	if err != nil {
+		if errors.Is(err, backupsvc.ErrNoPersistentVolume) {
+			return flows.Result{}, flows.ValidationError(err)
+		}
+
+		return flows.Result{}, err
+	}
+
+	if copied == nil {
+		res.Phase = v1alpha1.TypeBackupPhaseCopying
+		return flows.Wait("RDB file is being copied"), nil
+	}
+
+	now := metav1.Now()
+	res.Phase = v1alpha1.TypeBackupPhaseCompleted
+	res.Location = copied.Location
+	res.Size = copied.Size
+	res.Checksum = copied.Checksum
+	res.CompletedAt = &now
+
+	message := fmt.Sprintf("%d bytes are copied to %s", copied.Size, copied.Location)
+	res.SetCondition(v1alpha1.ConditionCompleted, metav1.ConditionTrue, v1alpha1.ReasonBackupCompleted, message)
+
+	return flows.Done(message), nil
+}
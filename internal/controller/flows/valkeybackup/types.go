@@ -0,0 +1,8 @@
+package valkeybackup
+
+// Steps of the flow in order they are run
+const (
+	StepTarget = "target"
+	StepSave   = "save"
+	StepCopy   = "copy"
+)
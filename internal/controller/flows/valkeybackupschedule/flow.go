@@ -0,0 +1,94 @@
+package valkeybackupschedule
+
+import (
+	"context"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/controller/flows"
+	backupsvc "github.com/uagolang/k8s-operator/internal/services/backup"
+)
+
+type FlowImpl struct {
+	k8sClient client.Client
+	backupSvc backupsvc.Service
+
+	steps flows.Steps[*v1alpha1.ValkeyBackupSchedule, *v1alpha1.ValkeyBackupScheduleStatus]
+}
+
+type ImplOption func(r *FlowImpl)
+
+func NewFlow(opts ...ImplOption) flows.Flow[*v1alpha1.ValkeyBackupSchedule, *v1alpha1.ValkeyBackupScheduleStatus] {
+	res := new(FlowImpl)
+	for _, opt := range opts {
+		opt(res)
+	}
+
+	res.steps = flows.Steps[*v1alpha1.ValkeyBackupSchedule, *v1alpha1.ValkeyBackupScheduleStatus]{
+		{Name: StepSchedule, Run: res.schedule},
+		{Name: StepRetention, Run: res.prune},
+	}
+
+	return res
+}
+
+func WithK8sClient(v client.Client) ImplOption {
+	return func(r *FlowImpl) {
+		r.k8sClient = v
+	}
+}
+
+func WithBackupSvc(v backupsvc.Service) ImplOption {
+	return func(r *FlowImpl) {
+		r.backupSvc = v
+	}
+}
+
+// Run creates backup when it's scheduled and prunes expired ones.
+// Backups are only labeled with the schedule, so they and their RDB files
+// outlive it, prune jobs are owned and removed together with the schedule,
+// so finalizers aren't needed
+func (r *FlowImpl) Run(ctx context.Context, item *v1alpha1.ValkeyBackupSchedule) (*v1alpha1.ValkeyBackupScheduleStatus, []string, error) {
+	logger := log.FromContext(ctx).WithValues("flow", "valkey_backup_schedule", "crd_name", item.Name)
+	ctx = log.IntoContext(ctx, logger)
+
+	res := &v1alpha1.ValkeyBackupScheduleStatus{
+		ObservedGeneration: item.Generation,
+		LastScheduleAt:     item.Status.LastScheduleAt,
+		// backups could be pruned already, so the latest results are kept
+		LastSuccessAt: item.Status.LastSuccessAt,
+		LastFailureAt: item.Status.LastFailureAt,
+		// previous conditions keep their transition time
+		Conditions: slices.Clone(item.Status.Conditions),
+		Steps:      slices.Clone(item.Status.Steps),
+	}
+
+	if _, err := r.steps.Run(ctx, item, res); err != nil {
+		return nil, nil, err
+	}
+
+	return res, item.Finalizers, nil
+}
+
+// listBackups returns backups created by the schedule
+func (r *FlowImpl) listBackups(ctx context.Context, item *v1alpha1.ValkeyBackupSchedule) ([]v1alpha1.ValkeyBackup, error) {
+	list := new(v1alpha1.ValkeyBackupList)
+	err := r.k8sClient.List(ctx, list,
+		client.InNamespace(item.Namespace),
+		client.MatchingLabels{v1alpha1.LabelBackupSchedule: item.Name},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return list.Items, nil
+}
+
+// ownerReference makes ValkeyBackupSchedule the controller of prune jobs
+func ownerReference(item *v1alpha1.ValkeyBackupSchedule) *metav1.OwnerReference {
+	return metav1.NewControllerRef(item, v1alpha1.GroupVersion.WithKind("ValkeyBackupSchedule"))
+}
@@ -0,0 +1,297 @@
+package valkeybackupschedule_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	databasev1alpha1 "github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/controller/flows"
+	"github.com/uagolang/k8s-operator/internal/controller/flows/valkeybackupschedule"
+	backupsvc "github.com/uagolang/k8s-operator/internal/services/backup"
+	"github.com/uagolang/k8s-operator/internal/utils"
+	"github.com/uagolang/k8s-operator/mocks"
+)
+
+func TestFlowRun(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	const (
+		resourceName     = "daily"
+		defaultNamespace = "default"
+	)
+
+	mockErr := errors.New("mock error")
+	mockK8sClient := mocks.NewMockK8sClient(ctrl)
+	mockBackupSvc := mocks.NewMockBackupService(ctrl)
+
+	flow := valkeybackupschedule.NewFlow(
+		valkeybackupschedule.WithK8sClient(mockK8sClient),
+		valkeybackupschedule.WithBackupSvc(mockBackupSvc),
+	)
+
+	now := time.Now()
+	// every minute, so at least one run is missed since two minutes ago
+	lastScheduleAt := metav1.NewTime(now.Add(-2 * time.Minute).Truncate(time.Second))
+
+	newSchedule := func(spec databasev1alpha1.ValkeyBackupScheduleSpec) *databasev1alpha1.ValkeyBackupSchedule {
+		spec.Schedule = "* * * * *"
+		spec.Template = databasev1alpha1.ValkeyBackupSpec{
+			ValkeyName: "valkey",
+			Image:      "curlimages/curl:8.10.1",
+			Destination: databasev1alpha1.BackupDestination{
+				PVC: &databasev1alpha1.PVCDestination{ClaimName: "backups"},
+			},
+		}
+
+		return &databasev1alpha1.ValkeyBackupSchedule{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              resourceName,
+				Namespace:         defaultNamespace,
+				UID:               "schedule-uid",
+				CreationTimestamp: metav1.NewTime(now.Add(-time.Hour)),
+			},
+			Spec: spec,
+			Status: databasev1alpha1.ValkeyBackupScheduleStatus{
+				LastScheduleAt: &lastScheduleAt,
+			},
+		}
+	}
+
+	backup := func(name string, phase databasev1alpha1.TypeBackupPhase, age time.Duration) databasev1alpha1.ValkeyBackup {
+		res := databasev1alpha1.ValkeyBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: defaultNamespace},
+			Status:     databasev1alpha1.ValkeyBackupStatus{Phase: phase},
+		}
+		if phase.IsFinished() {
+			res.Status.CompletedAt = &metav1.Time{Time: now.Add(-age)}
+		}
+		if phase == databasev1alpha1.TypeBackupPhaseCompleted {
+			res.Status.Location = "pvc://backups/" + name + ".rdb"
+		}
+
+		return res
+	}
+
+	listBackups := func(backups ...databasev1alpha1.ValkeyBackup) {
+		mockK8sClient.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&databasev1alpha1.ValkeyBackupList{}), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+				obj.(*databasev1alpha1.ValkeyBackupList).Items = backups
+				return nil
+			})
+	}
+
+	deleteBackup := func(name string) {
+		mockK8sClient.EXPECT().Delete(gomock.Any(), gomock.AssignableToTypeOf(&databasev1alpha1.ValkeyBackup{})).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.Object, _ ...runtimeclient.DeleteOption) error {
+				require.Equal(t, name, obj.GetName())
+				return nil
+			})
+	}
+
+	t.Run("backup is created", func(t *testing.T) {
+		listBackups(backup("daily-1", databasev1alpha1.TypeBackupPhaseCompleted, time.Hour))
+		mockK8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&databasev1alpha1.ValkeyBackup{})).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.Object, _ ...runtimeclient.CreateOption) error {
+				item := obj.(*databasev1alpha1.ValkeyBackup)
+				require.Regexp(t, `^daily-\d+$`, item.Name)
+				require.Equal(t, resourceName, item.Labels[databasev1alpha1.LabelBackupSchedule])
+				// backups outlive the schedule
+				require.Empty(t, item.OwnerReferences)
+				require.Equal(t, "valkey", item.Spec.ValkeyName)
+				return nil
+			})
+		listBackups(backup("daily-1", databasev1alpha1.TypeBackupPhaseCompleted, time.Hour))
+
+		st, finalizers, err := flow.Run(ctx, newSchedule(databasev1alpha1.ValkeyBackupScheduleSpec{}))
+		require.NoError(t, err)
+		require.Empty(t, finalizers)
+		require.Len(t, st.Active, 1)
+		require.True(t, st.LastScheduleAt.After(lastScheduleAt.Time))
+		require.True(t, st.NextScheduleAt.After(now))
+		require.WithinDuration(t, now.Add(-time.Hour), st.LastSuccessAt.Time, time.Second)
+		require.Nil(t, st.LastFailureAt)
+		require.True(t, meta.IsStatusConditionTrue(st.Conditions, databasev1alpha1.ConditionScheduled))
+		require.Equal(t, databasev1alpha1.TypeStepStateDone, st.Steps[0].State)
+	})
+
+	t.Run("nothing is scheduled", func(t *testing.T) {
+		item := newSchedule(databasev1alpha1.ValkeyBackupScheduleSpec{})
+		justScheduled := metav1.NewTime(now.Add(time.Minute))
+		item.Status.LastScheduleAt = &justScheduled
+
+		listBackups()
+		listBackups()
+
+		st, _, err := flow.Run(ctx, item)
+		require.NoError(t, err)
+		require.Equal(t, &justScheduled, st.LastScheduleAt)
+		require.Empty(t, st.Active)
+	})
+
+	t.Run("backup is skipped while previous one is running", func(t *testing.T) {
+		running := backup("daily-1", databasev1alpha1.TypeBackupPhaseCopying, 0)
+		listBackups(running)
+		listBackups(running)
+
+		st, _, err := flow.Run(ctx, newSchedule(databasev1alpha1.ValkeyBackupScheduleSpec{}))
+		require.NoError(t, err)
+		require.Equal(t, []string{"daily-1"}, st.Active)
+		// skipped run isn't retried
+		require.True(t, st.LastScheduleAt.After(lastScheduleAt.Time))
+	})
+
+	t.Run("running backup is replaced", func(t *testing.T) {
+		running := backup("daily-1", databasev1alpha1.TypeBackupPhaseSaving, 0)
+		listBackups(running)
+		deleteBackup("daily-1")
+		mockK8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+		listBackups()
+
+		st, _, err := flow.Run(ctx, newSchedule(databasev1alpha1.ValkeyBackupScheduleSpec{
+			ConcurrencyPolicy: databasev1alpha1.TypeConcurrencyPolicyReplace,
+		}))
+		require.NoError(t, err)
+		require.Len(t, st.Active, 1)
+		require.NotEqual(t, "daily-1", st.Active[0])
+	})
+
+	t.Run("backups run concurrently", func(t *testing.T) {
+		listBackups(backup("daily-1", databasev1alpha1.TypeBackupPhaseSaving, 0))
+		mockK8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+		listBackups()
+
+		st, _, err := flow.Run(ctx, newSchedule(databasev1alpha1.ValkeyBackupScheduleSpec{
+			ConcurrencyPolicy: databasev1alpha1.TypeConcurrencyPolicyAllow,
+		}))
+		require.NoError(t, err)
+		require.Len(t, st.Active, 2)
+	})
+
+	t.Run("schedule is suspended", func(t *testing.T) {
+		listBackups(backup("daily-1", databasev1alpha1.TypeBackupPhaseFailed, time.Minute))
+		listBackups()
+
+		st, _, err := flow.Run(ctx, newSchedule(databasev1alpha1.ValkeyBackupScheduleSpec{Suspend: true}))
+		require.NoError(t, err)
+		require.Nil(t, st.NextScheduleAt)
+		require.Equal(t, &lastScheduleAt, st.LastScheduleAt)
+		require.NotNil(t, st.LastFailureAt)
+		require.Equal(t, databasev1alpha1.ReasonScheduleSuspended,
+			meta.FindStatusCondition(st.Conditions, databasev1alpha1.ConditionScheduled).Reason)
+	})
+
+	t.Run("invalid schedule", func(t *testing.T) {
+		item := newSchedule(databasev1alpha1.ValkeyBackupScheduleSpec{})
+		item.Spec.Schedule = "every day"
+
+		_, _, err := flow.Run(ctx, item)
+		require.Equal(t, flows.ErrorClassValidation, flows.Classify(err))
+	})
+
+	t.Run("backups are pruned by count", func(t *testing.T) {
+		backups := []databasev1alpha1.ValkeyBackup{
+			backup("daily-4", databasev1alpha1.TypeBackupPhaseFailed, time.Minute),
+			backup("daily-3", databasev1alpha1.TypeBackupPhaseCompleted, 2*time.Minute),
+			backup("daily-2", databasev1alpha1.TypeBackupPhaseFailed, 3*time.Minute),
+			backup("daily-1", databasev1alpha1.TypeBackupPhaseCompleted, 4*time.Minute),
+		}
+		item := newSchedule(databasev1alpha1.ValkeyBackupScheduleSpec{
+			Suspend:   true,
+			Retention: databasev1alpha1.BackupRetention{KeepLast: utils.Pointer(int32(1))},
+		})
+
+		listBackups(backups...)
+		listBackups(backups...)
+		// failed backup older than the kept one is deleted without prune job
+		deleteBackup("daily-2")
+		mockBackupSvc.EXPECT().Prune(gomock.Any(), &backupsvc.PruneRequest{
+			Name:        "daily-1",
+			Namespace:   defaultNamespace,
+			Destination: backups[3].Spec.Destination,
+			Owner: &metav1.OwnerReference{
+				APIVersion:         databasev1alpha1.GroupVersion.String(),
+				Kind:               "ValkeyBackupSchedule",
+				Name:               resourceName,
+				UID:                "schedule-uid",
+				Controller:         utils.Pointer(true),
+				BlockOwnerDeletion: utils.Pointer(true),
+			},
+		}).Return(true, nil)
+		deleteBackup("daily-1")
+
+		st, _, err := flow.Run(ctx, item)
+		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.StepStatus{
+			Name:    valkeybackupschedule.StepRetention,
+			State:   databasev1alpha1.TypeStepStateDone,
+			Message: "2 backups are pruned",
+		}, st.Steps[1])
+	})
+
+	t.Run("backups are pruned by age", func(t *testing.T) {
+		backups := []databasev1alpha1.ValkeyBackup{
+			backup("daily-2", databasev1alpha1.TypeBackupPhaseCompleted, time.Hour),
+			backup("daily-1", databasev1alpha1.TypeBackupPhaseCompleted, 3*time.Hour),
+		}
+		item := newSchedule(databasev1alpha1.ValkeyBackupScheduleSpec{
+			Suspend:   true,
+			Retention: databasev1alpha1.BackupRetention{MaxAge: &metav1.Duration{Duration: 2 * time.Hour}},
+		})
+
+		listBackups(backups...)
+		listBackups(backups...)
+		mockBackupSvc.EXPECT().Prune(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, req *backupsvc.PruneRequest) (bool, error) {
+				require.Equal(t, "daily-1", req.Name)
+				return false, nil
+			})
+
+		st, _, err := flow.Run(ctx, item)
+		require.NoError(t, err)
+		require.Equal(t, databasev1alpha1.StepStatus{
+			Name:    valkeybackupschedule.StepRetention,
+			State:   databasev1alpha1.TypeStepStateWaiting,
+			Message: "1 backups are being pruned",
+		}, st.Steps[1])
+	})
+
+	t.Run("prune error", func(t *testing.T) {
+		backups := []databasev1alpha1.ValkeyBackup{
+			backup("daily-1", databasev1alpha1.TypeBackupPhaseCompleted, 3*time.Hour),
+		}
+		item := newSchedule(databasev1alpha1.ValkeyBackupScheduleSpec{
+			Suspend:   true,
+			Retention: databasev1alpha1.BackupRetention{MaxAge: &metav1.Duration{Duration: time.Hour}},
+		})
+
+		listBackups(backups...)
+		listBackups(backups...)
+		mockBackupSvc.EXPECT().Prune(gomock.Any(), gomock.Any()).Return(false, backupsvc.ErrPruneFailed)
+
+		st, _, err := flow.Run(ctx, item)
+		require.Nil(t, st)
+		require.ErrorIs(t, err, backupsvc.ErrPruneFailed)
+
+		var stepErr *flows.StepError
+		require.ErrorAs(t, err, &stepErr)
+		require.Equal(t, valkeybackupschedule.StepRetention, stepErr.Step)
+	})
+
+	t.Run("create backup error", func(t *testing.T) {
+		listBackups()
+		mockK8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(mockErr)
+
+		_, _, err := flow.Run(ctx, newSchedule(databasev1alpha1.ValkeyBackupScheduleSpec{}))
+		require.ErrorIs(t, err, mockErr)
+	})
+}
@@ -0,0 +1,234 @@
+package valkeybackupschedule
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+	"github.com/samber/lo"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/controller/flows"
+	backupsvc "github.com/uagolang/k8s-operator/internal/services/backup"
+)
+
+// schedule creates backup for the latest missed run, earlier missed
+// runs are skipped, so downtime of the operator doesn't cause a burst
+func (r *FlowImpl) schedule(ctx context.Context, item *v1alpha1.ValkeyBackupSchedule, res *v1alpha1.ValkeyBackupScheduleStatus) (flows.Result, error) {
+	sched, err := cron.ParseStandard(item.Spec.Schedule)
+	if err != nil {
+		return flows.Result{}, flows.ValidationError(errors.Wrap(err, "invalid schedule"))
+	}
+
+	backups, err := r.listBackups(ctx, item)
+	if err != nil {
+		return flows.Result{}, err
+	}
+	observeBackups(backups, res)
+
+	if item.Spec.Suspend {
+		res.NextScheduleAt = nil
+		res.SetCondition(v1alpha1.ConditionScheduled, metav1.ConditionFalse, v1alpha1.ReasonScheduleSuspended, "schedule is suspended")
+		return flows.Done("schedule is suspended"), nil
+	}
+
+	now := time.Now()
+	next := metav1.NewTime(sched.Next(now))
+	res.NextScheduleAt = &next
+	res.SetCondition(v1alpha1.ConditionScheduled, metav1.ConditionTrue, v1alpha1.ReasonScheduleActive,
+		"next backup is scheduled at "+next.UTC().Format(time.RFC3339))
+
+	since := item.CreationTimestamp.Time
+	if res.LastScheduleAt != nil {
+		since = res.LastScheduleAt.Time
+	}
+
+	scheduledAt, ok := lastMissed(sched, since, now)
+	if !ok {
+		return flows.Done("nothing is scheduled"), nil
+	}
+
+	if len(res.Active) > 0 {
+		switch lo.CoalesceOrEmpty(item.Spec.ConcurrencyPolicy, v1alpha1.TypeConcurrencyPolicyForbid) {
+		case v1alpha1.TypeConcurrencyPolicyForbid:
+			res.LastScheduleAt = &metav1.Time{Time: scheduledAt}
+			return flows.Done(strings.Join(res.Active, ", ") + " is running, scheduled backup is skipped"), nil
+		case v1alpha1.TypeConcurrencyPolicyReplace:
+			if err = r.deleteBackups(ctx, item, res.Active); err != nil {
+				return flows.Result{}, err
+			}
+			res.Active = nil
+		}
+	}
+
+	backup := newBackup(item, scheduledAt)
+
+	log.FromContext(ctx).Info("creating backup", "backup", backup.Name)
+	err = r.k8sClient.Create(ctx, backup)
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return flows.Result{}, err
+	}
+
+	res.LastScheduleAt = &metav1.Time{Time: scheduledAt}
+	res.Active = append(res.Active, backup.Name)
+
+	return flows.Done(backup.Name + " is created"), nil
+}
+
+// prune removes RDB files of expired backups first, backup is deleted
+// after its file, so a file isn't left without an object pointing to it
+func (r *FlowImpl) prune(ctx context.Context, item *v1alpha1.ValkeyBackupSchedule, _ *v1alpha1.ValkeyBackupScheduleStatus) (flows.Result, error) {
+	backups, err := r.listBackups(ctx, item)
+	if err != nil {
+		return flows.Result{}, err
+	}
+
+	expired := expiredBackups(item.Spec.Retention, backups, time.Now())
+	if len(expired) == 0 {
+		return flows.Done("nothing to prune"), nil
+	}
+
+	var pruning int
+	for _, backup := range expired {
+		if backup.Status.Location != "" {
+			pruned, err := r.backupSvc.Prune(ctx, &backupsvc.PruneRequest{
+				Name:        backup.Name,
+				Namespace:   backup.Namespace,
+				Image:       backup.Spec.Image,
+				Destination: backup.Spec.Destination,
+				Owner:       ownerReference(item),
+			})
+			if err != nil {
+				return flows.Result{}, err
+			}
+			if !pruned {
+				pruning++
+				continue
+			}
+		}
+
+		if err = r.deleteBackups(ctx, item, []string{backup.Name}); err != nil {
+			return flows.Result{}, err
+		}
+	}
+
+	if pruning > 0 {
+		return flows.Wait(fmt.Sprintf("%d backups are being pruned", pruning)), nil
+	}
+
+	return flows.Done(fmt.Sprintf("%d backups are pruned", len(expired))), nil
+}
+
+func (r *FlowImpl) deleteBackups(ctx context.Context, item *v1alpha1.ValkeyBackupSchedule, names []string) error {
+	for _, name := range names {
+		log.FromContext(ctx).Info("deleting backup", "backup", name)
+
+		err := r.k8sClient.Delete(ctx, &v1alpha1.ValkeyBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: item.Namespace},
+		})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// observeBackups reports running backups and the latest finished ones
+func observeBackups(backups []v1alpha1.ValkeyBackup, res *v1alpha1.ValkeyBackupScheduleStatus) {
+	res.Active = nil
+
+	for _, backup := range backups {
+		status := backup.Status
+		switch {
+		case !status.Phase.IsFinished():
+			res.Active = append(res.Active, backup.Name)
+		case status.CompletedAt == nil:
+		case status.Phase == v1alpha1.TypeBackupPhaseCompleted:
+			res.LastSuccessAt = latest(res.LastSuccessAt, status.CompletedAt)
+		case status.Phase == v1alpha1.TypeBackupPhaseFailed:
+			res.LastFailureAt = latest(res.LastFailureAt, status.CompletedAt)
+		}
+	}
+
+	slices.Sort(res.Active)
+}
+
+// expiredBackups returns finished backups which aren't kept by retention
+func expiredBackups(retention v1alpha1.BackupRetention, backups []v1alpha1.ValkeyBackup, now time.Time) []v1alpha1.ValkeyBackup {
+	finished := lo.Filter(backups, func(v v1alpha1.ValkeyBackup, _ int) bool {
+		return v.Status.Phase.IsFinished()
+	})
+	// the newest first
+	slices.SortFunc(finished, func(a, b v1alpha1.ValkeyBackup) int {
+		return finishedAt(b).Compare(finishedAt(a))
+	})
+
+	var res []v1alpha1.ValkeyBackup
+	var completed int32
+	for _, backup := range finished {
+		keepLast := retention.KeepLast != nil
+		isCompleted := backup.Status.Phase == v1alpha1.TypeBackupPhaseCompleted
+		if isCompleted {
+			completed++
+		}
+
+		switch {
+		case retention.MaxAge != nil && now.Sub(finishedAt(backup)) > retention.MaxAge.Duration:
+			res = append(res, backup)
+		case keepLast && isCompleted && completed > *retention.KeepLast:
+			res = append(res, backup)
+		case keepLast && !isCompleted && completed >= *retention.KeepLast:
+			// failed backup is older than every kept completed one
+			res = append(res, backup)
+		}
+	}
+
+	return res
+}
+
+// lastMissed returns the latest run of the schedule after since
+func lastMissed(sched cron.Schedule, since, now time.Time) (time.Time, bool) {
+	var res time.Time
+	for t := sched.Next(since); !t.After(now); t = sched.Next(t) {
+		res = t
+	}
+
+	return res, !res.IsZero()
+}
+
+// newBackup labels the backup with the schedule, it isn't owned by the
+// schedule, so backups and their RDB files outlive removed schedule
+func newBackup(item *v1alpha1.ValkeyBackupSchedule, scheduledAt time.Time) *v1alpha1.ValkeyBackup {
+	return &v1alpha1.ValkeyBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", item.Name, scheduledAt.Unix()),
+			Namespace: item.Namespace,
+			Labels:    map[string]string{v1alpha1.LabelBackupSchedule: item.Name},
+		},
+		Spec: *item.Spec.Template.DeepCopy(),
+	}
+}
+
+func finishedAt(backup v1alpha1.ValkeyBackup) time.Time {
+	if backup.Status.CompletedAt != nil {
+		return backup.Status.CompletedAt.Time
+	}
+
+	return backup.CreationTimestamp.Time
+}
+
+func latest(a, b *metav1.Time) *metav1.Time {
+	if a == nil || b.After(a.Time) {
+		return b.DeepCopy()
+	}
+
+	return a
+}
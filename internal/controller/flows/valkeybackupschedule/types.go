@@ -0,0 +1,7 @@
+package valkeybackupschedule
+
+// Steps of the flow in order they are run
+const (
+	StepSchedule  = "schedule"
+	StepRetention = "retention"
+)
@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/samber/lo"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/uagolang/k8s-operator/internal/controller/flows"
+	"github.com/uagolang/k8s-operator/internal/utils"
+)
+
+// Status is a status of the reconciled object built by flow
+type Status[S any] interface {
+	IsChanged(new S) bool
+	SetLastReconcileAt(t metav1.Time)
+}
+
+// Object is a reconciled object which status is built by flow
+type Object[S any] interface {
+	client.Object
+	GetStatus() S
+	SetStatus(status S)
+}
+
+// Kind describes what differs between reconciled kinds
+type Kind[T Object[S], S Status[S]] interface {
+	// NewObject returns empty object of the kind
+	NewObject() T
+	// FailedStatus returns status which reports flow error
+	FailedStatus(item T, err error) S
+	// RequeueResult returns when object should be reconciled again without events
+	RequeueResult(status S) ctrl.Result
+	// PausedStatus returns status of the object which flow shouldn't be run for,
+	// false is returned if object isn't paused
+	PausedStatus(item T) (S, bool)
+}
+
+// FlowReconciler runs flow for the object and saves
+// finalizers and status returned by it
+type FlowReconciler[T Object[S], S Status[S]] struct {
+	Client  client.Client
+	Flow    flows.Flow[T, S]
+	Kind    Kind[T, S]
+	Backoff *Backoff
+}
+
+func (r *FlowReconciler[T, S]) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var emptyResp ctrl.Result
+
+	item := r.Kind.NewObject()
+	if err := r.Client.Get(ctx, req.NamespacedName, item); err != nil {
+		if k8serrors.IsNotFound(err) {
+			r.Backoff.Reset(req.NamespacedName)
+			return emptyResp, reconcile.TerminalError(err)
+		} else {
+			return emptyResp, err
+		}
+	}
+
+	if status, paused := r.Kind.PausedStatus(item); paused {
+		// object is reconciled again when it's resumed by the user
+		r.Backoff.Reset(req.NamespacedName)
+		if item.GetStatus().IsChanged(status) {
+			status.SetLastReconcileAt(metav1.Now())
+			if err := r.patchStatus(ctx, item, status); err != nil && !k8serrors.IsNotFound(err) {
+				return emptyResp, err
+			}
+		}
+
+		return emptyResp, nil
+	}
+
+	status, finalizers, flowErr := r.Flow.Run(ctx, item)
+	if flowErr != nil {
+		status = r.Kind.FailedStatus(item, flowErr)
+	} else {
+		r.Backoff.Reset(req.NamespacedName)
+	}
+
+	shouldUpdateFinalizers := !utils.SlicesEqualSorted(item.GetFinalizers(), finalizers)
+	if flowErr == nil && shouldUpdateFinalizers {
+		if err := r.patchFinalizers(ctx, item, finalizers); err != nil {
+			if k8serrors.IsNotFound(err) {
+				return emptyResp, reconcile.TerminalError(err)
+			}
+
+			return emptyResp, err
+		}
+		if !item.GetDeletionTimestamp().IsZero() && len(item.GetFinalizers()) == 0 {
+			// object is removed after the last finalizer
+			return emptyResp, nil
+		}
+		// status is saved as well, so the phase reached by the flow isn't lost
+	}
+
+	if item.GetStatus().IsChanged(status) {
+		status.SetLastReconcileAt(metav1.Now())
+		if err := r.patchStatus(ctx, item, status); err != nil {
+			if k8serrors.IsNotFound(err) {
+				return emptyResp, reconcile.TerminalError(err)
+			}
+
+			return emptyResp, err
+		}
+	}
+
+	if flowErr != nil {
+		return r.failedResult(ctx, req, flowErr)
+	}
+
+	return r.Kind.RequeueResult(status), nil
+}
+
+// patchFinalizers adds and removes finalizers of the flow, finalizers
+// of others are kept. Object is read again on conflict, so changes
+// made since it was read aren't overwritten
+func (r *FlowReconciler[T, S]) patchFinalizers(ctx context.Context, item T, finalizers []string) error {
+	added, removed := lo.Difference(finalizers, item.GetFinalizers())
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		orig := item.DeepCopyObject().(T)
+		for _, v := range added {
+			controllerutil.AddFinalizer(item, v)
+		}
+		for _, v := range removed {
+			controllerutil.RemoveFinalizer(item, v)
+		}
+
+		return r.patch(ctx, item, orig, func(p client.Patch) error {
+			return r.Client.Patch(ctx, item, p)
+		})
+	})
+}
+
+// patchStatus saves status computed by the flow, it's written
+// again on conflict on top of the latest object
+func (r *FlowReconciler[T, S]) patchStatus(ctx context.Context, item T, status S) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		orig := item.DeepCopyObject().(T)
+		item.SetStatus(status)
+
+		return r.patch(ctx, item, orig, func(p client.Patch) error {
+			return r.Client.Status().Patch(ctx, item, p)
+		})
+	})
+}
+
+// patch sends merge patch which fails when object was changed
+// after it was read, object is read again in this case
+func (r *FlowReconciler[T, S]) patch(ctx context.Context, item, orig T, send func(p client.Patch) error) error {
+	err := send(client.MergeFromWithOptions(orig, client.MergeFromWithOptimisticLock{}))
+	if !k8serrors.IsConflict(err) {
+		return err
+	}
+
+	if getErr := r.Client.Get(ctx, client.ObjectKeyFromObject(item), item); getErr != nil {
+		return getErr
+	}
+
+	return err
+}
+
+// failedResult stops retries of errors which won't be fixed without
+// changing the object, others are retried with backoff of their class
+func (r *FlowReconciler[T, S]) failedResult(ctx context.Context, req ctrl.Request, err error) (ctrl.Result, error) {
+	class := flows.Classify(err)
+	if class == flows.ErrorClassValidation {
+		r.Backoff.Reset(req.NamespacedName)
+		return ctrl.Result{}, reconcile.TerminalError(err)
+	}
+
+	delay := r.Backoff.Next(req.NamespacedName, class)
+	log.FromContext(ctx).Error(err, "flow failed", "class", class, "retry_after", delay)
+
+	return ctrl.Result{RequeueAfter: delay}, nil
+}
@@ -47,17 +47,21 @@ import (
 // http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
 
 var (
-	cfg              *rest.Config
-	k8sClient        client.Client
-	testEnv          *envtest.Environment
-	controllerValkey *ValkeyReconciler
-	mockErr          = errors.New("mock error")
+	cfg                *rest.Config
+	k8sClient          client.Client
+	testEnv            *envtest.Environment
+	controllerValkey   *ValkeyReconciler
+	controllerBackup   *ValkeyBackupReconciler
+	controllerSchedule *ValkeyBackupScheduleReconciler
+	mockErr            = errors.New("mock error")
 )
 
 var (
 	mockK8sClient       *mocks.MockK8sClient
 	mockK8sStatusClient *mocks.MockK8sStatusClient
-	mockFlow            *mocks.MockFlow
+	mockFlow            *mocks.MockFlow[*databasev1alpha1.Valkey, *databasev1alpha1.ValkeyStatus]
+	mockBackupFlow      *mocks.MockFlow[*databasev1alpha1.ValkeyBackup, *databasev1alpha1.ValkeyBackupStatus]
+	mockScheduleFlow    *mocks.MockFlow[*databasev1alpha1.ValkeyBackupSchedule, *databasev1alpha1.ValkeyBackupScheduleStatus]
 )
 
 func init() {
@@ -112,6 +116,8 @@ var _ = BeforeSuite(func() {
 		// to Update resource Status object
 		WithStatusSubresource(
 			&databasev1alpha1.Valkey{},
+			&databasev1alpha1.ValkeyBackup{},
+			&databasev1alpha1.ValkeyBackupSchedule{},
 		).
 		Build()
 	// just test that fake client was initialized
@@ -121,7 +127,9 @@ var _ = BeforeSuite(func() {
 
 	// init mocks
 	mockK8sClient = mocks.NewMockK8sClient(mockCtrl)
-	mockFlow = mocks.NewMockFlow(mockCtrl)
+	mockFlow = mocks.NewMockFlow[*databasev1alpha1.Valkey, *databasev1alpha1.ValkeyStatus](mockCtrl)
+	mockBackupFlow = mocks.NewMockFlow[*databasev1alpha1.ValkeyBackup, *databasev1alpha1.ValkeyBackupStatus](mockCtrl)
+	mockScheduleFlow = mocks.NewMockFlow[*databasev1alpha1.ValkeyBackupSchedule, *databasev1alpha1.ValkeyBackupScheduleStatus](mockCtrl)
 	mockK8sStatusClient = mocks.NewMockK8sStatusClient(mockCtrl)
 
 	// init crd controllers
@@ -130,6 +138,16 @@ var _ = BeforeSuite(func() {
 		Scheme: k8sClient.Scheme(),
 		Flow:   mockFlow,
 	}
+	controllerBackup = &ValkeyBackupReconciler{
+		Client: k8sClient,
+		Scheme: k8sClient.Scheme(),
+		Flow:   mockBackupFlow,
+	}
+	controllerSchedule = &ValkeyBackupScheduleReconciler{
+		Client: k8sClient,
+		Scheme: k8sClient.Scheme(),
+		Flow:   mockScheduleFlow,
+	}
 })
 
 var _ = AfterSuite(func() {
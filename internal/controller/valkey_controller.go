@@ -18,18 +18,30 @@ package controller
 
 import (
 	"context"
+	"slices"
 	"time"
 
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/uagolang/k8s-operator/api/v1alpha1"
 	"github.com/uagolang/k8s-operator/internal/controller/flows"
-	"github.com/uagolang/k8s-operator/internal/utils"
+	valkeysvc "github.com/uagolang/k8s-operator/internal/services/valkey"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
 	"github.com/uagolang/k8s-operator/mocks"
 )
 
@@ -40,9 +52,20 @@ type ValkeyReconciler struct {
 	fakeClient client.Client
 
 	Scheme *runtime.Scheme
-	Flow   flows.Flow
+	Flow   flows.Flow[*v1alpha1.Valkey, *v1alpha1.ValkeyStatus]
+
+	// ResyncPeriod is a safety net for missed events, healthy Valkey
+	// is reconciled again after it. Zero disables periodic resync.
+	ResyncPeriod time.Duration
+
+	backoff Backoff
 }
 
+// progressRequeuePeriod is used while Valkey isn't running, e.g. cluster
+// slots are moved, which doesn't produce events of child objects.
+// Reconcile never waits for child objects, it's resumed from the phase instead
+const progressRequeuePeriod = 10 * time.Second
+
 func (r *ValkeyReconciler) SetK8sClient(c *mocks.MockK8sClient) {
 	r.fakeClient = r.Client
 	r.Client = c
@@ -66,70 +89,202 @@ func (r *ValkeyReconciler) RollbackK8sClient() {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.17.3/pkg/reconcile
 func (r *ValkeyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	var emptyResp ctrl.Result
-	requeueRes := ctrl.Result{RequeueAfter: 10 * time.Second}
-
-	item := new(v1alpha1.Valkey)
-	if err := r.Get(ctx, req.NamespacedName, item); err != nil {
-		if k8serrors.IsNotFound(err) {
-			return emptyResp, reconcile.TerminalError(err)
-		} else {
-			return emptyResp, err
-		}
+	res := &FlowReconciler[*v1alpha1.Valkey, *v1alpha1.ValkeyStatus]{
+		Client:  r.Client,
+		Flow:    r.Flow,
+		Kind:    r,
+		Backoff: &r.backoff,
 	}
 
-	status := new(v1alpha1.ValkeyStatus)
-	statusItem, finalizers, err := r.Flow.Run(ctx, *item)
-	if err == nil {
-		var ok bool
-		status, ok = statusItem.(*v1alpha1.ValkeyStatus)
-		if !ok {
-			return emptyResp, flows.ErrInvalidOutputType
-		}
-	} else {
-		status = &v1alpha1.ValkeyStatus{
-			Status:          v1alpha1.TypeStatusFailed,
-			LastReconcileAt: utils.Pointer(metav1.Now()),
-			Error:           err.Error(),
-		}
+	return res.Reconcile(ctx, req)
+}
+
+// NewObject returns empty Valkey
+func (r *ValkeyReconciler) NewObject() *v1alpha1.Valkey {
+	return new(v1alpha1.Valkey)
+}
+
+// RequeueResult returns when Valkey should be reconciled again without events
+func (r *ValkeyReconciler) RequeueResult(status *v1alpha1.ValkeyStatus) ctrl.Result {
+	// claims aren't watched, expansion is checked until it's finished,
+	// kubelet updates mounted certificates without events too
+	if status.Phase != v1alpha1.TypePhaseRunning || status.Status != v1alpha1.TypeStatusHealthy ||
+		meta.IsStatusConditionTrue(status.Conditions, v1alpha1.ConditionStorageResizing) ||
+		meta.IsStatusConditionFalse(status.Conditions, v1alpha1.ConditionCertificateReady) {
+		return ctrl.Result{RequeueAfter: progressRequeuePeriod}
 	}
 
-	shouldUpdateFinalizers := !utils.SlicesEqualSorted(item.Finalizers, finalizers)
-	if err == nil && shouldUpdateFinalizers {
-		item.Finalizers = finalizers
-		if err = r.Update(ctx, item); err != nil {
-			if k8serrors.IsNotFound(err) {
-				return emptyResp, reconcile.TerminalError(err)
-			}
+	return ctrl.Result{RequeueAfter: r.ResyncPeriod}
+}
 
-			return emptyResp, err
-		}
+// FailedStatus reports reconcile error, the phase and primary are kept so
+// the next reconcile resumes from them, conditions keep their transition time.
+// Replicas and shards aren't observed by failed reconcile, so they're kept too
+func (r *ValkeyReconciler) FailedStatus(item *v1alpha1.Valkey, err error) *v1alpha1.ValkeyStatus {
+	res := &v1alpha1.ValkeyStatus{
+		Phase:              item.Status.Phase,
+		Primary:            item.Status.Primary,
+		ReadyReplicas:      item.Status.ReadyReplicas,
+		Shards:             slices.Clone(item.Status.Shards),
+		Status:             v1alpha1.TypeStatusFailed,
+		Error:              err.Error(),
+		ObservedGeneration: item.Generation,
+		Conditions:         slices.Clone(item.Status.Conditions),
+		Steps:              failedSteps(item.Status.Steps, err),
+	}
 
-		return emptyResp, nil
+	reason := failedReason(err)
+	res.SetCondition(v1alpha1.ConditionReady, metav1.ConditionFalse, reason, err.Error())
+	res.SetCondition(v1alpha1.ConditionDegraded, metav1.ConditionTrue, reason, err.Error())
+	if errors.Is(err, valkeysvc.ErrPasswordSecretNotFound) {
+		res.SetCondition(v1alpha1.ConditionSecretReady, metav1.ConditionFalse, v1alpha1.ReasonSecretNotFound, err.Error())
 	}
 
-	changed := item.Status.IsChanged(status)
-	if !changed {
-		return requeueRes, nil
+	return res
+}
+
+// PausedStatus keeps status of paused Valkey, only Paused condition is added.
+// Deletion is paused too, so child objects are kept while finalizer is there
+func (r *ValkeyReconciler) PausedStatus(item *v1alpha1.Valkey) (*v1alpha1.ValkeyStatus, bool) {
+	by := item.PausedBy()
+	if by == "" {
+		return nil, false
 	}
 
-	item.Status = *status
-	item.Status.LastReconcileAt = utils.Pointer(metav1.Now())
-	err = r.Status().Update(ctx, item)
-	if err != nil {
-		if k8serrors.IsNotFound(err) {
-			return emptyResp, reconcile.TerminalError(err)
-		}
+	res := item.Status.DeepCopy()
+	res.SetCondition(v1alpha1.ConditionPaused, metav1.ConditionTrue, v1alpha1.ReasonPaused, "reconcile is paused by "+by)
 
-		return emptyResp, err
+	return res, true
+}
+
+// failedSteps marks the failed step, steps after it weren't run,
+// so their results of the previous reconcile are reported as pending
+func failedSteps(steps []v1alpha1.StepStatus, err error) []v1alpha1.StepStatus {
+	res := slices.Clone(steps)
+
+	var stepErr *flows.StepError
+	if !errors.As(err, &stepErr) {
+		return res
 	}
 
-	return emptyResp, nil
+	failed := v1alpha1.StepStatus{Name: stepErr.Step, State: v1alpha1.TypeStepStateFailed, Message: stepErr.Err.Error()}
+	i := slices.IndexFunc(res, func(v v1alpha1.StepStatus) bool { return v.Name == stepErr.Step })
+	if i < 0 {
+		return append(res, failed)
+	}
+
+	res[i] = failed
+	for j := i + 1; j < len(res); j++ {
+		res[j] = v1alpha1.StepStatus{Name: res[j].Name, State: v1alpha1.TypeStepStatePending}
+	}
+
+	return res
+}
+
+// failedReason returns condition reason of the flow error
+func failedReason(err error) string {
+	switch flows.Classify(err) {
+	case flows.ErrorClassValidation:
+		return v1alpha1.ReasonInvalidSpec
+	case flows.ErrorClassForbidden:
+		return v1alpha1.ReasonForbidden
+	case flows.ErrorClassDependency:
+		return v1alpha1.ReasonDependencyNotReady
+	default:
+		return v1alpha1.ReasonReconcileFailed
+	}
 }
 
+// indexSecretNames indexes Valkeys by names of referenced Secrets
+const indexSecretNames = ".spec.secretNames"
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ValkeyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1alpha1.Valkey{}, indexSecretNames, secretNames)
+	if err != nil {
+		return err
+	}
+
+	// only pods of operator workloads carry the app label
+	podPredicate := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		_, ok := obj.GetLabels()[render.LabelApp]
+		return ok
+	})
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.Valkey{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.Secret{}).
+		Owns(&corev1.ConfigMap{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.findValkeysForSecret)).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(r.findValkeyForPod),
+			builder.WithPredicates(podPredicate)).
 		Complete(r)
 }
+
+// findValkeyForPod returns Valkey which owns StatefulSet of the pod,
+// so crashed or restarted pods are noticed without polling
+func (r *ValkeyReconciler) findValkeyForPod(ctx context.Context, obj client.Object) []reconcile.Request {
+	ref := metav1.GetControllerOf(obj)
+	if ref == nil || ref.Kind != "StatefulSet" {
+		return nil
+	}
+
+	sts := new(appsv1.StatefulSet)
+	err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: obj.GetNamespace()}, sts)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			log.FromContext(ctx).Error(err, "failed to get statefulset for pod", "pod", obj.GetName())
+		}
+		return nil
+	}
+
+	owner := metav1.GetControllerOf(sts)
+	if owner == nil || owner.Kind != "Valkey" || owner.APIVersion != v1alpha1.GroupVersion.String() {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Name:      owner.Name,
+		Namespace: obj.GetNamespace(),
+	}}}
+}
+
+// findValkeysForSecret returns Valkeys which take password or certificates
+// from the Secret, so rotated password is propagated to their pods and
+// renewed certificates are reloaded
+func (r *ValkeyReconciler) findValkeysForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	items := new(v1alpha1.ValkeyList)
+	err := r.List(ctx, items,
+		client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{indexSecretNames: obj.GetName()},
+	)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to list valkeys for secret", "secret", obj.GetName())
+		return nil
+	}
+
+	return lo.Map(items.Items, func(item v1alpha1.Valkey, _ int) reconcile.Request {
+		return reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&item)}
+	})
+}
+
+// secretNames returns names of the referenced password and TLS Secrets,
+// the owned password Secret is watched as owned object
+func secretNames(obj client.Object) []string {
+	item, ok := obj.(*v1alpha1.Valkey)
+	if !ok {
+		return nil
+	}
+
+	var res []string
+	if ref := item.Spec.PasswordSecretRef(); ref != nil {
+		res = append(res, ref.Name)
+	}
+	if item.Spec.TLSEnabled() {
+		res = append(res, render.TLSSecret(item.Name, item.Spec.TLS))
+	}
+
+	return res
+}
@@ -18,19 +18,26 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"go.uber.org/mock/gomock"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	databasev1alpha1 "github.com/uagolang/k8s-operator/api/v1alpha1"
 	"github.com/uagolang/k8s-operator/internal/controller/flows"
 	"github.com/uagolang/k8s-operator/internal/controller/flows/valkey"
+	"github.com/uagolang/k8s-operator/internal/utils"
 )
 
 const defaultNamespace = "default"
@@ -56,7 +63,7 @@ var _ = Describe("Valkey Controller", func() {
 			ObjectMeta: resourceObjectMeta,
 			Spec: databasev1alpha1.ValkeySpec{
 				Image:    valkeyImage,
-				Replicas: 1,
+				Replicas: utils.Pointer(int32(1)),
 				User:     "user",
 				Password: "password",
 				Volume: databasev1alpha1.Volume{
@@ -101,6 +108,44 @@ var _ = Describe("Valkey Controller", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("should save phase together with finalizers", func() {
+			mockFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&databasev1alpha1.ValkeyStatus{
+				Phase:  databasev1alpha1.TypePhaseCreating,
+				Status: databasev1alpha1.TypeStatusUpdating,
+			}, []string{valkey.Finalizer}, nil)
+
+			res, err := controllerValkey.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.RequeueAfter).To(Equal(progressRequeuePeriod))
+
+			item := new(databasev1alpha1.Valkey)
+			Expect(controllerValkey.Client.Get(ctx, typeNamespacedName, item)).To(Succeed())
+			Expect(item.Finalizers).To(Equal([]string{valkey.Finalizer}))
+			Expect(item.Status.Phase).To(Equal(databasev1alpha1.TypePhaseCreating))
+		})
+
+		It("should skip flow of paused resource", func() {
+			item := new(databasev1alpha1.Valkey)
+			Expect(k8sClient.Get(ctx, typeNamespacedName, item)).To(Succeed())
+			item.Annotations = map[string]string{databasev1alpha1.AnnotationPaused: "true"}
+			Expect(k8sClient.Update(ctx, item)).To(Succeed())
+
+			// flow isn't expected to be run
+			res, err := controllerValkey.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.RequeueAfter).To(BeZero())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, item)).To(Succeed())
+			cond := meta.FindStatusCondition(item.Status.Conditions, databasev1alpha1.ConditionPaused)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+			Expect(cond.Message).To(ContainSubstring(databasev1alpha1.AnnotationPaused))
+		})
+
 		It("get resource internal error", func() {
 			controllerValkey.SetK8sClient(mockK8sClient)
 			defer controllerValkey.RollbackK8sClient()
@@ -130,16 +175,6 @@ var _ = Describe("Valkey Controller", func() {
 			Expect(err).To(HaveOccurred())
 		})
 
-		It("flow run returns undefined status type error", func() {
-			mockFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&databasev1alpha1.ValkeySpec{}, []string{valkey.Finalizer}, nil)
-
-			_, err := controllerValkey.Reconcile(ctx, reconcile.Request{
-				NamespacedName: typeNamespacedName,
-			})
-			Expect(err).To(HaveOccurred())
-			Expect(err).To(Equal(flows.ErrInvalidOutputType))
-		})
-
 		It("flow run returns internal error", func() {
 			mockFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&databasev1alpha1.ValkeyStatus{}, []string{}, mockErr)
 
@@ -152,10 +187,67 @@ var _ = Describe("Valkey Controller", func() {
 			err = controllerValkey.Client.Get(ctx, typeNamespacedName, res)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(res.Status.Status).To(Equal(databasev1alpha1.TypeStatusFailed))
+			Expect(res.Status.Phase).To(Equal(resource.Status.Phase))
 			Expect(res.Status.Error).To(Equal(mockErr.Error()))
 			Expect(res.Status.LastReconcileAt).NotTo(BeZero())
 		})
 
+		It("flow run returns step error", func() {
+			mockFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(nil, nil, &flows.StepError{
+				Step: valkey.StepPods,
+				Err:  mockErr,
+			})
+
+			_, err := controllerValkey.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			res := new(databasev1alpha1.Valkey)
+			Expect(controllerValkey.Client.Get(ctx, typeNamespacedName, res)).To(Succeed())
+			Expect(res.Status.Steps).To(ContainElement(databasev1alpha1.StepStatus{
+				Name:    valkey.StepPods,
+				State:   databasev1alpha1.TypeStepStateFailed,
+				Message: mockErr.Error(),
+			}))
+		})
+
+		It("invalid spec isn't retried", func() {
+			mockFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(nil, nil, flows.ValidationError(mockErr))
+
+			_, err := controllerValkey.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(errors.Is(err, reconcile.TerminalError(nil))).To(BeTrue())
+
+			res := new(databasev1alpha1.Valkey)
+			Expect(controllerValkey.Client.Get(ctx, typeNamespacedName, res)).To(Succeed())
+			Expect(meta.FindStatusCondition(res.Status.Conditions, databasev1alpha1.ConditionReady).Reason).
+				To(Equal(databasev1alpha1.ReasonInvalidSpec))
+		})
+
+		It("missing dependency is retried with backoff", func() {
+			controllerValkey.backoff = Backoff{Delays: map[flows.ErrorClass]BackoffDelay{
+				flows.ErrorClassDependency: {Base: time.Second, Max: 3 * time.Second},
+			}}
+			defer func() { controllerValkey.backoff = Backoff{} }()
+
+			mockFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(nil, nil, flows.DependencyError(mockErr)).Times(3)
+
+			for _, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+				res, err := controllerValkey.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(res.RequeueAfter).To(Equal(want))
+			}
+
+			item := new(databasev1alpha1.Valkey)
+			Expect(controllerValkey.Client.Get(ctx, typeNamespacedName, item)).To(Succeed())
+			Expect(meta.FindStatusCondition(item.Status.Conditions, databasev1alpha1.ConditionReady).Reason).
+				To(Equal(databasev1alpha1.ReasonDependencyNotReady))
+		})
+
 		It("update resource internal error", func() {
 			controllerValkey.SetK8sClient(mockK8sClient)
 			defer controllerValkey.RollbackK8sClient()
@@ -163,7 +255,7 @@ var _ = Describe("Valkey Controller", func() {
 			mockK8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 			mockFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&databasev1alpha1.ValkeyStatus{}, []string{}, mockErr)
 			mockK8sClient.EXPECT().Status().Return(mockK8sStatusClient)
-			mockK8sStatusClient.EXPECT().Update(gomock.Any(), gomock.Any()).Return(mockErr)
+			mockK8sStatusClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
 
 			_, err := controllerValkey.Reconcile(ctx, reconcile.Request{
 				NamespacedName: typeNamespacedName,
@@ -178,7 +270,7 @@ var _ = Describe("Valkey Controller", func() {
 			mockK8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 			mockFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&databasev1alpha1.ValkeyStatus{}, []string{}, mockErr)
 			mockK8sClient.EXPECT().Status().Return(mockK8sStatusClient)
-			mockK8sStatusClient.EXPECT().Update(gomock.Any(), gomock.Any()).Return(k8serrors.NewNotFound(
+			mockK8sStatusClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(k8serrors.NewNotFound(
 				schema.GroupResource{Group: "", Resource: "valkey"},
 				resourceName,
 			))
@@ -195,7 +287,7 @@ var _ = Describe("Valkey Controller", func() {
 
 			mockK8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 			mockFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&databasev1alpha1.ValkeyStatus{}, []string{valkey.Finalizer}, nil)
-			mockK8sClient.EXPECT().Update(gomock.Any(), gomock.Any()).Return(mockErr)
+			mockK8sClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
 
 			_, err := controllerValkey.Reconcile(ctx, reconcile.Request{
 				NamespacedName: typeNamespacedName,
@@ -209,7 +301,7 @@ var _ = Describe("Valkey Controller", func() {
 
 			mockK8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 			mockFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&databasev1alpha1.ValkeyStatus{}, []string{valkey.Finalizer}, nil)
-			mockK8sClient.EXPECT().Update(gomock.Any(), gomock.Any()).Return(k8serrors.NewNotFound(
+			mockK8sClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(k8serrors.NewNotFound(
 				schema.GroupResource{Group: "", Resource: "valkey"},
 				resourceName,
 			))
@@ -220,11 +312,46 @@ var _ = Describe("Valkey Controller", func() {
 			Expect(err).To(HaveOccurred())
 		})
 
-		It("nothing changed", func() {
+		It("finalizers are patched again on conflict", func() {
 			controllerValkey.SetK8sClient(mockK8sClient)
 			defer controllerValkey.RollbackK8sClient()
 
 			mockK8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+			mockFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&databasev1alpha1.ValkeyStatus{}, []string{valkey.Finalizer}, nil)
+			gomock.InOrder(
+				mockK8sClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(k8serrors.NewConflict(
+					schema.GroupResource{Group: "", Resource: "valkey"},
+					resourceName,
+					mockErr,
+				)),
+				// finalizer added by someone else meanwhile is kept
+				mockK8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+						obj.SetFinalizers([]string{"other"})
+						return nil
+					}),
+				mockK8sClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+						Expect(obj.GetFinalizers()).To(Equal([]string{"other", valkey.Finalizer}))
+						return nil
+					}),
+			)
+
+			_, err := controllerValkey.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("time of the last reconcile doesn't change status", func() {
+			controllerValkey.SetK8sClient(mockK8sClient)
+			defer controllerValkey.RollbackK8sClient()
+
+			mockK8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+					obj.(*databasev1alpha1.Valkey).Status.LastReconcileAt = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+					return nil
+				})
 			mockFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&databasev1alpha1.ValkeyStatus{}, []string{}, nil)
 
 			_, err := controllerValkey.Reconcile(ctx, reconcile.Request{
@@ -232,5 +359,107 @@ var _ = Describe("Valkey Controller", func() {
 			})
 			Expect(err).NotTo(HaveOccurred())
 		})
+
+		It("nothing changed", func() {
+			controllerValkey.SetK8sClient(mockK8sClient)
+			defer controllerValkey.RollbackK8sClient()
+
+			mockK8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+			mockFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&databasev1alpha1.ValkeyStatus{}, []string{}, nil)
+
+			res, err := controllerValkey.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.RequeueAfter).To(Equal(progressRequeuePeriod))
+		})
+
+		It("nothing changed for healthy resource", func() {
+			controllerValkey.SetK8sClient(mockK8sClient)
+			defer controllerValkey.RollbackK8sClient()
+			controllerValkey.ResyncPeriod = time.Hour
+			defer func() { controllerValkey.ResyncPeriod = 0 }()
+
+			healthy := databasev1alpha1.ValkeyStatus{
+				Phase:  databasev1alpha1.TypePhaseRunning,
+				Status: databasev1alpha1.TypeStatusHealthy,
+			}
+			mockK8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ types.NamespacedName, obj client.Object, _ ...client.GetOption) error {
+					obj.(*databasev1alpha1.Valkey).Status = healthy
+					return nil
+				})
+			mockFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&healthy, []string{}, nil)
+
+			res, err := controllerValkey.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.RequeueAfter).To(Equal(time.Hour))
+		})
+
+		It("should map pod to owning Valkey", func() {
+			owner := &databasev1alpha1.Valkey{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, owner)).To(Succeed())
+
+			sts := &appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: defaultNamespace,
+					OwnerReferences: []metav1.OwnerReference{
+						*metav1.NewControllerRef(owner, databasev1alpha1.GroupVersion.WithKind("Valkey")),
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, sts)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(ctx, sts)).To(Succeed()) }()
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName + "-0",
+					Namespace: defaultNamespace,
+					OwnerReferences: []metav1.OwnerReference{
+						*metav1.NewControllerRef(sts, appsv1.SchemeGroupVersion.WithKind("StatefulSet")),
+					},
+				},
+			}
+			Expect(controllerValkey.findValkeyForPod(ctx, pod)).To(Equal([]reconcile.Request{
+				{NamespacedName: typeNamespacedName},
+			}))
+
+			pod.OwnerReferences = nil
+			Expect(controllerValkey.findValkeyForPod(ctx, pod)).To(BeEmpty())
+		})
+
+		It("should keep observed state in failed status", func() {
+			item := &databasev1alpha1.Valkey{Status: databasev1alpha1.ValkeyStatus{
+				Phase:         databasev1alpha1.TypePhaseRunning,
+				ReadyReplicas: 3,
+				Shards:        []databasev1alpha1.ShardStatus{{Index: 0, Status: databasev1alpha1.TypeStatusHealthy}},
+				Steps: []databasev1alpha1.StepStatus{
+					{Name: "resources", State: databasev1alpha1.TypeStepStateDone},
+					{Name: "topology", State: databasev1alpha1.TypeStepStateDone},
+					{Name: "health", State: databasev1alpha1.TypeStepStateDone},
+				},
+			}}
+
+			res := controllerValkey.FailedStatus(item, &flows.StepError{Step: "topology", Err: errors.New("timeout")})
+			Expect(res.ReadyReplicas).To(Equal(int32(3)))
+			Expect(res.Shards).To(Equal(item.Status.Shards))
+			Expect(res.Steps).To(Equal([]databasev1alpha1.StepStatus{
+				{Name: "resources", State: databasev1alpha1.TypeStepStateDone},
+				{Name: "topology", State: databasev1alpha1.TypeStepStateFailed, Message: "timeout"},
+				{Name: "health", State: databasev1alpha1.TypeStepStatePending},
+			}))
+		})
+
+		It("should index referenced secrets", func() {
+			item := &databasev1alpha1.Valkey{ObjectMeta: metav1.ObjectMeta{Name: resourceName}}
+			Expect(secretNames(item)).To(BeEmpty())
+
+			item.Spec.Auth = &databasev1alpha1.Auth{PasswordSecretRef: &databasev1alpha1.SecretKeyRef{Name: "custom"}}
+			item.Spec.TLS = &databasev1alpha1.TLS{Enabled: true}
+			Expect(secretNames(item)).To(Equal([]string{"custom", resourceName + "-tls"}))
+		})
 	})
 })
@@ -0,0 +1,103 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/controller/flows"
+)
+
+// ValkeyBackupReconciler reconciles a ValkeyBackup object
+type ValkeyBackupReconciler struct {
+	client.Client
+
+	Scheme *runtime.Scheme
+	Flow   flows.Flow[*v1alpha1.ValkeyBackup, *v1alpha1.ValkeyBackupStatus]
+
+	backoff Backoff
+}
+
+//+kubebuilder:rbac:groups=database.kuberly.io,resources=valkeybackups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=database.kuberly.io,resources=valkeybackups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
+
+// Reconcile takes the backup, finished backup isn't reconciled anymore
+func (r *ValkeyBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	res := &FlowReconciler[*v1alpha1.ValkeyBackup, *v1alpha1.ValkeyBackupStatus]{
+		Client:  r.Client,
+		Flow:    r.Flow,
+		Kind:    r,
+		Backoff: &r.backoff,
+	}
+
+	return res.Reconcile(ctx, req)
+}
+
+// NewObject returns empty ValkeyBackup
+func (r *ValkeyBackupReconciler) NewObject() *v1alpha1.ValkeyBackup {
+	return new(v1alpha1.ValkeyBackup)
+}
+
+// RequeueResult polls BGSAVE progress, it doesn't produce events,
+// the copy job is watched
+func (r *ValkeyBackupReconciler) RequeueResult(status *v1alpha1.ValkeyBackupStatus) ctrl.Result {
+	if status.Phase.IsFinished() {
+		return ctrl.Result{}
+	}
+
+	return ctrl.Result{RequeueAfter: progressRequeuePeriod}
+}
+
+// FailedStatus reports reconcile error, the phase, pod and start time
+// are kept, so the next reconcile continues the same backup
+func (r *ValkeyBackupReconciler) FailedStatus(item *v1alpha1.ValkeyBackup, err error) *v1alpha1.ValkeyBackupStatus {
+	res := &v1alpha1.ValkeyBackupStatus{
+		ObservedGeneration: item.Generation,
+		Phase:              item.Status.Phase,
+		Error:              err.Error(),
+		Pod:                item.Status.Pod,
+		StartedAt:          item.Status.StartedAt,
+		Conditions:         slices.Clone(item.Status.Conditions),
+		Steps:              failedSteps(item.Status.Steps, err),
+	}
+
+	res.SetCondition(v1alpha1.ConditionCompleted, metav1.ConditionFalse, failedReason(err), err.Error())
+
+	return res
+}
+
+// PausedStatus returns false, backups can't be paused
+func (r *ValkeyBackupReconciler) PausedStatus(_ *v1alpha1.ValkeyBackup) (*v1alpha1.ValkeyBackupStatus, bool) {
+	return nil, false
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ValkeyBackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ValkeyBackup{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	databasev1alpha1 "github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/controller/flows"
+	"github.com/uagolang/k8s-operator/internal/controller/flows/valkeybackup"
+)
+
+var _ = Describe("ValkeyBackup Controller", func() {
+	Context("Resource reconcile process", func() {
+		const resourceName = "test-backup"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: defaultNamespace,
+		}
+
+		resource := &databasev1alpha1.ValkeyBackup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      resourceName,
+				Namespace: defaultNamespace,
+			},
+			Spec: databasev1alpha1.ValkeyBackupSpec{
+				ValkeyName: "test-resource",
+				Destination: databasev1alpha1.BackupDestination{
+					PVC: &databasev1alpha1.PVCDestination{ClaimName: "backups"},
+				},
+			},
+		}
+
+		BeforeEach(func() {
+			By("beforeEach: create ValkeyBackup")
+			resource.ResourceVersion = ""
+			Expect(controllerBackup.Client.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &databasev1alpha1.ValkeyBackup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+
+			By("afterEach: cleanup ValkeyBackup")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should requeue backup in progress", func() {
+			mockBackupFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&databasev1alpha1.ValkeyBackupStatus{
+				Phase: databasev1alpha1.TypeBackupPhaseSaving,
+				Pod:   "test-resource-0",
+			}, nil, nil)
+
+			res, err := controllerBackup.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.RequeueAfter).To(Equal(progressRequeuePeriod))
+
+			item := new(databasev1alpha1.ValkeyBackup)
+			Expect(k8sClient.Get(ctx, typeNamespacedName, item)).To(Succeed())
+			Expect(item.Status.Phase).To(Equal(databasev1alpha1.TypeBackupPhaseSaving))
+			Expect(item.Finalizers).To(BeEmpty())
+		})
+
+		It("should not requeue completed backup", func() {
+			mockBackupFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&databasev1alpha1.ValkeyBackupStatus{
+				Phase:    databasev1alpha1.TypeBackupPhaseCompleted,
+				Location: "pvc://backups/test-backup.rdb",
+				Size:     1024,
+			}, nil, nil)
+
+			res, err := controllerBackup.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.IsZero()).To(BeTrue())
+
+			item := new(databasev1alpha1.ValkeyBackup)
+			Expect(k8sClient.Get(ctx, typeNamespacedName, item)).To(Succeed())
+			Expect(item.Status.Size).To(Equal(int64(1024)))
+		})
+
+		It("should report failed step", func() {
+			mockBackupFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(nil, nil, &flows.StepError{
+				Step: valkeybackup.StepSave,
+				Err:  mockErr,
+			})
+
+			res, err := controllerBackup.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.RequeueAfter).NotTo(BeZero())
+
+			item := new(databasev1alpha1.ValkeyBackup)
+			Expect(k8sClient.Get(ctx, typeNamespacedName, item)).To(Succeed())
+			Expect(item.Status.Error).To(ContainSubstring(mockErr.Error()))
+			Expect(meta.IsStatusConditionFalse(item.Status.Conditions, databasev1alpha1.ConditionCompleted)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,130 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/controller/flows"
+)
+
+// retentionRequeuePeriod is used while schedule is suspended,
+// so backups expired by age are still pruned
+const retentionRequeuePeriod = time.Hour
+
+// ValkeyBackupScheduleReconciler reconciles a ValkeyBackupSchedule object
+type ValkeyBackupScheduleReconciler struct {
+	client.Client
+
+	Scheme *runtime.Scheme
+	Flow   flows.Flow[*v1alpha1.ValkeyBackupSchedule, *v1alpha1.ValkeyBackupScheduleStatus]
+
+	backoff Backoff
+}
+
+//+kubebuilder:rbac:groups=database.kuberly.io,resources=valkeybackupschedules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=database.kuberly.io,resources=valkeybackupschedules/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=database.kuberly.io,resources=valkeybackups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+
+// Reconcile creates scheduled backups and prunes expired ones
+func (r *ValkeyBackupScheduleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	res := &FlowReconciler[*v1alpha1.ValkeyBackupSchedule, *v1alpha1.ValkeyBackupScheduleStatus]{
+		Client:  r.Client,
+		Flow:    r.Flow,
+		Kind:    r,
+		Backoff: &r.backoff,
+	}
+
+	return res.Reconcile(ctx, req)
+}
+
+// NewObject returns empty ValkeyBackupSchedule
+func (r *ValkeyBackupScheduleReconciler) NewObject() *v1alpha1.ValkeyBackupSchedule {
+	return new(v1alpha1.ValkeyBackupSchedule)
+}
+
+// RequeueResult wakes the schedule up at the next run,
+// backups and prune jobs are watched
+func (r *ValkeyBackupScheduleReconciler) RequeueResult(status *v1alpha1.ValkeyBackupScheduleStatus) ctrl.Result {
+	if status.NextScheduleAt == nil {
+		return ctrl.Result{RequeueAfter: retentionRequeuePeriod}
+	}
+
+	return ctrl.Result{RequeueAfter: max(time.Until(status.NextScheduleAt.Time), time.Second)}
+}
+
+// FailedStatus reports reconcile error, time of the last
+// run and results of backups are kept
+func (r *ValkeyBackupScheduleReconciler) FailedStatus(item *v1alpha1.ValkeyBackupSchedule, err error) *v1alpha1.ValkeyBackupScheduleStatus {
+	res := &v1alpha1.ValkeyBackupScheduleStatus{
+		ObservedGeneration: item.Generation,
+		Error:              err.Error(),
+		Active:             slices.Clone(item.Status.Active),
+		LastScheduleAt:     item.Status.LastScheduleAt,
+		NextScheduleAt:     item.Status.NextScheduleAt,
+		LastSuccessAt:      item.Status.LastSuccessAt,
+		LastFailureAt:      item.Status.LastFailureAt,
+		Conditions:         slices.Clone(item.Status.Conditions),
+		Steps:              failedSteps(item.Status.Steps, err),
+	}
+
+	res.SetCondition(v1alpha1.ConditionScheduled, metav1.ConditionFalse, failedReason(err), err.Error())
+
+	return res
+}
+
+// PausedStatus returns false, schedule is stopped by spec.suspend
+// and retention is enforced meanwhile
+func (r *ValkeyBackupScheduleReconciler) PausedStatus(_ *v1alpha1.ValkeyBackupSchedule) (*v1alpha1.ValkeyBackupScheduleStatus, bool) {
+	return nil, false
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ValkeyBackupScheduleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ValkeyBackupSchedule{}).
+		Watches(&v1alpha1.ValkeyBackup{}, handler.EnqueueRequestsFromMapFunc(findScheduleForBackup)).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
+
+// findScheduleForBackup returns schedule which created the backup,
+// backups aren't owned by it, so they're matched by label
+func findScheduleForBackup(_ context.Context, obj client.Object) []reconcile.Request {
+	name := obj.GetLabels()[v1alpha1.LabelBackupSchedule]
+	if name == "" {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Name:      name,
+		Namespace: obj.GetNamespace(),
+	}}}
+}
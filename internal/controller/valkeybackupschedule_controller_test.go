@@ -0,0 +1,122 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	databasev1alpha1 "github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/controller/flows"
+	"github.com/uagolang/k8s-operator/internal/controller/flows/valkeybackupschedule"
+)
+
+var _ = Describe("ValkeyBackupSchedule Controller", func() {
+	Context("Resource reconcile process", func() {
+		const resourceName = "test-schedule"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: defaultNamespace,
+		}
+
+		resource := &databasev1alpha1.ValkeyBackupSchedule{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      resourceName,
+				Namespace: defaultNamespace,
+			},
+			Spec: databasev1alpha1.ValkeyBackupScheduleSpec{
+				Schedule: "0 3 * * *",
+				Template: databasev1alpha1.ValkeyBackupSpec{
+					ValkeyName: "test-resource",
+					Destination: databasev1alpha1.BackupDestination{
+						PVC: &databasev1alpha1.PVCDestination{ClaimName: "backups"},
+					},
+				},
+			},
+		}
+
+		BeforeEach(func() {
+			By("beforeEach: create ValkeyBackupSchedule")
+			resource.ResourceVersion = ""
+			Expect(controllerSchedule.Client.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &databasev1alpha1.ValkeyBackupSchedule{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+
+			By("afterEach: cleanup ValkeyBackupSchedule")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should requeue at the next run", func() {
+			next := metav1.NewTime(time.Now().Add(time.Hour).Truncate(time.Second))
+			mockScheduleFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&databasev1alpha1.ValkeyBackupScheduleStatus{
+				NextScheduleAt: &next,
+			}, nil, nil)
+
+			res, err := controllerSchedule.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.RequeueAfter).To(BeNumerically("~", time.Hour, time.Second))
+
+			item := new(databasev1alpha1.ValkeyBackupSchedule)
+			Expect(k8sClient.Get(ctx, typeNamespacedName, item)).To(Succeed())
+			Expect(item.Status.NextScheduleAt.Equal(&next)).To(BeTrue())
+		})
+
+		It("should requeue suspended schedule for retention", func() {
+			mockScheduleFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(&databasev1alpha1.ValkeyBackupScheduleStatus{}, nil, nil)
+
+			res, err := controllerSchedule.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.RequeueAfter).To(Equal(retentionRequeuePeriod))
+		})
+
+		It("should report failed step", func() {
+			mockScheduleFlow.EXPECT().Run(gomock.Any(), gomock.Any()).Return(nil, nil, &flows.StepError{
+				Step: valkeybackupschedule.StepRetention,
+				Err:  mockErr,
+			})
+
+			res, err := controllerSchedule.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.RequeueAfter).NotTo(BeZero())
+
+			item := new(databasev1alpha1.ValkeyBackupSchedule)
+			Expect(k8sClient.Get(ctx, typeNamespacedName, item)).To(Succeed())
+			Expect(item.Status.Error).To(ContainSubstring(mockErr.Error()))
+			Expect(meta.IsStatusConditionFalse(item.Status.Conditions, databasev1alpha1.ConditionScheduled)).To(BeTrue())
+		})
+	})
+})
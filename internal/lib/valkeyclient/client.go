@@ -0,0 +1,445 @@
+package valkeyclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/samber/lo"
+)
+
+const (
+	RolePrimary = "master"
+	RoleReplica = "slave"
+)
+
+// SlotsCount is a number of hash slots in Valkey Cluster
+const SlotsCount = 16384
+
+// migrateBatch is a number of keys moved by single MIGRATE command
+const migrateBatch = 100
+
+const migrateTimeout = 5 * time.Second
+
+var ErrUnexpectedReply = errors.New("unexpected reply")
+
+// Options describes how to reach single Valkey server
+type Options struct {
+	Addr     string
+	Password string
+	// TLS is used to connect to TLS port, nil means plaintext connection
+	TLS *tls.Config
+}
+
+// Role is a parsed reply of ROLE command
+type Role struct {
+	Role string
+	// Offset is a replication offset, it's zero on the primary which
+	// has no replicated data yet, e.g. restarted without volume
+	Offset int64
+	// PrimaryHost and PrimaryPort are filled only for replicas
+	PrimaryHost string
+	PrimaryPort int
+	// LinkState is a replication link state, e.g. 'connected'
+	LinkState string
+}
+
+// SlotRange is an inclusive range of hash slots
+type SlotRange struct {
+	Start int
+	End   int
+}
+
+// ClusterNode is a parsed line of CLUSTER NODES reply
+type ClusterNode struct {
+	ID   string
+	Addr string
+	// Flags are e.g. 'myself', 'master', 'slave', 'fail'
+	Flags []string
+	// PrimaryID is filled only for replicas
+	PrimaryID string
+	LinkState string
+	Slots     []SlotRange
+}
+
+func (n *ClusterNode) HasFlag(flag string) bool {
+	return slices.Contains(n.Flags, flag)
+}
+
+func (n *ClusterNode) IsPrimary() bool {
+	return n.HasFlag(RolePrimary)
+}
+
+func (n *ClusterNode) IsFailed() bool {
+	return n.HasFlag("fail") || n.HasFlag("noaddr")
+}
+
+// SlotsCount returns number of slots owned by the node
+func (n *ClusterNode) SlotsCount() int {
+	var res int
+	for _, r := range n.Slots {
+		res += r.End - r.Start + 1
+	}
+
+	return res
+}
+
+// Persistence is a parsed 'persistence' section of INFO reply
+type Persistence struct {
+	// BGSaveInProgress means that RDB file is being written
+	BGSaveInProgress bool
+	// LastSaveAt is a time of the last successful save
+	LastSaveAt time.Time
+	// LastBGSaveOK is false if the last BGSAVE failed
+	LastBGSaveOK bool
+}
+
+type Client interface {
+	Role(ctx context.Context, opts Options) (*Role, error)
+	ReplicaOf(ctx context.Context, opts Options, host string, port int) error
+	ReplicaOfNoOne(ctx context.Context, opts Options) error
+	// SentinelPrimary returns address of the primary monitored by Sentinel
+	// under given name, empty host is returned if Sentinel doesn't know it
+	SentinelPrimary(ctx context.Context, opts Options, name string) (string, int, error)
+	// ClusterNodes returns cluster members as seen by the node,
+	// the node itself is marked with 'myself' flag
+	ClusterNodes(ctx context.Context, opts Options) ([]ClusterNode, error)
+	ClusterMeet(ctx context.Context, opts Options, host string, port int) error
+	ClusterAddSlotsRange(ctx context.Context, opts Options, slots SlotRange) error
+	ClusterReplicate(ctx context.Context, opts Options, nodeID string) error
+	ClusterForget(ctx context.Context, opts Options, nodeID string) error
+	// MigrateSlot moves slot with all its keys from one primary to another
+	MigrateSlot(ctx context.Context, from, to Options, slot int) error
+	// BGSave starts writing RDB file in background
+	BGSave(ctx context.Context, opts Options) error
+	Persistence(ctx context.Context, opts Options) (*Persistence, error)
+	// ConfigSet changes parameters of running server in a single command
+	ConfigSet(ctx context.Context, opts Options, params map[string]string) error
+	// Certificate returns leaf certificate presented on TLS port, it isn't
+	// verified, so expired certificate is returned too
+	Certificate(ctx context.Context, opts Options) (*x509.Certificate, error)
+}
+
+type client struct{}
+
+func New() Client {
+	return new(client)
+}
+
+func (c *client) conn(opts Options) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:      opts.Addr,
+		Password:  opts.Password,
+		TLSConfig: opts.TLS,
+	})
+}
+
+func (c *client) do(ctx context.Context, opts Options, args ...any) (any, error) {
+	rdb := c.conn(opts)
+	defer rdb.Close()
+
+	return rdb.Do(ctx, args...).Result()
+}
+
+func (c *client) Role(ctx context.Context, opts Options) (*Role, error) {
+	reply, err := c.do(ctx, opts, "ROLE")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRole(reply)
+}
+
+func (c *client) ReplicaOf(ctx context.Context, opts Options, host string, port int) error {
+	_, err := c.do(ctx, opts, "REPLICAOF", host, strconv.Itoa(port))
+	return err
+}
+
+func (c *client) ReplicaOfNoOne(ctx context.Context, opts Options) error {
+	_, err := c.do(ctx, opts, "REPLICAOF", "NO", "ONE")
+	return err
+}
+
+func (c *client) SentinelPrimary(ctx context.Context, opts Options, name string) (string, int, error) {
+	reply, err := c.do(ctx, opts, "SENTINEL", "GET-MASTER-ADDR-BY-NAME", name)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", 0, nil
+		}
+
+		return "", 0, err
+	}
+
+	items, ok := reply.([]any)
+	if !ok || len(items) != 2 {
+		return "", 0, errors.Wrapf(ErrUnexpectedReply, "sentinel primary: %v", reply)
+	}
+
+	port, err := strconv.Atoi(fmt.Sprint(items[1]))
+	if err != nil {
+		return "", 0, errors.Wrapf(ErrUnexpectedReply, "sentinel primary port: %v", items[1])
+	}
+
+	return fmt.Sprint(items[0]), port, nil
+}
+
+func (c *client) ClusterNodes(ctx context.Context, opts Options) ([]ClusterNode, error) {
+	reply, err := c.do(ctx, opts, "CLUSTER", "NODES")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseClusterNodes(fmt.Sprint(reply))
+}
+
+func (c *client) ClusterMeet(ctx context.Context, opts Options, host string, port int) error {
+	_, err := c.do(ctx, opts, "CLUSTER", "MEET", host, strconv.Itoa(port))
+	return err
+}
+
+func (c *client) ClusterAddSlotsRange(ctx context.Context, opts Options, slots SlotRange) error {
+	_, err := c.do(ctx, opts, "CLUSTER", "ADDSLOTSRANGE", strconv.Itoa(slots.Start), strconv.Itoa(slots.End))
+	return err
+}
+
+func (c *client) ClusterReplicate(ctx context.Context, opts Options, nodeID string) error {
+	_, err := c.do(ctx, opts, "CLUSTER", "REPLICATE", nodeID)
+	return err
+}
+
+func (c *client) ClusterForget(ctx context.Context, opts Options, nodeID string) error {
+	_, err := c.do(ctx, opts, "CLUSTER", "FORGET", nodeID)
+	return err
+}
+
+func (c *client) MigrateSlot(ctx context.Context, from, to Options, slot int) error {
+	host, port, err := net.SplitHostPort(to.Addr)
+	if err != nil {
+		return err
+	}
+
+	src := c.conn(from)
+	defer src.Close()
+	dst := c.conn(to)
+	defer dst.Close()
+
+	srcID, err := src.Do(ctx, "CLUSTER", "MYID").Text()
+	if err != nil {
+		return err
+	}
+	dstID, err := dst.Do(ctx, "CLUSTER", "MYID").Text()
+	if err != nil {
+		return err
+	}
+
+	slotArg := strconv.Itoa(slot)
+	if err = dst.Do(ctx, "CLUSTER", "SETSLOT", slotArg, "IMPORTING", srcID).Err(); err != nil {
+		return err
+	}
+	if err = src.Do(ctx, "CLUSTER", "SETSLOT", slotArg, "MIGRATING", dstID).Err(); err != nil {
+		return err
+	}
+
+	for {
+		keys, err := src.Do(ctx, "CLUSTER", "GETKEYSINSLOT", slotArg, migrateBatch).StringSlice()
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			break
+		}
+
+		args := []any{"MIGRATE", host, port, "", 0, migrateTimeout.Milliseconds(), "REPLACE"}
+		if to.Password != "" {
+			args = append(args, "AUTH", to.Password)
+		}
+		args = append(args, "KEYS")
+		for _, key := range keys {
+			args = append(args, key)
+		}
+
+		if err = src.Do(ctx, args...).Err(); err != nil {
+			return err
+		}
+	}
+
+	// destination is notified first, so slot is never left without owner
+	if err = dst.Do(ctx, "CLUSTER", "SETSLOT", slotArg, "NODE", dstID).Err(); err != nil {
+		return err
+	}
+
+	return src.Do(ctx, "CLUSTER", "SETSLOT", slotArg, "NODE", dstID).Err()
+}
+
+func (c *client) BGSave(ctx context.Context, opts Options) error {
+	_, err := c.do(ctx, opts, "BGSAVE")
+	return err
+}
+
+func (c *client) Persistence(ctx context.Context, opts Options) (*Persistence, error) {
+	reply, err := c.do(ctx, opts, "INFO", "persistence")
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePersistence(fmt.Sprint(reply))
+}
+
+func (c *client) ConfigSet(ctx context.Context, opts Options, params map[string]string) error {
+	args := []any{"CONFIG", "SET"}
+	keys := lo.Keys(params)
+	slices.Sort(keys)
+	for _, key := range keys {
+		args = append(args, key, params[key])
+	}
+
+	_, err := c.do(ctx, opts, args...)
+	return err
+}
+
+func (c *client) Certificate(ctx context.Context, opts Options) (*x509.Certificate, error) {
+	cfg := new(tls.Config)
+	if opts.TLS != nil {
+		cfg = opts.TLS.Clone()
+	}
+	// nothing is sent over the connection, certificate is only compared
+	cfg.InsecureSkipVerify = true //nolint:gosec
+
+	dialer := &tls.Dialer{Config: cfg}
+	conn, err := dialer.DialContext(ctx, "tcp", opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.Wrapf(ErrUnexpectedReply, "no certificate is presented by %s", opts.Addr)
+	}
+
+	return certs[0], nil
+}
+
+func parseClusterNodes(reply string) ([]ClusterNode, error) {
+	var res []ClusterNode
+	for _, line := range strings.Split(strings.TrimSpace(reply), "\n") {
+		// <id> <ip:port@cport[,hostname]> <flags> <primary> <ping-sent> <pong-recv> <epoch> <link-state> <slot>...
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			return nil, errors.Wrapf(ErrUnexpectedReply, "cluster nodes: %s", line)
+		}
+
+		node := ClusterNode{
+			ID:        fields[0],
+			Addr:      strings.SplitN(strings.SplitN(fields[1], ",", 2)[0], "@", 2)[0],
+			Flags:     strings.Split(fields[2], ","),
+			LinkState: fields[7],
+		}
+		if fields[3] != "-" {
+			node.PrimaryID = fields[3]
+		}
+
+		for _, slot := range fields[8:] {
+			// slots in migration look like '[slot->-id]'
+			if strings.HasPrefix(slot, "[") {
+				continue
+			}
+
+			start, end, _ := strings.Cut(slot, "-")
+			if end == "" {
+				end = start
+			}
+
+			startNum, err := strconv.Atoi(start)
+			if err != nil {
+				return nil, errors.Wrapf(ErrUnexpectedReply, "cluster nodes slot: %s", slot)
+			}
+			endNum, err := strconv.Atoi(end)
+			if err != nil {
+				return nil, errors.Wrapf(ErrUnexpectedReply, "cluster nodes slot: %s", slot)
+			}
+			node.Slots = append(node.Slots, SlotRange{Start: startNum, End: endNum})
+		}
+
+		res = append(res, node)
+	}
+
+	return res, nil
+}
+
+func parseRole(reply any) (*Role, error) {
+	items, ok := reply.([]any)
+	if !ok || len(items) == 0 {
+		return nil, errors.Wrapf(ErrUnexpectedReply, "role: %v", reply)
+	}
+
+	res := &Role{Role: fmt.Sprint(items[0])}
+	if res.Role != RoleReplica {
+		// primary reply: [master, offset, replicas]
+		if res.Role == RolePrimary && len(items) > 1 {
+			offset, err := strconv.ParseInt(fmt.Sprint(items[1]), 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(ErrUnexpectedReply, "role offset: %v", items[1])
+			}
+			res.Offset = offset
+		}
+
+		return res, nil
+	}
+
+	// replica reply: [slave, host, port, state, offset]
+	if len(items) < 4 {
+		return nil, errors.Wrapf(ErrUnexpectedReply, "role: %v", reply)
+	}
+	if len(items) > 4 {
+		offset, err := strconv.ParseInt(fmt.Sprint(items[4]), 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(ErrUnexpectedReply, "role offset: %v", items[4])
+		}
+		res.Offset = offset
+	}
+
+	port, err := strconv.Atoi(fmt.Sprint(items[2]))
+	if err != nil {
+		return nil, errors.Wrapf(ErrUnexpectedReply, "role port: %v", items[2])
+	}
+
+	res.PrimaryHost = fmt.Sprint(items[1])
+	res.PrimaryPort = port
+	res.LinkState = fmt.Sprint(items[3])
+
+	return res, nil
+}
+
+func parsePersistence(reply string) (*Persistence, error) {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(reply, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if ok {
+			fields[key] = value
+		}
+	}
+
+	inProgress, ok := fields["rdb_bgsave_in_progress"]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnexpectedReply, "persistence: %s", reply)
+	}
+	lastSave, err := strconv.ParseInt(fields["rdb_last_save_time"], 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(ErrUnexpectedReply, "persistence last save: %s", fields["rdb_last_save_time"])
+	}
+
+	return &Persistence{
+		BGSaveInProgress: inProgress == "1",
+		LastSaveAt:       time.Unix(lastSave, 0),
+		LastBGSaveOK:     fields["rdb_last_bgsave_status"] == "ok",
+	}, nil
+}
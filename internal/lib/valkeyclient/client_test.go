@@ -0,0 +1,184 @@
+package valkeyclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   any
+		want    *Role
+		wantErr bool
+	}{
+		{
+			name:  "primary",
+			reply: []any{"master", int64(3129659), []any{}},
+			want:  &Role{Role: RolePrimary, Offset: 3129659},
+		},
+		{
+			name:  "replica",
+			reply: []any{"slave", "db-0.db.default.svc", int64(6379), "connected", int64(3167038)},
+			want: &Role{
+				Role:        RoleReplica,
+				Offset:      3167038,
+				PrimaryHost: "db-0.db.default.svc",
+				PrimaryPort: 6379,
+				LinkState:   "connected",
+			},
+		},
+		{
+			name:    "invalid reply",
+			reply:   "OK",
+			wantErr: true,
+		},
+		{
+			name:    "short replica reply",
+			reply:   []any{"slave", "db-0"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRole(tt.reply)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnexpectedReply) {
+					t.Errorf("parseRole() error = %v, want %v", err, ErrUnexpectedReply)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRole() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRole() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClusterNodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    []ClusterNode
+		wantErr bool
+	}{
+		{
+			name: "primary and replica",
+			reply: "07c37dfeb235213a872192d90877d0cd55635b91 10.0.0.2:6379@16379,db-1 slave e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 0 1426238317239 4 connected\n" +
+				"e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 10.0.0.1:6379@16379 myself,master - 0 0 1 connected 0-5460 5462 [5461->-07c37dfeb235213a872192d90877d0cd55635b91]\n",
+			want: []ClusterNode{
+				{
+					ID:        "07c37dfeb235213a872192d90877d0cd55635b91",
+					Addr:      "10.0.0.2:6379",
+					Flags:     []string{"slave"},
+					PrimaryID: "e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca",
+					LinkState: "connected",
+				},
+				{
+					ID:        "e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca",
+					Addr:      "10.0.0.1:6379",
+					Flags:     []string{"myself", "master"},
+					LinkState: "connected",
+					Slots:     []SlotRange{{Start: 0, End: 5460}, {Start: 5462, End: 5462}},
+				},
+			},
+		},
+		{
+			name:    "short line",
+			reply:   "e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 10.0.0.1:6379@16379 myself,master",
+			wantErr: true,
+		},
+		{
+			name:    "invalid slot",
+			reply:   "e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 10.0.0.1:6379@16379 myself,master - 0 0 1 connected a-b",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseClusterNodes(tt.reply)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnexpectedReply) {
+					t.Errorf("parseClusterNodes() error = %v, want %v", err, ErrUnexpectedReply)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseClusterNodes() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseClusterNodes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePersistence(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    *Persistence
+		wantErr bool
+	}{
+		{
+			name: "saved",
+			reply: "# Persistence\r\nloading:0\r\nrdb_changes_since_last_save:0\r\n" +
+				"rdb_bgsave_in_progress:0\r\nrdb_last_save_time:1730000000\r\nrdb_last_bgsave_status:ok\r\n",
+			want: &Persistence{LastSaveAt: time.Unix(1730000000, 0), LastBGSaveOK: true},
+		},
+		{
+			name: "in progress after failure",
+			reply: "# Persistence\r\nrdb_bgsave_in_progress:1\r\n" +
+				"rdb_last_save_time:1730000000\r\nrdb_last_bgsave_status:err\r\n",
+			want: &Persistence{BGSaveInProgress: true, LastSaveAt: time.Unix(1730000000, 0)},
+		},
+		{
+			name:    "empty reply",
+			reply:   "",
+			wantErr: true,
+		},
+		{
+			name:    "invalid last save",
+			reply:   "rdb_bgsave_in_progress:0\r\nrdb_last_save_time:now\r\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePersistence(tt.reply)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnexpectedReply) {
+					t.Errorf("parsePersistence() error = %v, want %v", err, ErrUnexpectedReply)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePersistence() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePersistence() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCertificate(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	got, err := New().Certificate(context.Background(), Options{Addr: srv.Listener.Addr().String()})
+	if err != nil {
+		t.Fatalf("Certificate() unexpected error = %v", err)
+	}
+	if !got.Equal(srv.Certificate()) {
+		t.Errorf("Certificate() = %v, want %v", got.Subject, srv.Certificate().Subject)
+	}
+}
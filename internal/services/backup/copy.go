@@ -0,0 +1,276 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/lib/validator"
+	"github.com/uagolang/k8s-operator/internal/utils"
+)
+
+type CopyRequest struct {
+	Name        string                     `json:"name" validate:"required"`
+	Namespace   string                     `json:"namespace" validate:"required"`
+	Pod         string                     `json:"pod" validate:"required"`
+	Image       string                     `json:"image" validate:"required"`
+	Destination v1alpha1.BackupDestination `json:"destination"`
+	Owner       *metav1.OwnerReference     `json:"owner" validate:"required"`
+}
+
+// CopyResult describes RDB file copied to the destination
+type CopyResult struct {
+	Location string
+	Size     int64
+	Checksum string
+}
+
+// copyScript copies RDB file aside first, so size and checksum match
+// the copied file even if BGSAVE rewrites it meanwhile. Result is written
+// to termination message of the container, it's read by operator
+const copyScript = `set -eu
+cp /data/` + rdbFile + ` /tmp/` + rdbFile + `
+size=$(stat -c %s /tmp/` + rdbFile + `)
+checksum=$(sha256sum /tmp/` + rdbFile + ` | cut -d ' ' -f 1)
+if [ -n "${BACKUP_FILE:-}" ]; then
+  mkdir -p "$(dirname "$BACKUP_FILE")"
+  cp /tmp/` + rdbFile + ` "$BACKUP_FILE"
+else
+  curl -fsS --aws-sigv4 "aws:amz:${S3_REGION}:s3" --user "${S3_ACCESS_KEY}:${S3_SECRET_KEY}" -T /tmp/` + rdbFile + ` "$S3_URL"
+fi
+printf '{"size":%s,"checksum":"%s"}' "$size" "$checksum" > /dev/termination-log
+`
+
+// Copy runs the job which copies RDB file of the pod to the destination,
+// nil result is returned until the job is finished
+func (s *backupService) Copy(ctx context.Context, i *CopyRequest) (*CopyResult, error) {
+	if err := validator.Validate(ctx, i); err != nil {
+		return nil, err
+	}
+
+	job := new(batchv1.Job)
+	err := s.k8sClient.Get(ctx, types.NamespacedName{
+		Name:      jobName(i.Name),
+		Namespace: i.Namespace,
+	}, job)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		return nil, s.createCopyJob(ctx, i)
+	}
+
+	failed, ok := lo.Find(job.Status.Conditions, func(c batchv1.JobCondition) bool {
+		return c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue
+	})
+	if ok {
+		return nil, errors.Wrap(ErrCopyFailed, failed.Message)
+	}
+	if job.Status.Succeeded == 0 {
+		return nil, nil
+	}
+
+	res, err := s.copyResult(ctx, i)
+	if err != nil {
+		return nil, err
+	}
+	res.Location = location(i)
+
+	return res, nil
+}
+
+// createCopyJob runs the job on the node of the pod, so data volume
+// with ReadWriteOnce access mode is mounted while the pod uses it
+func (s *backupService) createCopyJob(ctx context.Context, i *CopyRequest) error {
+	pod := new(corev1.Pod)
+	err := s.k8sClient.Get(ctx, types.NamespacedName{
+		Name:      i.Pod,
+		Namespace: i.Namespace,
+	}, pod)
+	if err != nil {
+		return err
+	}
+
+	claim, ok := lo.Find(pod.Spec.Volumes, func(v corev1.Volume) bool {
+		return v.PersistentVolumeClaim != nil
+	})
+	if !ok {
+		return errors.Wrap(ErrNoPersistentVolume, i.Pod)
+	}
+
+	job := copyJob(i, pod.Spec.NodeName, claim.PersistentVolumeClaim.ClaimName)
+
+	log.FromContext(ctx).Info("creating copy job", "job", job.Name, "pod", i.Pod)
+	err = s.k8sClient.Create(ctx, job)
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	return nil
+}
+
+// copyResult reads size and checksum reported by the succeeded pod of the job
+func (s *backupService) copyResult(ctx context.Context, i *CopyRequest) (*CopyResult, error) {
+	pods := new(corev1.PodList)
+	err := s.k8sClient.List(ctx, pods,
+		client.InNamespace(i.Namespace),
+		client.MatchingLabels{LabelBackup: i.Name},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated == nil {
+				continue
+			}
+
+			var res struct {
+				Size     int64  `json:"size"`
+				Checksum string `json:"checksum"`
+			}
+			err = json.Unmarshal([]byte(status.State.Terminated.Message), &res)
+			if err != nil {
+				return nil, errors.Wrapf(ErrCopyResultNotParsed, "%s: %s", pod.Name, err)
+			}
+
+			return &CopyResult{Size: res.Size, Checksum: res.Checksum}, nil
+		}
+	}
+
+	return nil, errors.Wrap(ErrCopyResultNotParsed, "succeeded pod of the job not found")
+}
+
+func copyJob(i *CopyRequest, node, claim string) *batchv1.Job {
+	labels := map[string]string{LabelBackup: i.Name}
+
+	volumes := []corev1.Volume{
+		{
+			Name: "data",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: claim,
+					ReadOnly:  true,
+				},
+			},
+		},
+		{
+			Name:         "tmp",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		},
+	}
+	mounts := []corev1.VolumeMount{
+		{Name: "data", MountPath: "/data", ReadOnly: true},
+		{Name: "tmp", MountPath: "/tmp"},
+	}
+
+	destVolumes, destMounts, env := destinationSpec(i.Destination, i.Name)
+	volumes = append(volumes, destVolumes...)
+	mounts = append(mounts, destMounts...)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            jobName(i.Name),
+			Namespace:       i.Namespace,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{*i.Owner},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: utils.Pointer(int32(copyJobBackoffLimit)),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					NodeName:      node,
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:                     "copy",
+						Image:                    i.Image,
+						Command:                  []string{"sh", "-c", copyScript},
+						Env:                      env,
+						VolumeMounts:             mounts,
+						TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+					}},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+}
+
+// destinationSpec returns volume and environment of the job which
+// points it to RDB file of the backup in the destination
+func destinationSpec(dest v1alpha1.BackupDestination, name string) ([]corev1.Volume, []corev1.VolumeMount, []corev1.EnvVar) {
+	switch {
+	case dest.PVC != nil:
+		volumes := []corev1.Volume{{
+			Name: "backup",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: dest.PVC.ClaimName,
+				},
+			},
+		}}
+		mounts := []corev1.VolumeMount{{Name: "backup", MountPath: "/backup"}}
+		env := []corev1.EnvVar{{
+			Name:  "BACKUP_FILE",
+			Value: path.Join("/backup", dest.PVC.FilePath(name)),
+		}}
+
+		return volumes, mounts, env
+	case dest.S3 != nil:
+		env := []corev1.EnvVar{
+			{Name: "S3_URL", Value: dest.S3.URL(name)},
+			{Name: "S3_REGION", Value: lo.CoalesceOrEmpty(dest.S3.Region, "us-east-1")},
+			secretEnv("S3_ACCESS_KEY", dest.S3.AccessKeyRef),
+			secretEnv("S3_SECRET_KEY", dest.S3.SecretKeyRef),
+		}
+
+		return nil, nil, env
+	default:
+		return nil, nil, nil
+	}
+}
+
+func secretEnv(name string, ref v1alpha1.SecretKeyRef) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: ref.Name},
+				Key:                  ref.Key,
+			},
+		},
+	}
+}
+
+// location returns where RDB file of the backup is copied to
+func location(i *CopyRequest) string {
+	if dest := i.Destination.PVC; dest != nil {
+		return "pvc://" + path.Join(dest.ClaimName, dest.FilePath(i.Name))
+	}
+
+	return "s3://" + path.Join(i.Destination.S3.Bucket, i.Destination.S3.ObjectKey(i.Name))
+}
+
+func jobName(name string) string {
+	return name + "-copy"
+}
@@ -0,0 +1,121 @@
+package backup
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/lib/validator"
+	"github.com/uagolang/k8s-operator/internal/utils"
+)
+
+type PruneRequest struct {
+	Name        string                     `json:"name" validate:"required"`
+	Namespace   string                     `json:"namespace" validate:"required"`
+	Image       string                     `json:"image" validate:"required"`
+	Destination v1alpha1.BackupDestination `json:"destination"`
+	Owner       *metav1.OwnerReference     `json:"owner" validate:"required"`
+}
+
+// pruneScript removes RDB file of the backup, missing file isn't an error
+const pruneScript = `set -eu
+if [ -n "${BACKUP_FILE:-}" ]; then
+  rm -f "$BACKUP_FILE"
+else
+  curl -fsS --aws-sigv4 "aws:amz:${S3_REGION}:s3" --user "${S3_ACCESS_KEY}:${S3_SECRET_KEY}" -X DELETE "$S3_URL"
+fi
+`
+
+// Prune runs the job which removes RDB file of the backup from
+// the destination, true is returned when the file is removed.
+// Job is owned by the caller, since the backup is deleted after it
+func (s *backupService) Prune(ctx context.Context, i *PruneRequest) (bool, error) {
+	if err := validator.Validate(ctx, i); err != nil {
+		return false, err
+	}
+
+	job := new(batchv1.Job)
+	err := s.k8sClient.Get(ctx, types.NamespacedName{
+		Name:      pruneJobName(i.Name),
+		Namespace: i.Namespace,
+	}, job)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return false, err
+		}
+
+		job = pruneJob(i)
+		log.FromContext(ctx).Info("creating prune job", "job", job.Name)
+		err = s.k8sClient.Create(ctx, job)
+		if err != nil && !k8serrors.IsAlreadyExists(err) {
+			return false, err
+		}
+
+		return false, nil
+	}
+
+	failed, isFailed := lo.Find(job.Status.Conditions, func(c batchv1.JobCondition) bool {
+		return c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue
+	})
+	if !isFailed && job.Status.Succeeded == 0 {
+		return false, nil
+	}
+
+	// finished job is removed, so failed one is created again by the next call
+	err = s.k8sClient.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return false, err
+	}
+
+	if isFailed {
+		return false, errors.Wrap(ErrPruneFailed, failed.Message)
+	}
+
+	return true, nil
+}
+
+func pruneJob(i *PruneRequest) *batchv1.Job {
+	labels := map[string]string{LabelBackup: i.Name}
+	volumes, mounts, env := destinationSpec(i.Destination, i.Name)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            pruneJobName(i.Name),
+			Namespace:       i.Namespace,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{*i.Owner},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: utils.Pointer(int32(copyJobBackoffLimit)),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:         "prune",
+						Image:        i.Image,
+						Command:      []string{"sh", "-c", pruneScript},
+						Env:          env,
+						VolumeMounts: mounts,
+					}},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+}
+
+func pruneJobName(name string) string {
+	return name + "-prune"
+}
@@ -0,0 +1,67 @@
+package backup
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/uagolang/k8s-operator/internal/lib/validator"
+	valkeysvc "github.com/uagolang/k8s-operator/internal/services/valkey"
+)
+
+type SaveRequest struct {
+	Pod       string    `json:"pod" validate:"required"`
+	Namespace string    `json:"namespace" validate:"required"`
+	Since     time.Time `json:"since" validate:"required"`
+}
+
+// Save triggers BGSAVE on the pod, returns true when RDB file
+// written after the backup was started is on the disk.
+// It's called until then, so BGSAVE is requested only once
+func (s *backupService) Save(ctx context.Context, i *SaveRequest) (bool, error) {
+	if err := validator.Validate(ctx, i); err != nil {
+		return false, err
+	}
+
+	pod := new(corev1.Pod)
+	err := s.k8sClient.Get(ctx, types.NamespacedName{
+		Name:      i.Pod,
+		Namespace: i.Namespace,
+	}, pod)
+	if err != nil {
+		return false, err
+	}
+	if pod.Status.PodIP == "" {
+		return false, nil
+	}
+
+	opts, err := valkeysvc.PodOptions(ctx, s.k8sClient, pod)
+	if err != nil {
+		return false, err
+	}
+	res, err := s.valkeyClient.Persistence(ctx, opts)
+	if err != nil {
+		return false, err
+	}
+	if res.BGSaveInProgress {
+		return false, nil
+	}
+	// last save time has seconds precision
+	if !res.LastSaveAt.Before(i.Since.Truncate(time.Second)) {
+		return true, nil
+	}
+
+	log.FromContext(ctx).Info("requesting BGSAVE", "pod", i.Pod)
+	if err = s.valkeyClient.BGSave(ctx, opts); err != nil {
+		return false, err
+	}
+	if !res.LastBGSaveOK {
+		// it's requested again, previous failure is reported until it succeeds
+		return false, ErrSaveFailed
+	}
+
+	return false, nil
+}
@@ -0,0 +1,43 @@
+package backup
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/uagolang/k8s-operator/internal/lib/valkeyclient"
+)
+
+type Service interface {
+	Save(ctx context.Context, i *SaveRequest) (bool, error)
+	Copy(ctx context.Context, i *CopyRequest) (*CopyResult, error)
+	Prune(ctx context.Context, i *PruneRequest) (bool, error)
+}
+
+type backupService struct {
+	k8sClient    client.Client
+	valkeyClient valkeyclient.Client
+}
+
+type Option func(s *backupService)
+
+func WithK8sClient(v client.Client) Option {
+	return func(s *backupService) {
+		s.k8sClient = v
+	}
+}
+
+func WithValkeyClient(v valkeyclient.Client) Option {
+	return func(s *backupService) {
+		s.valkeyClient = v
+	}
+}
+
+func NewBackupService(opts ...Option) Service {
+	s := new(backupService)
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
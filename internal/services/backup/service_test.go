@@ -0,0 +1,450 @@
+package backup_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	validatorlib "github.com/uagolang/k8s-operator/internal/lib/validator"
+	"github.com/uagolang/k8s-operator/internal/lib/valkeyclient"
+	"github.com/uagolang/k8s-operator/internal/services/backup"
+	"github.com/uagolang/k8s-operator/mocks"
+)
+
+func TestSave(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockErr := errors.New("mock error")
+	k8sClient := mocks.NewMockK8sClient(ctrl)
+	valkeyClient := mocks.NewMockValkeyClient(ctrl)
+	s := backup.NewBackupService(
+		backup.WithK8sClient(k8sClient),
+		backup.WithValkeyClient(valkeyClient),
+	)
+
+	since := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	req := &backup.SaveRequest{
+		Pod:       "valkey-0",
+		Namespace: "default",
+		Since:     since,
+	}
+	opts := valkeyclient.Options{Addr: "10.0.0.1:6379"}
+
+	getPod := func(ip string) {
+		k8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "valkey-0", Namespace: "default"}, gomock.AssignableToTypeOf(&v1.Pod{})).DoAndReturn(
+			func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+				obj.(*v1.Pod).Status.PodIP = ip
+				return nil
+			})
+	}
+
+	t.Run("bgsave is requested", func(t *testing.T) {
+		getPod("10.0.0.1")
+		valkeyClient.EXPECT().Persistence(gomock.Any(), opts).Return(&valkeyclient.Persistence{
+			LastSaveAt:   since.Add(-time.Hour),
+			LastBGSaveOK: true,
+		}, nil)
+		valkeyClient.EXPECT().BGSave(gomock.Any(), opts).Return(nil)
+
+		saved, err := s.Save(ctx, req)
+		require.NoError(t, err)
+		require.False(t, saved)
+	})
+
+	t.Run("bgsave is in progress", func(t *testing.T) {
+		getPod("10.0.0.1")
+		valkeyClient.EXPECT().Persistence(gomock.Any(), opts).Return(&valkeyclient.Persistence{
+			BGSaveInProgress: true,
+			LastSaveAt:       since.Add(-time.Hour),
+			LastBGSaveOK:     true,
+		}, nil)
+
+		saved, err := s.Save(ctx, req)
+		require.NoError(t, err)
+		require.False(t, saved)
+	})
+
+	t.Run("saved after backup was started", func(t *testing.T) {
+		getPod("10.0.0.1")
+		valkeyClient.EXPECT().Persistence(gomock.Any(), opts).Return(&valkeyclient.Persistence{
+			LastSaveAt:   since,
+			LastBGSaveOK: true,
+		}, nil)
+
+		saved, err := s.Save(ctx, req)
+		require.NoError(t, err)
+		require.True(t, saved)
+	})
+
+	t.Run("previous bgsave failed", func(t *testing.T) {
+		getPod("10.0.0.1")
+		valkeyClient.EXPECT().Persistence(gomock.Any(), opts).Return(&valkeyclient.Persistence{
+			LastSaveAt: since.Add(-time.Hour),
+		}, nil)
+		// it's requested again
+		valkeyClient.EXPECT().BGSave(gomock.Any(), opts).Return(nil)
+
+		_, err := s.Save(ctx, req)
+		require.ErrorIs(t, err, backup.ErrSaveFailed)
+	})
+
+	t.Run("pod has no ip yet", func(t *testing.T) {
+		getPod("")
+
+		saved, err := s.Save(ctx, req)
+		require.NoError(t, err)
+		require.False(t, saved)
+	})
+
+	t.Run("with validation errors", func(t *testing.T) {
+		_, err := s.Save(ctx, &backup.SaveRequest{})
+		require.Len(t, validatorlib.GetErrors(err), 3)
+	})
+
+	t.Run("get pod failed", func(t *testing.T) {
+		k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+
+		_, err := s.Save(ctx, req)
+		require.ErrorIs(t, err, mockErr)
+	})
+
+	t.Run("bgsave failed", func(t *testing.T) {
+		getPod("10.0.0.1")
+		valkeyClient.EXPECT().Persistence(gomock.Any(), opts).Return(&valkeyclient.Persistence{LastBGSaveOK: true}, nil)
+		valkeyClient.EXPECT().BGSave(gomock.Any(), opts).Return(mockErr)
+
+		_, err := s.Save(ctx, req)
+		require.ErrorIs(t, err, mockErr)
+	})
+}
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockErr := errors.New("mock error")
+	k8sClient := mocks.NewMockK8sClient(ctrl)
+	s := backup.NewBackupService(backup.WithK8sClient(k8sClient))
+
+	owner := &metav1.OwnerReference{
+		APIVersion: v1alpha1.GroupVersion.String(),
+		Kind:       "ValkeyBackup",
+		Name:       "nightly",
+		UID:        "backup-uid",
+	}
+	s3Request := &backup.CopyRequest{
+		Name:      "nightly",
+		Namespace: "default",
+		Pod:       "valkey-0",
+		Image:     "curlimages/curl:8.10.1",
+		Destination: v1alpha1.BackupDestination{
+			S3: &v1alpha1.S3Destination{
+				Endpoint:     "http://minio.minio:9000/",
+				Bucket:       "backups",
+				Prefix:       "valkey",
+				AccessKeyRef: v1alpha1.SecretKeyRef{Name: "minio", Key: "access"},
+				SecretKeyRef: v1alpha1.SecretKeyRef{Name: "minio", Key: "secret"},
+			},
+		},
+		Owner: owner,
+	}
+	pvcRequest := *s3Request
+	pvcRequest.Destination = v1alpha1.BackupDestination{
+		PVC: &v1alpha1.PVCDestination{ClaimName: "backups", Path: "valkey"},
+	}
+
+	jobKey := types.NamespacedName{Name: "nightly-copy", Namespace: "default"}
+
+	getJob := func(status batchv1.JobStatus) {
+		k8sClient.EXPECT().Get(gomock.Any(), jobKey, gomock.AssignableToTypeOf(&batchv1.Job{})).DoAndReturn(
+			func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+				obj.(*batchv1.Job).Status = status
+				return nil
+			})
+	}
+
+	jobNotFound := func() {
+		k8sClient.EXPECT().Get(gomock.Any(), jobKey, gomock.AssignableToTypeOf(&batchv1.Job{})).
+			Return(k8serrors.NewNotFound(schema.GroupResource{Group: "batch", Resource: "jobs"}, jobKey.Name))
+	}
+
+	getPod := func(volumes ...v1.Volume) {
+		k8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "valkey-0", Namespace: "default"}, gomock.AssignableToTypeOf(&v1.Pod{})).DoAndReturn(
+			func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+				obj.(*v1.Pod).Spec = v1.PodSpec{NodeName: "node-1", Volumes: volumes}
+				return nil
+			})
+	}
+
+	dataVolume := v1.Volume{
+		Name: "data",
+		VolumeSource: v1.VolumeSource{
+			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "data-valkey-0"},
+		},
+	}
+
+	listPods := func(pods ...v1.Pod) {
+		k8sClient.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&v1.PodList{}), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+				obj.(*v1.PodList).Items = pods
+				return nil
+			})
+	}
+
+	succeededPod := func(message string) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "nightly-copy-abcde"},
+			Status: v1.PodStatus{
+				Phase: v1.PodSucceeded,
+				ContainerStatuses: []v1.ContainerStatus{{
+					State: v1.ContainerState{
+						Terminated: &v1.ContainerStateTerminated{Message: message},
+					},
+				}},
+			},
+		}
+	}
+
+	t.Run("job to s3 is created", func(t *testing.T) {
+		jobNotFound()
+		getPod(v1.Volume{Name: "tmp"}, dataVolume)
+		k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&batchv1.Job{})).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.Object, _ ...runtimeclient.CreateOption) error {
+				job := obj.(*batchv1.Job)
+				require.Equal(t, "nightly-copy", job.Name)
+				require.Equal(t, []metav1.OwnerReference{*owner}, job.OwnerReferences)
+
+				spec := job.Spec.Template.Spec
+				// data volume is mounted on the node of the pod
+				require.Equal(t, "node-1", spec.NodeName)
+				require.Equal(t, "data-valkey-0", spec.Volumes[0].PersistentVolumeClaim.ClaimName)
+				require.True(t, spec.Volumes[0].PersistentVolumeClaim.ReadOnly)
+
+				env := lo.SliceToMap(spec.Containers[0].Env, func(e v1.EnvVar) (string, v1.EnvVar) { return e.Name, e })
+				require.Equal(t, "http://minio.minio:9000/backups/valkey/nightly.rdb", env["S3_URL"].Value)
+				require.Equal(t, "us-east-1", env["S3_REGION"].Value)
+				require.Equal(t, "access", env["S3_ACCESS_KEY"].ValueFrom.SecretKeyRef.Key)
+				require.Equal(t, "secret", env["S3_SECRET_KEY"].ValueFrom.SecretKeyRef.Key)
+				return nil
+			})
+
+		res, err := s.Copy(ctx, s3Request)
+		require.NoError(t, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("job to pvc is created", func(t *testing.T) {
+		jobNotFound()
+		getPod(dataVolume)
+		k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&batchv1.Job{})).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.Object, _ ...runtimeclient.CreateOption) error {
+				spec := obj.(*batchv1.Job).Spec.Template.Spec
+				require.Len(t, spec.Volumes, 3)
+				require.Equal(t, "backups", spec.Volumes[2].PersistentVolumeClaim.ClaimName)
+				require.Equal(t, []v1.EnvVar{{Name: "BACKUP_FILE", Value: "/backup/valkey/nightly.rdb"}},
+					spec.Containers[0].Env)
+				return nil
+			})
+
+		res, err := s.Copy(ctx, &pvcRequest)
+		require.NoError(t, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("pod without persistent volume", func(t *testing.T) {
+		jobNotFound()
+		getPod()
+
+		_, err := s.Copy(ctx, s3Request)
+		require.ErrorIs(t, err, backup.ErrNoPersistentVolume)
+	})
+
+	t.Run("job is running", func(t *testing.T) {
+		getJob(batchv1.JobStatus{Active: 1})
+
+		res, err := s.Copy(ctx, s3Request)
+		require.NoError(t, err)
+		require.Nil(t, res)
+	})
+
+	t.Run("job succeeded", func(t *testing.T) {
+		getJob(batchv1.JobStatus{Succeeded: 1})
+		listPods(
+			v1.Pod{Status: v1.PodStatus{Phase: v1.PodFailed}},
+			succeededPod(`{"size":1024,"checksum":"abc"}`),
+		)
+
+		res, err := s.Copy(ctx, s3Request)
+		require.NoError(t, err)
+		require.Equal(t, &backup.CopyResult{
+			Location: "s3://backups/valkey/nightly.rdb",
+			Size:     1024,
+			Checksum: "abc",
+		}, res)
+	})
+
+	t.Run("job to pvc succeeded", func(t *testing.T) {
+		getJob(batchv1.JobStatus{Succeeded: 1})
+		listPods(succeededPod(`{"size":1024,"checksum":"abc"}`))
+
+		res, err := s.Copy(ctx, &pvcRequest)
+		require.NoError(t, err)
+		require.Equal(t, "pvc://backups/valkey/nightly.rdb", res.Location)
+	})
+
+	t.Run("job result is invalid", func(t *testing.T) {
+		getJob(batchv1.JobStatus{Succeeded: 1})
+		listPods(succeededPod("sha256sum: not found"))
+
+		_, err := s.Copy(ctx, s3Request)
+		require.ErrorIs(t, err, backup.ErrCopyResultNotParsed)
+	})
+
+	t.Run("job failed", func(t *testing.T) {
+		getJob(batchv1.JobStatus{
+			Failed: 3,
+			Conditions: []batchv1.JobCondition{{
+				Type:    batchv1.JobFailed,
+				Status:  v1.ConditionTrue,
+				Message: "Job has reached the specified backoff limit",
+			}},
+		})
+
+		_, err := s.Copy(ctx, s3Request)
+		require.ErrorIs(t, err, backup.ErrCopyFailed)
+	})
+
+	t.Run("with validation errors", func(t *testing.T) {
+		_, err := s.Copy(ctx, &backup.CopyRequest{})
+		require.Len(t, validatorlib.GetErrors(err), 5)
+	})
+
+	t.Run("create job failed", func(t *testing.T) {
+		jobNotFound()
+		getPod(dataVolume)
+		k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(mockErr)
+
+		_, err := s.Copy(ctx, s3Request)
+		require.ErrorIs(t, err, mockErr)
+	})
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockErr := errors.New("mock error")
+	k8sClient := mocks.NewMockK8sClient(ctrl)
+	s := backup.NewBackupService(backup.WithK8sClient(k8sClient))
+
+	owner := &metav1.OwnerReference{
+		APIVersion: v1alpha1.GroupVersion.String(),
+		Kind:       "ValkeyBackupSchedule",
+		Name:       "daily",
+		UID:        "schedule-uid",
+	}
+	req := &backup.PruneRequest{
+		Name:      "daily-1735732800",
+		Namespace: "default",
+		Image:     "curlimages/curl:8.10.1",
+		Destination: v1alpha1.BackupDestination{
+			PVC: &v1alpha1.PVCDestination{ClaimName: "backups", Path: "valkey"},
+		},
+		Owner: owner,
+	}
+
+	jobKey := types.NamespacedName{Name: "daily-1735732800-prune", Namespace: "default"}
+
+	getJob := func(status batchv1.JobStatus) {
+		k8sClient.EXPECT().Get(gomock.Any(), jobKey, gomock.AssignableToTypeOf(&batchv1.Job{})).DoAndReturn(
+			func(_ context.Context, key types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+				job := obj.(*batchv1.Job)
+				job.Name = key.Name
+				job.Status = status
+				return nil
+			})
+	}
+
+	t.Run("job is created", func(t *testing.T) {
+		k8sClient.EXPECT().Get(gomock.Any(), jobKey, gomock.AssignableToTypeOf(&batchv1.Job{})).
+			Return(k8serrors.NewNotFound(schema.GroupResource{Group: "batch", Resource: "jobs"}, jobKey.Name))
+		k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&batchv1.Job{})).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.Object, _ ...runtimeclient.CreateOption) error {
+				job := obj.(*batchv1.Job)
+				require.Equal(t, "daily-1735732800-prune", job.Name)
+				require.Equal(t, []metav1.OwnerReference{*owner}, job.OwnerReferences)
+
+				spec := job.Spec.Template.Spec
+				require.Equal(t, "backups", spec.Volumes[0].PersistentVolumeClaim.ClaimName)
+				require.Equal(t, []v1.EnvVar{{Name: "BACKUP_FILE", Value: "/backup/valkey/daily-1735732800.rdb"}},
+					spec.Containers[0].Env)
+				return nil
+			})
+
+		pruned, err := s.Prune(ctx, req)
+		require.NoError(t, err)
+		require.False(t, pruned)
+	})
+
+	t.Run("job is running", func(t *testing.T) {
+		getJob(batchv1.JobStatus{Active: 1})
+
+		pruned, err := s.Prune(ctx, req)
+		require.NoError(t, err)
+		require.False(t, pruned)
+	})
+
+	t.Run("job succeeded", func(t *testing.T) {
+		getJob(batchv1.JobStatus{Succeeded: 1})
+		k8sClient.EXPECT().Delete(gomock.Any(), gomock.AssignableToTypeOf(&batchv1.Job{}), gomock.Any()).Return(nil)
+
+		pruned, err := s.Prune(ctx, req)
+		require.NoError(t, err)
+		require.True(t, pruned)
+	})
+
+	t.Run("job failed", func(t *testing.T) {
+		getJob(batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{
+				Type:    batchv1.JobFailed,
+				Status:  v1.ConditionTrue,
+				Message: "Job has reached the specified backoff limit",
+			}},
+		})
+		// failed job is removed, so it's created again
+		k8sClient.EXPECT().Delete(gomock.Any(), gomock.AssignableToTypeOf(&batchv1.Job{}), gomock.Any()).Return(nil)
+
+		_, err := s.Prune(ctx, req)
+		require.ErrorIs(t, err, backup.ErrPruneFailed)
+	})
+
+	t.Run("delete job failed", func(t *testing.T) {
+		getJob(batchv1.JobStatus{Succeeded: 1})
+		k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+
+		_, err := s.Prune(ctx, req)
+		require.ErrorIs(t, err, mockErr)
+	})
+
+	t.Run("with validation errors", func(t *testing.T) {
+		_, err := s.Prune(ctx, &backup.PruneRequest{})
+		require.Len(t, validatorlib.GetErrors(err), 4)
+	})
+}
@@ -0,0 +1,24 @@
+package backup
+
+import (
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrSaveFailed          = errors.New("BGSAVE failed")
+	ErrCopyFailed          = errors.New("copy job failed")
+	ErrNoPersistentVolume  = errors.New("pod has no persistent volume")
+	ErrCopyResultNotParsed = errors.New("copy result can't be parsed")
+	ErrPruneFailed         = errors.New("prune job failed")
+)
+
+const (
+	// LabelBackup is set on copy and prune jobs, value is a name of the backup
+	LabelBackup = "database.kuberly.io/backup"
+
+	// rdbFile is a name of RDB file written by BGSAVE to data volume
+	rdbFile = "dump.rdb"
+
+	// copyJobBackoffLimit is a number of retries of copy and prune jobs
+	copyJobBackoffLimit = 2
+)
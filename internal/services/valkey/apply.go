@@ -0,0 +1,117 @@
+package valkey
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// apply creates or updates object with server-side apply, fields set by
+// operator are owned by its field manager and manual changes of them are
+// reverted. Returns true when object was changed while desired state is the
+// same as at the previous apply, i.e. manual drift was corrected.
+func (s *valkeyService) apply(ctx context.Context, obj client.Object) (bool, error) {
+	hash, err := objectHash(obj)
+	if err != nil {
+		return false, err
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[annotationDesiredHash] = hash
+	obj.SetAnnotations(annotations)
+
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	live := obj.DeepCopyObject().(client.Object)
+	err = s.k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), live)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return false, err
+		}
+		live = nil
+	}
+
+	err = s.k8sClient.Patch(ctx, obj, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership)
+	if err != nil {
+		return false, err
+	}
+
+	if live == nil || live.GetAnnotations()[annotationDesiredHash] != hash {
+		return false, nil
+	}
+
+	drifted := !sameState(live, obj)
+	if drifted {
+		log.FromContext(ctx).Info("manual changes were reverted", "kind", kind, "name", obj.GetName())
+	}
+
+	return drifted, nil
+}
+
+// objectHash returns hash of desired object, it's stored on the object,
+// so changes of desired state aren't reported as drift
+func objectHash(obj client.Object) (string, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// sameState compares objects ignoring status and metadata
+// which is changed by every write
+func sameState(a, b client.Object) bool {
+	left, err := runtime.DefaultUnstructuredConverter.ToUnstructured(a)
+	if err != nil {
+		return false
+	}
+	right, err := runtime.DefaultUnstructuredConverter.ToUnstructured(b)
+	if err != nil {
+		return false
+	}
+
+	for _, obj := range []map[string]any{left, right} {
+		delete(obj, "apiVersion")
+		delete(obj, "kind")
+		delete(obj, "status")
+		if meta, ok := obj["metadata"].(map[string]any); ok {
+			delete(meta, "resourceVersion")
+			delete(meta, "managedFields")
+			delete(meta, "generation")
+		}
+	}
+
+	return equality.Semantic.DeepEqual(left, right)
+}
+
+// applyAll applies objects one by one and returns
+// kind and name of objects where drift was corrected
+func (s *valkeyService) applyAll(ctx context.Context, objs ...client.Object) ([]string, error) {
+	var res []string
+	for _, obj := range objs {
+		// response of apply doesn't keep type meta
+		ref := obj.GetObjectKind().GroupVersionKind().Kind + "/" + obj.GetName()
+
+		drifted, err := s.apply(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		if drifted {
+			res = append(res, ref)
+		}
+	}
+
+	return res, nil
+}
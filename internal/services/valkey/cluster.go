@@ -0,0 +1,395 @@
+package valkey
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/lib/validator"
+	"github.com/uagolang/k8s-operator/internal/lib/valkeyclient"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
+	"github.com/uagolang/k8s-operator/internal/utils"
+)
+
+// maxSlotMigrations limits number of slots moved by single sync,
+// so reconcile doesn't block while big dataset is rebalanced
+const maxSlotMigrations = 128
+
+type SyncClusterRequest struct {
+	CrdName          string `json:"crd_name" validate:"required"`
+	Namespace        string `json:"namespace" validate:"required"`
+	Shards           int32  `json:"shards" validate:"required,min=1"`
+	ReplicasPerShard int32  `json:"replicas_per_shard" validate:"min=0"`
+}
+
+// clusterMember is a pod of StatefulSet with cluster node as the pod sees itself,
+// node is nil while pod is not ready
+type clusterMember struct {
+	pod  *corev1.Pod
+	node *valkeyclient.ClusterNode
+	opts valkeyclient.Options
+}
+
+// SyncCluster joins pods into Valkey Cluster and keeps its topology:
+// pods of StatefulSet are split into shards one by one, the first pod of
+// every shard owns slots and the rest replicate from it. Hash slots are
+// spread evenly between shards, so when shards count is changed slots are
+// moved step by step and StatefulSet is scaled down only after removed
+// shards are drained. Returns health of every shard.
+func (s *valkeyService) SyncCluster(ctx context.Context, i *SyncClusterRequest) ([]v1alpha1.ShardStatus, error) {
+	if err := validator.Validate(ctx, i); err != nil {
+		return nil, err
+	}
+
+	logger := log.FromContext(ctx)
+
+	pods, err := s.listPods(ctx, i.CrdName, i.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	shardSize := int(1 + i.ReplicasPerShard)
+	size := int(i.Shards) * shardSize
+	// pods above desired size belong to shards which are being drained
+	total := max(size, len(pods))
+
+	byName := lo.KeyBy(pods, func(p corev1.Pod) string { return p.Name })
+	members := make([]clusterMember, total)
+	ready := true
+	var view []valkeyclient.ClusterNode
+	for ordinal := range members {
+		pod, ok := byName[render.PodName(i.CrdName, ordinal)]
+		if !ok || !isPodReady(&pod) {
+			ready = false
+			continue
+		}
+
+		opts, err := s.podOptions(ctx, &pod)
+		if err != nil {
+			return nil, err
+		}
+		nodes, err := s.valkeyClient.ClusterNodes(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		myself, ok := lo.Find(nodes, func(n valkeyclient.ClusterNode) bool { return n.HasFlag("myself") })
+		if !ok {
+			return nil, errors.Errorf("pod %s is not found in its cluster nodes", pod.Name)
+		}
+
+		members[ordinal] = clusterMember{pod: &pod, node: &myself, opts: opts}
+		if ordinal == 0 {
+			view = nodes
+		}
+	}
+
+	statuses := clusterStatus(members, int(i.Shards), shardSize)
+	if !ready {
+		// topology is changed only when every pod is reachable
+		return statuses, nil
+	}
+
+	changed, err := s.meetNodes(ctx, members, view)
+	if err != nil || changed {
+		return statuses, err
+	}
+
+	s.forgetNodes(ctx, members, view)
+
+	if !lo.ContainsBy(members, func(m clusterMember) bool { return m.node.SlotsCount() > 0 }) {
+		logger.Info("assigning slots", "shards", i.Shards)
+		return statuses, s.assignSlots(ctx, members, int(i.Shards), shardSize)
+	}
+
+	err = s.replicateShards(ctx, members, shardSize)
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err = s.rebalanceSlots(ctx, members, int(i.Shards), shardSize)
+	if err != nil || changed {
+		return statuses, err
+	}
+
+	if len(pods) > size {
+		err = s.scaleDownCluster(ctx, i, members[size:], size)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return statuses, nil
+}
+
+// meetNodes introduces every node unknown to the first pod,
+// the rest of the cluster learns about it by gossip
+func (s *valkeyService) meetNodes(ctx context.Context, members []clusterMember, view []valkeyclient.ClusterNode) (bool, error) {
+	known := lo.SliceToMap(view, func(n valkeyclient.ClusterNode) (string, bool) { return n.ID, true })
+
+	var changed bool
+	for _, m := range members[1:] {
+		if known[m.node.ID] {
+			continue
+		}
+
+		log.FromContext(ctx).Info("adding node to cluster", "pod", m.pod.Name)
+		err := s.valkeyClient.ClusterMeet(ctx, members[0].opts, m.pod.Status.PodIP, render.PodPort(m.pod))
+		if err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// forgetNodes removes failed nodes which don't belong to any pod,
+// e.g. nodes of removed shards or pods restarted without volume
+func (s *valkeyService) forgetNodes(ctx context.Context, members []clusterMember, view []valkeyclient.ClusterNode) {
+	logger := log.FromContext(ctx)
+
+	ids := lo.SliceToMap(members, func(m clusterMember) (string, bool) { return m.node.ID, true })
+	for _, n := range view {
+		if ids[n.ID] || !n.IsFailed() {
+			continue
+		}
+
+		for _, m := range members {
+			if m.node.PrimaryID == n.ID {
+				continue
+			}
+
+			// forget is best effort, node could be already forgotten
+			err := s.valkeyClient.ClusterForget(ctx, m.opts, n.ID)
+			if err != nil {
+				logger.Info("failed to forget node", "pod", m.pod.Name, "node", n.ID, "error", err.Error())
+			}
+		}
+	}
+}
+
+func (s *valkeyService) assignSlots(ctx context.Context, members []clusterMember, shards, shardSize int) error {
+	for idx := 0; idx < shards; idx++ {
+		primary := members[idx*shardSize]
+		err := s.valkeyClient.ClusterAddSlotsRange(ctx, primary.opts, slotRange(idx, shards))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replicateShards points every pod of the shard to its primary
+func (s *valkeyService) replicateShards(ctx context.Context, members []clusterMember, shardSize int) error {
+	for _, shard := range lo.Chunk(members, shardSize) {
+		primary := shardPrimary(shard)
+		if primary == nil {
+			continue
+		}
+
+		for _, m := range shard {
+			// primary with slots can't become replica before it's drained
+			if m.pod == primary.pod || m.node.PrimaryID == primary.node.ID || m.node.SlotsCount() > 0 {
+				continue
+			}
+
+			log.FromContext(ctx).Info("configuring replica", "pod", m.pod.Name, "primary", primary.pod.Name)
+			err := s.valkeyClient.ClusterReplicate(ctx, m.opts, primary.node.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// rebalanceSlots moves slots from primaries which own more than their share,
+// including primaries of drained shards, to primaries which own less
+func (s *valkeyService) rebalanceSlots(ctx context.Context, members []clusterMember, shards, shardSize int) (bool, error) {
+	type move struct {
+		slot int
+		from *clusterMember
+	}
+
+	var surplus []move
+	var deficit []*clusterMember
+	need := make(map[*clusterMember]int)
+	for idx, shard := range lo.Chunk(members, shardSize) {
+		primary := shardPrimary(shard)
+		if primary == nil {
+			if idx < shards {
+				// wait until every active shard has primary
+				return false, nil
+			}
+			continue
+		}
+
+		var target int
+		if idx < shards {
+			r := slotRange(idx, shards)
+			target = r.End - r.Start + 1
+		}
+
+		count := primary.node.SlotsCount()
+		switch {
+		case count > target:
+			slots := expandSlots(primary.node.Slots)
+			for _, slot := range slots[target:] {
+				surplus = append(surplus, move{slot: slot, from: primary})
+			}
+		case count < target:
+			deficit = append(deficit, primary)
+			need[primary] = target - count
+		}
+	}
+
+	var moved int
+	for _, to := range deficit {
+		for ; need[to] > 0 && len(surplus) > 0; need[to]-- {
+			if moved == maxSlotMigrations {
+				return true, nil
+			}
+
+			m := surplus[0]
+			surplus = surplus[1:]
+
+			err := s.valkeyClient.MigrateSlot(ctx, m.from.opts, to.opts, m.slot)
+			if err != nil {
+				return false, err
+			}
+			moved++
+		}
+	}
+
+	if moved > 0 {
+		log.FromContext(ctx).Info("slots were moved", "count", moved)
+	}
+
+	return moved > 0, nil
+}
+
+// scaleDownCluster removes pods of drained shards
+func (s *valkeyService) scaleDownCluster(ctx context.Context, i *SyncClusterRequest, drained []clusterMember, size int) error {
+	if lo.ContainsBy(drained, func(m clusterMember) bool { return m.node.SlotsCount() > 0 }) {
+		return nil
+	}
+
+	res, err := s.getStatefulSet(ctx, types.NamespacedName{
+		Name:      i.CrdName,
+		Namespace: i.Namespace,
+	})
+	if err != nil {
+		return err
+	}
+	if res == nil || res.Spec.Replicas == nil || *res.Spec.Replicas == int32(size) {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("removing drained shards", "replicas", size)
+
+	patch := client.MergeFrom(res.DeepCopy())
+	res.Spec.Replicas = utils.Pointer(int32(size))
+
+	return s.k8sClient.Patch(ctx, res, patch)
+}
+
+// shardPrimary returns member which owns slots of the shard,
+// the first primary is used for shard without slots
+func shardPrimary(shard []clusterMember) *clusterMember {
+	var res *clusterMember
+	for idx := range shard {
+		m := &shard[idx]
+		if m.node == nil || !m.node.IsPrimary() {
+			continue
+		}
+		if m.node.SlotsCount() > 0 {
+			return m
+		}
+		if res == nil {
+			res = m
+		}
+	}
+
+	return res
+}
+
+func clusterStatus(members []clusterMember, shards, shardSize int) []v1alpha1.ShardStatus {
+	res := make([]v1alpha1.ShardStatus, 0, len(members)/shardSize)
+	for idx, shard := range lo.Chunk(members, shardSize) {
+		status := v1alpha1.ShardStatus{
+			Index:  int32(idx),
+			Status: v1alpha1.TypeStatusUpdating,
+		}
+
+		primary := shardPrimary(shard)
+		if primary != nil {
+			status.Primary = primary.pod.Name
+			status.Slots = formatSlots(primary.node.Slots)
+			status.ReadyReplicas = int32(lo.CountBy(shard, func(m clusterMember) bool {
+				return m.node != nil && m.node.PrimaryID == primary.node.ID
+			}))
+		}
+
+		ready := !lo.ContainsBy(shard, func(m clusterMember) bool { return m.node == nil })
+		switch {
+		case idx >= shards:
+			// shard is being drained
+		case primary == nil && ready:
+			status.Status = v1alpha1.TypeStatusFailed
+		case primary != nil && status.Slots != "" && int(status.ReadyReplicas) == shardSize-1:
+			status.Status = v1alpha1.TypeStatusHealthy
+		}
+
+		res = append(res, status)
+	}
+
+	return res
+}
+
+// slotRange returns slots of the shard when slots are spread evenly
+func slotRange(shard, shards int) valkeyclient.SlotRange {
+	base := valkeyclient.SlotsCount / shards
+	rest := valkeyclient.SlotsCount % shards
+
+	start := shard*base + min(shard, rest)
+	end := start + base - 1
+	if shard < rest {
+		end++
+	}
+
+	return valkeyclient.SlotRange{Start: start, End: end}
+}
+
+func expandSlots(ranges []valkeyclient.SlotRange) []int {
+	var res []int
+	for _, r := range ranges {
+		for slot := r.Start; slot <= r.End; slot++ {
+			res = append(res, slot)
+		}
+	}
+
+	return res
+}
+
+func formatSlots(ranges []valkeyclient.SlotRange) string {
+	res := lo.Map(ranges, func(r valkeyclient.SlotRange, _ int) string {
+		if r.Start == r.End {
+			return strconv.Itoa(r.Start)
+		}
+
+		return strconv.Itoa(r.Start) + "-" + strconv.Itoa(r.End)
+	})
+
+	return strings.Join(res, ",")
+}
@@ -0,0 +1,73 @@
+package valkey
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/uagolang/k8s-operator/internal/lib/validator"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
+)
+
+type ApplyConfigRequest struct {
+	CrdName   string            `json:"crd_name" validate:"required"`
+	Namespace string            `json:"namespace" validate:"required"`
+	Config    map[string]string `json:"config,omitempty" validate:"omitempty"`
+}
+
+// ApplyConfig sets dynamic parameters on every ready pod by CONFIG SET, so
+// they're changed without restart. Hash of applied parameters is kept on the
+// pod, so the pod is configured once per change. Removed parameters keep
+// their value until the pod is restarted
+func (s *valkeyService) ApplyConfig(ctx context.Context, i *ApplyConfigRequest) error {
+	if err := validator.Validate(ctx, i); err != nil {
+		return err
+	}
+	if err := render.ValidateConfig(i.Config); err != nil {
+		return err
+	}
+
+	pods, err := s.listPods(ctx, i.CrdName, i.Namespace)
+	if err != nil {
+		return err
+	}
+
+	config := render.DynamicConfig(i.Config)
+	hash := render.ConfigHash(config)
+	for idx := range pods {
+		pod := &pods[idx]
+		if !isPodReady(pod) || pod.Annotations[annotationAppliedConfigHash] == hash {
+			continue
+		}
+
+		if len(config) > 0 {
+			opts, err := s.podOptions(ctx, pod)
+			if err != nil {
+				return err
+			}
+
+			log.FromContext(ctx).Info("applying config", "pod", pod.Name)
+			if err = s.valkeyClient.ConfigSet(ctx, opts, config); err != nil {
+				return err
+			}
+		}
+
+		if err = s.setAppliedConfigHash(ctx, pod, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *valkeyService) setAppliedConfigHash(ctx context.Context, pod *corev1.Pod, hash string) error {
+	patch := client.MergeFrom(pod.DeepCopy())
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[annotationAppliedConfigHash] = hash
+
+	return s.k8sClient.Patch(ctx, pod, patch)
+}
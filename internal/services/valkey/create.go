@@ -2,32 +2,37 @@ package valkey
 
 import (
 	"context"
-	"encoding/base64"
-	"time"
+	"crypto/rand"
+	"encoding/hex"
 
-	appsv1 "k8s.io/api/apps/v1"
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/apimachinery/pkg/util/wait"
 
 	"github.com/uagolang/k8s-operator/api/v1alpha1"
 	"github.com/uagolang/k8s-operator/internal/lib/validator"
-	"github.com/uagolang/k8s-operator/internal/utils"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
 )
 
 type CreateRequest struct {
-	CrdName   string            `json:"crd_name" validate:"required"`
-	Namespace string            `json:"namespace" validate:"required"`
-	Image     string            `json:"image" validate:"required"`
-	User      string            `json:"user" validate:"required"`
-	Password  string            `json:"password" validate:"required"`
-	Replicas  int32             `json:"replicas" validate:"required"`
-	Volume    v1alpha1.Volume   `json:"volume" validate:"required"`
-	Resource  v1alpha1.Resource `json:"resource" validate:"required"`
+	CrdName           string                  `json:"crd_name" validate:"required"`
+	Namespace         string                  `json:"namespace" validate:"required"`
+	Image             string                  `json:"image" validate:"required"`
+	User              string                  `json:"user" validate:"required"`
+	Password          string                  `json:"password" validate:"omitempty"`
+	PasswordSecretRef *v1alpha1.SecretKeyRef  `json:"password_secret_ref,omitempty" validate:"omitempty"`
+	Replicas          *int32                  `json:"replicas" validate:"required_unless=Mode cluster"`
+	Mode              v1alpha1.TypeMode       `json:"mode" validate:"omitempty,oneof=standalone replication cluster"`
+	Sentinel          *v1alpha1.Sentinel      `json:"sentinel,omitempty" validate:"omitempty"`
+	Cluster           *v1alpha1.Cluster       `json:"cluster,omitempty" validate:"required_if=Mode cluster"`
+	Volume            v1alpha1.Volume         `json:"volume" validate:"required"`
+	Resource          v1alpha1.Resource       `json:"resource" validate:"required"`
+	TLS               *v1alpha1.TLS           `json:"tls,omitempty" validate:"omitempty"`
+	Config            map[string]string       `json:"config,omitempty" validate:"omitempty"`
+	RestoreFrom       *v1alpha1.RestoreSource `json:"restore_from,omitempty" validate:"omitempty"`
+	Owner             *metav1.OwnerReference  `json:"owner" validate:"required"`
 }
 
 func (s *valkeyService) Create(ctx context.Context, i *CreateRequest) error {
@@ -35,229 +40,124 @@ func (s *valkeyService) Create(ctx context.Context, i *CreateRequest) error {
 		return err
 	}
 
-	_, err := s.createSecret(ctx, i)
+	item := i.toValkey()
+	sec, err := s.createSecret(ctx, item)
 	if err != nil {
 		return err
 	}
 
-	_, err = s.createDeployment(ctx, i)
+	restore, err := s.restoreSource(ctx, item)
 	if err != nil {
 		return err
 	}
 
-	_, err = s.createService(ctx, i)
+	_, key := render.PasswordSecret(item.Name, item.Spec.PasswordSecretRef())
+	objs, err := render.Render(item, render.Options{
+		Password:     sec.Data[key],
+		PasswordHash: secretHash(sec, key),
+		Restore:      restore,
+	})
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func (s *valkeyService) createSecret(ctx context.Context, i *CreateRequest) (*corev1.Secret, error) {
-	res := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      i.CrdName,
-			Namespace: i.Namespace,
-		},
-		StringData: map[string]string{
-			secretKeyPassword: base64.StdEncoding.EncodeToString([]byte(i.Password)),
-		},
-	}
-	if err := s.k8sClient.Create(ctx, res); err != nil && !k8serrors.IsAlreadyExists(err) {
-		return nil, err
+	if _, err = s.applyAll(ctx, objs.List()...); err != nil {
+		return err
 	}
 
-	return res, s.waitForSecret(res.Name, res.Namespace, defaultWaitDuration)
-}
-
-func (s *valkeyService) waitForSecret(name, namespace string, dur time.Duration) error {
-	ctx, cancel := context.WithTimeout(context.Background(), dur)
-	defer cancel()
-
-	return wait.PollUntilContextTimeout(ctx, pollInterval, dur, true, func(ctx context.Context) (bool, error) {
-		sec, err := s.getSecret(ctx, types.NamespacedName{
-			Name:      name,
-			Namespace: namespace,
-		})
-		if err != nil {
-			return false, err
-		}
-
-		if sec == nil {
-			return false, nil
-		}
+	if !item.Spec.Volume.Enabled {
+		return nil
+	}
 
-		return true, nil
+	// claims kept after deletion of the instance with the same name
+	// are taken by StatefulSet, they are only released here
+	return s.adoptRetainedClaims(ctx, types.NamespacedName{
+		Name:      item.Name,
+		Namespace: item.Namespace,
 	})
 }
 
-func (s *valkeyService) createDeployment(ctx context.Context, i *CreateRequest) (*appsv1.Deployment, error) {
-	var volumeMounts []corev1.VolumeMount
-	var volumes []corev1.Volume
-
-	pvcName := "valkey-pvc"
-
-	if i.Volume.Enabled {
-		volumeMounts = []corev1.VolumeMount{{
-			Name:      "data",
-			MountPath: "/data",
-		}}
-		volumes = []corev1.Volume{{
-			Name: "data",
-			VolumeSource: corev1.VolumeSource{
-				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-					ClaimName: pvcName,
-				},
-			},
-		}}
-	}
-
-	pvc := &corev1.PersistentVolumeClaim{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      pvcName,
-			Namespace: i.Namespace,
-		},
-		Spec: corev1.PersistentVolumeClaimSpec{
-			AccessModes: []corev1.PersistentVolumeAccessMode{
-				corev1.ReadWriteOnce,
-			},
-			Resources: corev1.VolumeResourceRequirements{
-				Requests: corev1.ResourceList{
-					corev1.ResourceStorage: resource.MustParse(i.Resource.Storage),
-				},
-			},
-		},
-	}
-	err := s.k8sClient.Create(ctx, pvc)
-	if err != nil && !k8serrors.IsAlreadyExists(err) {
-		return nil, err
-	}
-
-	resourceList := corev1.ResourceList{
-		corev1.ResourceCPU:    resource.MustParse(i.Resource.CPU),
-		corev1.ResourceMemory: resource.MustParse(i.Resource.Memory),
-	}
-
-	res := &appsv1.Deployment{
+// toValkey returns instance the request was built for,
+// so objects are rendered the same way on create and update
+func (i *CreateRequest) toValkey() *v1alpha1.Valkey {
+	res := &v1alpha1.Valkey{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      i.CrdName,
 			Namespace: i.Namespace,
+			UID:       i.Owner.UID,
 		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: utils.Pointer(i.Replicas),
-			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{"app": i.CrdName},
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{"app": i.CrdName},
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "valkey",
-							Image: i.Image,
-							Env: []corev1.EnvVar{
-								{
-									Name:  "VALKEY_USER",
-									Value: i.User,
-								},
-								{
-									Name: "VALKEY_PASSWORD",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: i.CrdName,
-											},
-											Key: "password",
-										},
-									},
-								},
-							},
-							Ports:        []corev1.ContainerPort{{ContainerPort: 6379}},
-							VolumeMounts: volumeMounts,
-							Resources: corev1.ResourceRequirements{
-								Requests: resourceList,
-								Limits:   resourceList,
-							},
-						},
-					},
-					Volumes: volumes,
-				},
-			},
+		Spec: v1alpha1.ValkeySpec{
+			Image:       i.Image,
+			Replicas:    i.Replicas,
+			Mode:        i.Mode,
+			Sentinel:    i.Sentinel,
+			Cluster:     i.Cluster,
+			User:        i.User,
+			Password:    i.Password,
+			Volume:      i.Volume,
+			Resource:    i.Resource,
+			TLS:         i.TLS,
+			Config:      i.Config,
+			RestoreFrom: i.RestoreFrom,
 		},
 	}
-	err = s.k8sClient.Create(ctx, res)
-	if err != nil && !k8serrors.IsAlreadyExists(err) {
-		return nil, err
+	if i.PasswordSecretRef != nil {
+		res.Spec.Auth = &v1alpha1.Auth{PasswordSecretRef: i.PasswordSecretRef}
 	}
 
-	return res, s.waitForDeployment(i.CrdName, i.Namespace, defaultWaitDuration)
+	return res
 }
 
-func (s *valkeyService) waitForDeployment(name, namespace string, dur time.Duration) error {
-	ctx, cancel := context.WithTimeout(context.Background(), dur)
-	defer cancel()
+// createSecret creates the Secret with password owned by operator once,
+// so generated password is kept, referenced Secret is only checked to exist
+func (s *valkeyService) createSecret(ctx context.Context, item *v1alpha1.Valkey) (*corev1.Secret, error) {
+	if ref := item.Spec.PasswordSecretRef(); ref != nil {
+		return s.getPasswordSecret(ctx, item.Name, item.Namespace, ref)
+	}
 
-	return wait.PollUntilContextTimeout(ctx, pollInterval, dur, true, func(ctx context.Context) (bool, error) {
-		dep, err := s.getDeployment(ctx, types.NamespacedName{
-			Name:      name,
-			Namespace: namespace,
-		})
+	password := item.Spec.Password
+	if password == "" {
+		var err error
+		password, err = generatePassword()
 		if err != nil {
-			return false, err
+			return nil, err
 		}
+	}
 
-		if dep == nil {
-			return false, nil
+	res := render.Secret(item, []byte(password))
+	if err := s.k8sClient.Create(ctx, res); err != nil {
+		if !k8serrors.IsAlreadyExists(err) {
+			return nil, err
 		}
 
-		return true, nil
-	})
+		// keep password which was generated before
+		return s.getPasswordSecret(ctx, item.Name, item.Namespace, nil)
+	}
+
+	return res, nil
 }
 
-func (s *valkeyService) createService(ctx context.Context, i *CreateRequest) (*corev1.Service, error) {
-	res := &corev1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      i.CrdName,
-			Namespace: i.Namespace,
-		},
-		Spec: corev1.ServiceSpec{
-			Selector: map[string]string{"app": "valkey"},
-			Ports: []corev1.ServicePort{
-				{
-					Port:       6379,
-					TargetPort: intstr.FromInt32(6379),
-				},
-			},
-			ClusterIP: "None",
-		},
-	}
-	err := s.k8sClient.Create(ctx, res)
-	if err != nil && !k8serrors.IsAlreadyExists(err) {
+func (s *valkeyService) getPasswordSecret(ctx context.Context, crdName, namespace string, ref *v1alpha1.SecretKeyRef) (*corev1.Secret, error) {
+	name, _ := render.PasswordSecret(crdName, ref)
+	res, err := s.getSecret(ctx, types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	})
+	if err != nil {
 		return nil, err
 	}
+	if res == nil {
+		return nil, errors.Wrapf(ErrPasswordSecretNotFound, "%s/%s", namespace, name)
+	}
 
-	return res, s.waitForService(types.NamespacedName{
-		Name:      i.CrdName,
-		Namespace: i.Namespace,
-	}, defaultWaitDuration)
+	return res, nil
 }
 
-func (s *valkeyService) waitForService(i types.NamespacedName, dur time.Duration) error {
-	ctx, cancel := context.WithTimeout(context.Background(), dur)
-	defer cancel()
-
-	return wait.PollUntilContextTimeout(ctx, pollInterval, dur, true, func(ctx context.Context) (bool, error) {
-		svc, err := s.getService(ctx, i)
-		if err != nil {
-			return false, err
-		}
-
-		if svc == nil {
-			return false, nil
-		}
+func generatePassword() (string, error) {
+	b := make([]byte, generatedPasswordLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
 
-		return true, nil
-	})
+	return hex.EncodeToString(b), nil
 }
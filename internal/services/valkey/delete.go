@@ -2,90 +2,204 @@ package valkey
 
 import (
 	"context"
+	"strings"
 
-	appsv1 "k8s.io/api/apps/v1"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
 	"github.com/uagolang/k8s-operator/internal/lib/validator"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
 )
 
 type DeleteRequest struct {
-	Name      string `json:"name" validate:"required"`
-	Namespace string `json:"namespace" validate:"required"`
+	Name           string                      `json:"name" validate:"required"`
+	Namespace      string                      `json:"namespace" validate:"required"`
+	UID            types.UID                   `json:"uid" validate:"omitempty"`
+	DeletionPolicy v1alpha1.TypeDeletionPolicy `json:"deletion_policy" validate:"omitempty,oneof=Delete Retain Snapshot"`
 }
 
+// Delete removes what garbage collector can't. Every child object is owned
+// by Valkey and removed together with it, only claims which were created
+// before StatefulSet got retention policy have no owner and outlive it.
+// Claims are kept or snapshotted before it depending on deletion policy.
 func (s *valkeyService) Delete(ctx context.Context, i *DeleteRequest) error {
 	if err := validator.Validate(ctx, i); err != nil {
 		return err
 	}
 
-	namespaced := types.NamespacedName{
+	key := types.NamespacedName{
 		Name:      i.Name,
 		Namespace: i.Namespace,
 	}
 
-	err := s.deleteService(ctx, namespaced)
+	switch i.DeletionPolicy {
+	case v1alpha1.TypeDeletionPolicyRetain:
+		return s.retainClaims(ctx, key)
+	case v1alpha1.TypeDeletionPolicySnapshot:
+		// claims are removed by garbage collector as soon as finalizer is
+		// released, snapshot controller doesn't take snapshot of claim which
+		// is being deleted, so deletion waits until snapshots are ready
+		if err := s.snapshotClaims(ctx, key, i.UID); err != nil {
+			return err
+		}
+	}
+
+	return s.deleteOrphanClaims(ctx, key)
+}
+
+func (s *valkeyService) listClaims(ctx context.Context, i types.NamespacedName) ([]corev1.PersistentVolumeClaim, error) {
+	claims := new(corev1.PersistentVolumeClaimList)
+	err := s.k8sClient.List(ctx, claims,
+		client.InNamespace(i.Namespace),
+		client.MatchingLabels(render.SelectorLabels(i.Name)),
+	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = s.deleteDeployment(ctx, namespaced)
+	return claims.Items, nil
+}
+
+// retainClaims releases claims from StatefulSet, so they aren't removed
+// by garbage collector, and labels them to be adopted by a new instance
+func (s *valkeyService) retainClaims(ctx context.Context, i types.NamespacedName) error {
+	claims, err := s.listClaims(ctx, i)
 	if err != nil {
 		return err
 	}
 
-	err = s.deleteSecret(ctx, namespaced)
+	for idx := range claims {
+		claim := &claims[idx]
+		patch := client.MergeFrom(claim.DeepCopy())
+
+		claim.OwnerReferences = lo.Reject(claim.OwnerReferences, func(ref metav1.OwnerReference, _ int) bool {
+			return ref.Kind == "StatefulSet"
+		})
+		if claim.Labels == nil {
+			claim.Labels = make(map[string]string)
+		}
+		claim.Labels[v1alpha1.LabelRetainedFrom] = i.Name
+
+		log.FromContext(ctx).Info("retaining claim", "claim", claim.Name)
+		err = s.k8sClient.Patch(ctx, claim, patch)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// snapshotClaims takes VolumeSnapshot of every claim, snapshots
+// aren't owned by Valkey, so they outlive it. ErrSnapshotNotReady is
+// returned until every snapshot is ready to use
+func (s *valkeyService) snapshotClaims(ctx context.Context, i types.NamespacedName, uid types.UID) error {
+	claims, err := s.listClaims(ctx, i)
 	if err != nil {
 		return err
 	}
 
+	var pending []string
+	for _, claim := range claims {
+		snapshot := volumeSnapshot(&claim, uid)
+
+		err = s.k8sClient.Create(ctx, snapshot)
+		if err == nil {
+			log.FromContext(ctx).Info("taking snapshot of claim", "claim", claim.Name, "snapshot", snapshot.GetName())
+		} else if !k8serrors.IsAlreadyExists(err) {
+			return err
+		}
+
+		if err = s.k8sClient.Get(ctx, client.ObjectKeyFromObject(snapshot), snapshot); err != nil {
+			return err
+		}
+
+		// e.g. there is no default snapshot class
+		if msg, _, _ := unstructured.NestedString(snapshot.Object, "status", "error", "message"); msg != "" {
+			return errors.Wrapf(ErrSnapshotFailed, "snapshot %s: %s", snapshot.GetName(), msg)
+		}
+		if ready, _, _ := unstructured.NestedBool(snapshot.Object, "status", "readyToUse"); !ready {
+			pending = append(pending, snapshot.GetName())
+		}
+	}
+
+	if len(pending) > 0 {
+		return errors.Wrapf(ErrSnapshotNotReady, "waiting for %s", strings.Join(pending, ", "))
+	}
+
 	return nil
 }
 
-func (s *valkeyService) deleteSecret(ctx context.Context, i types.NamespacedName) error {
-	err := s.k8sClient.Delete(ctx, &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      i.Name,
-			Namespace: i.Namespace,
-		},
-	})
+// volumeSnapshot returns snapshot of the claim, name contains uid of Valkey,
+// so the instance recreated with the same name doesn't reuse old snapshots
+func volumeSnapshot(claim *corev1.PersistentVolumeClaim, uid types.UID) *unstructured.Unstructured {
+	name := claim.Name
+	if uid != "" {
+		name += "-" + string(uid)[:min(len(uid), snapshotUIDLength)]
+	}
+
+	res := new(unstructured.Unstructured)
+	res.SetGroupVersionKind(volumeSnapshotGVK)
+	res.SetName(name)
+	res.SetNamespace(claim.Namespace)
+	res.SetLabels(claim.Labels)
+	_ = unstructured.SetNestedField(res.Object, claim.Name, "spec", "source", "persistentVolumeClaimName")
+
+	return res
+}
+
+// adoptRetainedClaims removes label of claims retained after deletion of the
+// instance with the same name, StatefulSet takes them by name of the claim
+func (s *valkeyService) adoptRetainedClaims(ctx context.Context, i types.NamespacedName) error {
+	claims, err := s.listClaims(ctx, i)
 	if err != nil {
-		if k8serrors.IsNotFound(err) {
-			return nil
+		return err
+	}
+
+	for idx := range claims {
+		claim := &claims[idx]
+		if _, ok := claim.Labels[v1alpha1.LabelRetainedFrom]; !ok {
+			continue
 		}
 
-		return err
+		patch := client.MergeFrom(claim.DeepCopy())
+		delete(claim.Labels, v1alpha1.LabelRetainedFrom)
+
+		log.FromContext(ctx).Info("adopting retained claim", "claim", claim.Name)
+		err = s.k8sClient.Patch(ctx, claim, patch)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func (s *valkeyService) deleteDeployment(ctx context.Context, i types.NamespacedName) error {
-	err := s.k8sClient.Delete(ctx, &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      i.Name,
-			Namespace: i.Namespace,
-		},
-	})
-	if err != nil && !k8serrors.IsNotFound(err) {
+func (s *valkeyService) deleteOrphanClaims(ctx context.Context, i types.NamespacedName) error {
+	claims, err := s.listClaims(ctx, i)
+	if err != nil {
 		return err
 	}
 
-	err = s.k8sClient.Delete(ctx, &corev1.PersistentVolumeClaim{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      i.Name,
-			Namespace: i.Namespace,
-		},
-	})
-	if err != nil {
-		if k8serrors.IsNotFound(err) {
-			return nil
+	for idx := range claims {
+		claim := &claims[idx]
+		if len(claim.OwnerReferences) > 0 {
+			continue
 		}
 
-		return err
+		log.FromContext(ctx).Info("deleting orphan claim", "claim", claim.Name)
+		err = s.k8sClient.Delete(ctx, claim)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
 	}
 
 	return nil
@@ -3,10 +3,14 @@ package valkey
 import (
 	"context"
 
+	"github.com/samber/lo"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/uagolang/k8s-operator/internal/lib/validator"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
 )
 
 type IsReadyRequest struct {
@@ -19,18 +23,41 @@ func (s *valkeyService) IsReady(ctx context.Context, i *IsReadyRequest) (bool, i
 		return false, 0, err
 	}
 
-	deployment := &appsv1.Deployment{}
+	sts := &appsv1.StatefulSet{}
 	err := s.k8sClient.Get(ctx, types.NamespacedName{
 		Name:      i.Name,
 		Namespace: i.Namespace,
-	}, deployment)
+	}, sts)
 	if err != nil {
 		return false, 0, err
 	}
 
-	if deployment.Status.ReadyReplicas > 0 {
-		return true, deployment.Status.ReadyReplicas, nil
+	if sts.Status.ReadyReplicas > 0 {
+		return true, sts.Status.ReadyReplicas, nil
 	}
 
 	return false, 0, nil
 }
+
+// IsStorageReady checks that every volume claim of StatefulSet is bound
+func (s *valkeyService) IsStorageReady(ctx context.Context, i *IsReadyRequest) (bool, error) {
+	if err := validator.Validate(ctx, i); err != nil {
+		return false, err
+	}
+
+	claims := new(corev1.PersistentVolumeClaimList)
+	err := s.k8sClient.List(ctx, claims,
+		client.InNamespace(i.Namespace),
+		client.MatchingLabels(render.SelectorLabels(i.Name)),
+	)
+	if err != nil {
+		return false, err
+	}
+	if len(claims.Items) == 0 {
+		return false, nil
+	}
+
+	return lo.EveryBy(claims.Items, func(c corev1.PersistentVolumeClaim) bool {
+		return c.Status.Phase == corev1.ClaimBound
+	}), nil
+}
@@ -0,0 +1,172 @@
+package valkey
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
+	"github.com/uagolang/k8s-operator/internal/utils"
+)
+
+// migrateDeployment replaces Deployment created by previous versions of
+// the operator with StatefulSet. Deployment is scaled down first, so its
+// pod doesn't write to the legacy claim while it's cloned and doesn't run
+// together with StatefulSet pods. Data from the legacy claim mounted by
+// the Deployment is cloned into the claim of the first replica, so instance
+// keeps its dataset. Legacy claim is left untouched and could be removed
+// manually. ErrMigrationInProgress is returned until pods are stopped
+func (s *valkeyService) migrateDeployment(ctx context.Context, item *v1alpha1.Valkey, sts *appsv1.StatefulSet) error {
+	dep, err := s.getDeployment(ctx, types.NamespacedName{
+		Name:      item.Name,
+		Namespace: item.Namespace,
+	})
+	if err != nil {
+		return err
+	}
+	if dep == nil {
+		return nil
+	}
+
+	if lo.FromPtrOr(dep.Spec.Replicas, 1) > 0 {
+		patch := client.MergeFrom(dep.DeepCopy())
+		dep.Spec.Replicas = utils.Pointer(int32(0))
+		if err = s.k8sClient.Patch(ctx, dep, patch); err != nil {
+			return err
+		}
+
+		return errors.Wrapf(ErrMigrationInProgress, "deployment %s is scaled down", dep.Name)
+	}
+
+	running, err := s.countDeploymentPods(ctx, dep)
+	if err != nil {
+		return err
+	}
+	if running > 0 {
+		return errors.Wrapf(ErrMigrationInProgress, "%d pods of deployment %s are running", running, dep.Name)
+	}
+
+	if len(sts.Spec.VolumeClaimTemplates) > 0 && mountsLegacyPvc(dep) {
+		err = s.cloneLegacyPvc(ctx, item.Namespace, sts)
+		if err != nil {
+			return err
+		}
+	}
+
+	// applied object is replaced with response of the server
+	_, err = s.apply(ctx, sts.DeepCopy())
+	if err != nil {
+		return err
+	}
+
+	err = s.k8sClient.Delete(ctx, dep, client.PropagationPolicy(metav1.DeletePropagationForeground))
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// cloneLegacyPvc pre-creates claim of the first StatefulSet replica using
+// legacy claim as a data source, StatefulSet controller adopts it by name
+func (s *valkeyService) cloneLegacyPvc(ctx context.Context, namespace string, sts *appsv1.StatefulSet) error {
+	legacy, err := s.getPvc(ctx, types.NamespacedName{
+		Name:      legacyPvcName,
+		Namespace: namespace,
+	})
+	if err != nil {
+		return err
+	}
+	if legacy == nil {
+		return nil
+	}
+
+	claim := *sts.Spec.VolumeClaimTemplates[0].DeepCopy()
+	claim.Name = render.PvcName(sts.Name, 0)
+	claim.Namespace = namespace
+	claim.Spec.StorageClassName = legacy.Spec.StorageClassName
+	claim.Spec.DataSource = &corev1.TypedLocalObjectReference{
+		Kind: "PersistentVolumeClaim",
+		Name: legacy.Name,
+	}
+
+	// clone can't be smaller than its source
+	legacySize := legacy.Spec.Resources.Requests[corev1.ResourceStorage]
+	if legacySize.Cmp(claim.Spec.Resources.Requests[corev1.ResourceStorage]) > 0 {
+		claim.Spec.Resources.Requests[corev1.ResourceStorage] = legacySize
+	}
+
+	err = s.k8sClient.Create(ctx, &claim)
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	return nil
+}
+
+// countDeploymentPods counts pods of Deployment including terminating ones,
+// they could still write to the legacy claim. Pods of StatefulSet could
+// match the selector too, they aren't counted
+func (s *valkeyService) countDeploymentPods(ctx context.Context, dep *appsv1.Deployment) (int, error) {
+	if dep.Spec.Selector == nil {
+		return 0, nil
+	}
+
+	pods := new(corev1.PodList)
+	err := s.k8sClient.List(ctx, pods,
+		client.InNamespace(dep.Namespace),
+		client.MatchingLabels(dep.Spec.Selector.MatchLabels),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return lo.CountBy(pods.Items, func(p corev1.Pod) bool {
+		ref := metav1.GetControllerOf(&p)
+		return ref == nil || ref.Kind != "StatefulSet"
+	}), nil
+}
+
+// mountsLegacyPvc tells whether Deployment used the legacy claim, it has the
+// same name in the namespace, so claim isn't cloned for other instances
+func mountsLegacyPvc(dep *appsv1.Deployment) bool {
+	return lo.ContainsBy(dep.Spec.Template.Spec.Volumes, func(v corev1.Volume) bool {
+		return v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName == legacyPvcName
+	})
+}
+
+func (s *valkeyService) getDeployment(ctx context.Context, i types.NamespacedName) (*appsv1.Deployment, error) {
+	res := new(appsv1.Deployment)
+	err := s.k8sClient.Get(ctx, i, res)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (s *valkeyService) getPvc(ctx context.Context, i types.NamespacedName) (*corev1.PersistentVolumeClaim, error) {
+	res := new(corev1.PersistentVolumeClaim)
+	err := s.k8sClient.Get(ctx, i, res)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,199 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+)
+
+const (
+	// AnnotationConfigHash on pod template restarts pods when static parameters are changed
+	AnnotationConfigHash = "database.kuberly.io/config-hash"
+
+	configVolumeName = "config"
+	configMountPath  = "/etc/valkey"
+	configFile       = "valkey.conf"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+// configParameters are parameters which could be set by spec, value tells
+// whether parameter is changed by CONFIG SET or requires restart. Parameters
+// managed by operator, e.g. ports, TLS, replication and cluster, aren't here
+var configParameters = map[string]bool{
+	// applied by CONFIG SET
+	"active-defrag-cycle-max":       true,
+	"active-defrag-cycle-min":       true,
+	"active-defrag-ignore-bytes":    true,
+	"active-defrag-threshold-lower": true,
+	"active-defrag-threshold-upper": true,
+	"active-expire-effort":          true,
+	"activedefrag":                  true,
+	"aof-load-truncated":            true,
+	"aof-use-rdb-preamble":          true,
+	"appendfsync":                   true,
+	"appendonly":                    true,
+	"auto-aof-rewrite-min-size":     true,
+	"auto-aof-rewrite-percentage":   true,
+	"busy-reply-threshold":          true,
+	"client-output-buffer-limit":    true,
+	"client-query-buffer-limit":     true,
+	"hash-max-listpack-entries":     true,
+	"hash-max-listpack-value":       true,
+	"hz":                            true,
+	"latency-monitor-threshold":     true,
+	"lazyfree-lazy-eviction":        true,
+	"lazyfree-lazy-expire":          true,
+	"lazyfree-lazy-server-del":      true,
+	"lazyfree-lazy-user-del":        true,
+	"lazyfree-lazy-user-flush":      true,
+	"lfu-decay-time":                true,
+	"lfu-log-factor":                true,
+	"list-max-listpack-size":        true,
+	"loglevel":                      true,
+	"maxclients":                    true,
+	"maxmemory":                     true,
+	"maxmemory-clients":             true,
+	"maxmemory-policy":              true,
+	"maxmemory-samples":             true,
+	"min-replicas-max-lag":          true,
+	"min-replicas-to-write":         true,
+	"no-appendfsync-on-rewrite":     true,
+	"notify-keyspace-events":        true,
+	"proto-max-bulk-len":            true,
+	"rdb-del-sync-files":            true,
+	"rdbchecksum":                   true,
+	"rdbcompression":                true,
+	"repl-backlog-size":             true,
+	"repl-diskless-load":            true,
+	"repl-diskless-sync":            true,
+	"repl-diskless-sync-delay":      true,
+	"repl-timeout":                  true,
+	"replica-lazy-flush":            true,
+	"replica-priority":              true,
+	"replica-serve-stale-data":      true,
+	"save":                          true,
+	"set-max-intset-entries":        true,
+	"slowlog-log-slower-than":       true,
+	"slowlog-max-len":               true,
+	"stop-writes-on-bgsave-error":   true,
+	"stream-node-max-bytes":         true,
+	"stream-node-max-entries":       true,
+	"tcp-keepalive":                 true,
+	"timeout":                       true,
+	"zset-max-listpack-entries":     true,
+	"zset-max-listpack-value":       true,
+
+	// read only on start
+	"appenddirname":  false,
+	"appendfilename": false,
+	"databases":      false,
+	"io-threads":     false,
+	"tcp-backlog":    false,
+}
+
+// ValidateConfig rejects parameters which aren't known or managed by operator,
+// values are written to the config file, so they can't span several lines
+func ValidateConfig(config map[string]string) error {
+	for _, key := range lo.Keys(config) {
+		if _, ok := configParameters[key]; !ok {
+			return errors.Wrapf(ErrInvalidConfig, "parameter %s isn't supported", key)
+		}
+		if strings.ContainsAny(config[key], "\r\n") {
+			return errors.Wrapf(ErrInvalidConfig, "value of %s contains line break", key)
+		}
+	}
+
+	return nil
+}
+
+// IsDynamicParameter tells whether parameter is applied by CONFIG SET
+func IsDynamicParameter(key string) bool {
+	return configParameters[key]
+}
+
+// DynamicConfig returns parameters which are applied without restart
+func DynamicConfig(config map[string]string) map[string]string {
+	return lo.PickBy(config, func(key, _ string) bool { return IsDynamicParameter(key) })
+}
+
+// ConfigHash returns hash of parameters which doesn't depend on their order
+func ConfigHash(config map[string]string) string {
+	if len(config) == 0 {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(configContent(config)))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// ConfigMapName returns name of the ConfigMap with valkey.conf
+func ConfigMapName(crdName string) string {
+	return crdName + "-config"
+}
+
+// ConfigMap renders valkey.conf with parameters of the spec,
+// the file is read by Valkey only on start and is rendered without parameters too
+func ConfigMap(item *v1alpha1.Valkey) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: objectMeta(item, ConfigMapName(item.Name)),
+		Data: map[string]string{
+			configFile: configContent(item.Spec.Config),
+		},
+	}
+}
+
+func configContent(config map[string]string) string {
+	keys := lo.Keys(config)
+	slices.Sort(keys)
+
+	var res strings.Builder
+	for _, key := range keys {
+		// empty value is written quoted, e.g. save "" disables snapshots
+		value := config[key]
+		if value == "" {
+			value = `""`
+		}
+		res.WriteString(key + " " + value + "\n")
+	}
+
+	return res.String()
+}
+
+// staticConfig returns parameters which require restart
+func staticConfig(config map[string]string) map[string]string {
+	return lo.OmitBy(config, func(key, _ string) bool { return IsDynamicParameter(key) })
+}
+
+func configVolume(item *v1alpha1.Valkey) (corev1.Volume, corev1.VolumeMount) {
+	volume := corev1.Volume{
+		Name: configVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: ConfigMapName(item.Name),
+				},
+			},
+		},
+	}
+	mount := corev1.VolumeMount{
+		Name:      configVolumeName,
+		MountPath: configMountPath,
+		ReadOnly:  true,
+	}
+
+	return volume, mount
+}
@@ -0,0 +1,106 @@
+package render
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+)
+
+const (
+	SecretKeyPassword = "password"
+	envPassword       = "VALKEY_PASSWORD"
+
+	// AnnotationPasswordHash on pod template restarts pods when password is changed
+	AnnotationPasswordHash = "database.kuberly.io/password-hash"
+
+	containerName = "valkey"
+	ContainerPort = 6379
+
+	clusterBusPortOffset = 10000
+	clusterConfigFile    = "nodes.conf"
+	clusterNodeTimeout   = "5000"
+
+	sentinelContainerName = "sentinel"
+	SentinelPort          = 26379
+	sentinelConfigVolume  = "config"
+	sentinelConfigPath    = "/etc/sentinel"
+
+	dataVolumeName = "data"
+	dataMountPath  = "/data"
+
+	LabelApp     = "app"
+	LabelRole    = "database.kuberly.io/role"
+	LabelPodName = "statefulset.kubernetes.io/pod-name"
+
+	RolePrimary = "primary"
+	RoleReplica = "replica"
+)
+
+func SelectorLabels(crdName string) map[string]string {
+	return map[string]string{LabelApp: crdName}
+}
+
+func SentinelSelectorLabels(crdName string) map[string]string {
+	return map[string]string{LabelApp: SentinelName(crdName)}
+}
+
+// PasswordSecret returns name and key of the Secret with password,
+// the Secret named after CRD is used when there is no reference
+func PasswordSecret(crdName string, ref *v1alpha1.SecretKeyRef) (string, string) {
+	if ref == nil {
+		return crdName, SecretKeyPassword
+	}
+	if ref.Key == "" {
+		return ref.Name, SecretKeyPassword
+	}
+
+	return ref.Name, ref.Key
+}
+
+// PodPasswordSecret returns name and key of the Secret with password which
+// the pod requires, it's empty when the pod doesn't require password,
+// e.g. it isn't restarted yet after password was enforced
+func PodPasswordSecret(pod *corev1.Pod) (string, string) {
+	container, ok := lo.Find(pod.Spec.Containers, func(c corev1.Container) bool { return c.Name == containerName })
+	if !ok || !slices.Contains(container.Args, "--requirepass") {
+		return "", ""
+	}
+
+	env, ok := lo.Find(container.Env, func(e corev1.EnvVar) bool { return e.Name == envPassword })
+	if !ok || env.ValueFrom == nil || env.ValueFrom.SecretKeyRef == nil {
+		return "", ""
+	}
+
+	return env.ValueFrom.SecretKeyRef.Name, env.ValueFrom.SecretKeyRef.Key
+}
+
+// PvcName returns name of the claim which StatefulSet controller
+// creates from volume claim template for pod with given ordinal
+func PvcName(crdName string, ordinal int) string {
+	return dataVolumeName + "-" + PodName(crdName, ordinal)
+}
+
+func PodName(crdName string, ordinal int) string {
+	return crdName + "-" + strconv.Itoa(ordinal)
+}
+
+// PodHost returns stable DNS name of the pod provided by headless service
+func PodHost(pod, crdName, namespace string) string {
+	return pod + "." + crdName + "." + namespace + ".svc"
+}
+
+func ReadWriteServiceName(crdName string) string {
+	return crdName + "-rw"
+}
+
+func ReadOnlyServiceName(crdName string) string {
+	return crdName + "-ro"
+}
+
+func SentinelName(crdName string) string {
+	return crdName + "-sentinel"
+}
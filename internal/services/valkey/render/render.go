@@ -0,0 +1,117 @@
+// Package render builds desired child objects of Valkey. Functions of the
+// package don't talk to the API server, so create and update paths apply
+// exactly the same objects and every spec combination is covered by tests.
+package render
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+)
+
+// Options contains state which isn't part of Valkey but changes desired objects
+type Options struct {
+	// Password is a value stored in the Secret owned by operator,
+	// the Secret isn't rendered when it's empty or Secret is referenced
+	Password []byte
+	// PasswordHash is a hash of the password used by pods,
+	// pods are restarted when it's changed
+	PasswordHash string
+	// Restore seeds empty data volumes, it's resolved only on create,
+	// so init container is kept from existing StatefulSet on update
+	Restore *Restore
+}
+
+// Objects are desired child objects of Valkey, optional ones are nil
+type Objects struct {
+	Secret              *corev1.Secret
+	ConfigMap           *corev1.ConfigMap
+	Certificate         *unstructured.Unstructured
+	StatefulSet         *appsv1.StatefulSet
+	Service             *corev1.Service
+	ReadWriteService    *corev1.Service
+	ReadOnlyService     *corev1.Service
+	SentinelService     *corev1.Service
+	SentinelStatefulSet *appsv1.StatefulSet
+}
+
+// Render returns desired objects of Valkey, rw service of replication
+// mode selects primary from status or the first pod when it's unknown
+func Render(item *v1alpha1.Valkey, opts Options) (*Objects, error) {
+	if err := ValidateConfig(item.Spec.Config); err != nil {
+		return nil, err
+	}
+
+	sts, err := StatefulSet(item, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Objects{
+		ConfigMap:   ConfigMap(item),
+		StatefulSet: sts,
+		Service:     Service(item),
+	}
+
+	if item.Spec.PasswordSecretRef() == nil && len(opts.Password) > 0 {
+		res.Secret = Secret(item, opts.Password)
+	}
+
+	if item.Spec.TLSEnabled() && item.Spec.TLS.IssuerRef != nil {
+		res.Certificate = Certificate(item)
+	}
+
+	if item.Spec.Mode == v1alpha1.TypeModeReplication {
+		res.ReadWriteService, res.ReadOnlyService = ReplicationServices(item)
+	}
+
+	if item.Spec.Sentinel != nil && item.Spec.Sentinel.Enabled {
+		res.SentinelService = SentinelService(item)
+		res.SentinelStatefulSet = SentinelStatefulSet(item, opts)
+	}
+
+	return res, nil
+}
+
+// List returns rendered objects in the order they should be applied
+func (o *Objects) List() []client.Object {
+	var res []client.Object
+	if o.Secret != nil {
+		res = append(res, o.Secret)
+	}
+	if o.Certificate != nil {
+		res = append(res, o.Certificate)
+	}
+
+	res = append(res, o.ConfigMap, o.StatefulSet, o.Service)
+
+	if o.ReadWriteService != nil {
+		res = append(res, o.ReadWriteService, o.ReadOnlyService)
+	}
+	if o.SentinelStatefulSet != nil {
+		res = append(res, o.SentinelService, o.SentinelStatefulSet)
+	}
+
+	return res
+}
+
+// ownerReferences makes object owned by Valkey,
+// so it's removed by garbage collector together with the CRD
+func ownerReferences(item *v1alpha1.Valkey) []metav1.OwnerReference {
+	return []metav1.OwnerReference{
+		*metav1.NewControllerRef(item, v1alpha1.GroupVersion.WithKind("Valkey")),
+	}
+}
+
+func objectMeta(item *v1alpha1.Valkey, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       item.Namespace,
+		Labels:          SelectorLabels(item.Name),
+		OwnerReferences: ownerReferences(item),
+	}
+}
@@ -0,0 +1,319 @@
+package render_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
+	"github.com/uagolang/k8s-operator/internal/utils"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func newValkey(mutate func(item *v1alpha1.Valkey)) *v1alpha1.Valkey {
+	item := &v1alpha1.Valkey{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app-db",
+			Namespace: "default",
+			UID:       "valkey-uid",
+		},
+		Spec: v1alpha1.ValkeySpec{
+			Image:    "valkey/valkey:8.0",
+			Replicas: utils.Pointer(int32(1)),
+			Mode:     v1alpha1.TypeModeStandalone,
+			User:     "root",
+			Resource: v1alpha1.Resource{
+				CPU:     "100m",
+				Memory:  "256Mi",
+				Storage: "1Gi",
+			},
+		},
+	}
+	mutate(item)
+
+	return item
+}
+
+func TestRender(t *testing.T) {
+	opts := render.Options{
+		Password:     []byte("secret"),
+		PasswordHash: "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b",
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(item *v1alpha1.Valkey)
+		opts   render.Options
+	}{
+		{
+			name:   "standalone",
+			mutate: func(item *v1alpha1.Valkey) {},
+			opts:   opts,
+		},
+		{
+			name: "standalone_with_volume",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Replicas = utils.Pointer(int32(2))
+				item.Spec.Volume = v1alpha1.Volume{Enabled: true, Storage: "1Gi"}
+			},
+			opts: opts,
+		},
+		{
+			name: "retained_volume",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Volume = v1alpha1.Volume{Enabled: true, Storage: "1Gi"}
+				item.Spec.DeletionPolicy = v1alpha1.TypeDeletionPolicyRetain
+			},
+			opts: opts,
+		},
+		{
+			name: "restore_from_backup",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Volume = v1alpha1.Volume{Enabled: true, Storage: "1Gi"}
+			},
+			opts: render.Options{
+				Password:     opts.Password,
+				PasswordHash: opts.PasswordHash,
+				Restore: &render.Restore{
+					Image:      "curlimages/curl:8.10.1",
+					BackupName: "app-db-nightly",
+					Destination: &v1alpha1.BackupDestination{
+						S3: &v1alpha1.S3Destination{
+							Endpoint:     "http://minio.minio:9000",
+							Bucket:       "backups",
+							Prefix:       "app-db",
+							Region:       "us-east-1",
+							AccessKeyRef: v1alpha1.SecretKeyRef{Name: "minio", Key: "accessKey"},
+							SecretKeyRef: v1alpha1.SecretKeyRef{Name: "minio", Key: "secretKey"},
+						},
+					},
+					Checksum: "abc",
+				},
+			},
+		},
+		{
+			name: "restore_from_pvc",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Volume = v1alpha1.Volume{Enabled: true, Storage: "1Gi"}
+			},
+			opts: render.Options{
+				Password:     opts.Password,
+				PasswordHash: opts.PasswordHash,
+				Restore: &render.Restore{
+					Image:      "curlimages/curl:8.10.1",
+					BackupName: "app-db-nightly",
+					Destination: &v1alpha1.BackupDestination{
+						PVC: &v1alpha1.PVCDestination{ClaimName: "backups", Path: "app-db"},
+					},
+				},
+			},
+		},
+		{
+			name: "clone",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Volume = v1alpha1.Volume{Enabled: true, Storage: "1Gi"}
+			},
+			opts: render.Options{
+				Password:     opts.Password,
+				PasswordHash: opts.PasswordHash,
+				Restore: &render.Restore{
+					Image:             "valkey/valkey:8.0",
+					SourceHost:        "prod-db-0.prod-db.default.svc",
+					SourcePasswordRef: &v1alpha1.SecretKeyRef{Name: "prod-db", Key: "password"},
+				},
+			},
+		},
+		{
+			name: "password_secret_ref",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Auth = &v1alpha1.Auth{
+					PasswordSecretRef: &v1alpha1.SecretKeyRef{Name: "app-db-auth", Key: "pass"},
+				}
+			},
+			opts: render.Options{PasswordHash: opts.PasswordHash},
+		},
+		{
+			name:   "password_not_known",
+			mutate: func(item *v1alpha1.Valkey) {},
+		},
+		{
+			name: "replication",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Mode = v1alpha1.TypeModeReplication
+				item.Spec.Replicas = utils.Pointer(int32(3))
+			},
+			opts: opts,
+		},
+		{
+			name: "replication_with_sentinel",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Mode = v1alpha1.TypeModeReplication
+				item.Spec.Replicas = utils.Pointer(int32(3))
+				item.Spec.Sentinel = &v1alpha1.Sentinel{Enabled: true, Image: "valkey/valkey:8.0-alpine"}
+				item.Status.Primary = "app-db-2"
+			},
+			opts: opts,
+		},
+		{
+			name: "cluster",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Mode = v1alpha1.TypeModeCluster
+				item.Spec.Replicas = utils.Pointer(int32(0))
+				item.Spec.Cluster = &v1alpha1.Cluster{Shards: 3, ReplicasPerShard: 1}
+				item.Spec.Volume = v1alpha1.Volume{Enabled: true, Storage: "1Gi"}
+			},
+			opts: opts,
+		},
+		{
+			name: "tls_secret",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.TLS = &v1alpha1.TLS{Enabled: true, SecretName: "app-db-certs"}
+			},
+			opts: opts,
+		},
+		{
+			name: "tls_issuer_without_plaintext",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Mode = v1alpha1.TypeModeCluster
+				item.Spec.Replicas = utils.Pointer(int32(0))
+				item.Spec.Cluster = &v1alpha1.Cluster{Shards: 3, ReplicasPerShard: 1}
+				item.Spec.TLS = &v1alpha1.TLS{
+					Enabled:          true,
+					IssuerRef:        &v1alpha1.IssuerRef{Name: "ca", Kind: "ClusterIssuer"},
+					DisablePlaintext: true,
+				}
+			},
+			opts: opts,
+		},
+		{
+			name: "tls_with_sentinel",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Mode = v1alpha1.TypeModeReplication
+				item.Spec.Replicas = utils.Pointer(int32(3))
+				item.Spec.Sentinel = &v1alpha1.Sentinel{Enabled: true}
+				item.Spec.TLS = &v1alpha1.TLS{Enabled: true, SecretName: "app-db-certs"}
+			},
+			opts: opts,
+		},
+		{
+			name: "config",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Config = map[string]string{
+					"maxmemory-policy": "allkeys-lru",
+					"save":             "",
+					"databases":        "4",
+				}
+			},
+			opts: opts,
+		},
+		{
+			name: "config_with_replication",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Mode = v1alpha1.TypeModeReplication
+				item.Spec.Replicas = utils.Pointer(int32(3))
+				item.Spec.Config = map[string]string{"maxmemory-policy": "allkeys-lru"}
+			},
+			opts: opts,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs, err := render.Render(newValkey(tt.mutate), tt.opts)
+			require.NoError(t, err)
+
+			docs := make([]string, 0, len(objs.List()))
+			for _, obj := range objs.List() {
+				data, err := yaml.Marshal(obj)
+				require.NoError(t, err)
+				docs = append(docs, string(data))
+			}
+			got := strings.Join(docs, "---\n")
+
+			path := filepath.Join("testdata", tt.name+".yaml")
+			if *update {
+				require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+			}
+
+			want, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, string(want), got)
+		})
+	}
+}
+
+func TestRender_InvalidQuantity(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(item *v1alpha1.Valkey)
+	}{
+		{
+			name:   "cpu",
+			mutate: func(item *v1alpha1.Valkey) { item.Spec.Resource.CPU = "" },
+		},
+		{
+			name:   "memory",
+			mutate: func(item *v1alpha1.Valkey) { item.Spec.Resource.Memory = "lots" },
+		},
+		{
+			name: "storage",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Volume.Enabled = true
+				item.Spec.Resource.Storage = ""
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := render.Render(newValkey(tt.mutate), render.Options{})
+			require.ErrorIs(t, err, render.ErrInvalidQuantity)
+			require.ErrorContains(t, err, "invalid "+tt.name)
+		})
+	}
+}
+
+func TestRender_InvalidConfig(t *testing.T) {
+	_, err := render.Render(newValkey(func(item *v1alpha1.Valkey) {
+		item.Spec.Config = map[string]string{"port": "6390"}
+	}), render.Options{})
+	require.ErrorIs(t, err, render.ErrInvalidConfig)
+}
+
+func TestConfigHash(t *testing.T) {
+	config := map[string]string{"maxmemory-policy": "allkeys-lru", "databases": "4"}
+
+	require.Empty(t, render.ConfigHash(nil))
+	require.Equal(t, render.ConfigHash(config), render.ConfigHash(map[string]string{
+		"databases":        "4",
+		"maxmemory-policy": "allkeys-lru",
+	}))
+	require.Equal(t, map[string]string{"maxmemory-policy": "allkeys-lru"}, render.DynamicConfig(config))
+}
+
+func TestRender_ReplicaCommand(t *testing.T) {
+	objects, err := render.Render(newValkey(func(item *v1alpha1.Valkey) {}), render.Options{})
+	require.NoError(t, err)
+	require.Empty(t, objects.StatefulSet.Spec.Template.Spec.Containers[0].Command)
+
+	objects, err = render.Render(newValkey(func(item *v1alpha1.Valkey) {
+		item.Spec.Mode = v1alpha1.TypeModeReplication
+		item.Spec.TLS = &v1alpha1.TLS{Enabled: true, SecretName: "app-db-certs", DisablePlaintext: true}
+	}), render.Options{})
+	require.NoError(t, err)
+
+	container := objects.StatefulSet.Spec.Template.Spec.Containers[0]
+	require.Equal(t, "valkey-server", container.Args[0])
+	require.Len(t, container.Command, 4)
+	script := container.Command[2]
+	require.Contains(t, script, "for SVC in app-db-rw.default.svc app-db-ro.default.svc")
+	require.Contains(t, script, `-p 6380 --tls --cacert /tls/ca.crt -a "$VALKEY_PASSWORD"`)
+	require.Contains(t, script, `--replicaof "$PRIMARY" 6380`)
+	require.Contains(t, script, "PRIMARY=app-db-0.app-db.default.svc")
+}
@@ -0,0 +1,111 @@
+package render
+
+import (
+	"path"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/utils"
+)
+
+const (
+	// RestoreContainerName is a name of init container which seeds data volume
+	RestoreContainerName = "restore"
+	// RestoreVolumeName is a name of volume with RDB file of the backup
+	RestoreVolumeName = "restore"
+
+	restoreMountPath = "/restore"
+	rdbFile          = "dump.rdb"
+)
+
+// Restore is a resolved source of RDB file which is seeded to empty
+// data volumes, exactly one of Destination or SourceHost is set
+type Restore struct {
+	// Image of the init container
+	Image string
+	// BackupName is a name of the backup which RDB file is downloaded
+	BackupName string
+	// Destination is where RDB file of the backup was copied to
+	Destination *v1alpha1.BackupDestination
+	// Checksum is sha256 of RDB file of the backup, it's verified if set
+	Checksum string
+	// SourceHost is an address of the instance which data is cloned
+	SourceHost string
+	// SourcePasswordRef is the Secret with password of the cloned instance
+	SourcePasswordRef *v1alpha1.SecretKeyRef
+}
+
+// restoreScript doesn't touch volume which has RDB file already,
+// so restarted and failed over pods keep their data
+const restoreScript = `set -eu
+if [ -f ` + dataMountPath + `/` + rdbFile + ` ]; then
+  echo "data volume isn't empty, restore is skipped"
+  exit 0
+fi
+if [ -n "${SOURCE_HOST:-}" ]; then
+  valkey-cli -h "$SOURCE_HOST" -p 6379 -a "$SOURCE_PASSWORD" --no-auth-warning --rdb ` + dataMountPath + `/restore.rdb
+elif [ -n "${RESTORE_FILE:-}" ]; then
+  cp "$RESTORE_FILE" ` + dataMountPath + `/restore.rdb
+else
+  curl -fsS --aws-sigv4 "aws:amz:${S3_REGION}:s3" --user "${S3_ACCESS_KEY}:${S3_SECRET_KEY}" -o ` + dataMountPath + `/restore.rdb "$S3_URL"
+fi
+if [ -n "${RESTORE_CHECKSUM:-}" ]; then
+  echo "$RESTORE_CHECKSUM  ` + dataMountPath + `/restore.rdb" | sha256sum -c -
+fi
+mv ` + dataMountPath + `/restore.rdb ` + dataMountPath + `/` + rdbFile + `
+`
+
+// restoreSpec returns init container which seeds data volume before
+// Valkey is started and volume it needs, volume is nil if none
+func restoreSpec(restore *Restore) (corev1.Container, *corev1.Volume) {
+	mounts := []corev1.VolumeMount{{Name: dataVolumeName, MountPath: dataMountPath}}
+
+	var env []corev1.EnvVar
+	var volume *corev1.Volume
+	switch dest := lo.FromPtr(restore.Destination); {
+	case restore.SourceHost != "":
+		ref := restore.SourcePasswordRef
+		env = []corev1.EnvVar{
+			{Name: "SOURCE_HOST", Value: restore.SourceHost},
+			secretKeyEnv("SOURCE_PASSWORD", ref.Name, ref.Key),
+		}
+	case dest.PVC != nil:
+		volume = &corev1.Volume{
+			Name: RestoreVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: dest.PVC.ClaimName,
+					ReadOnly:  true,
+				},
+			},
+		}
+		mounts = append(mounts, corev1.VolumeMount{Name: RestoreVolumeName, MountPath: restoreMountPath, ReadOnly: true})
+		env = []corev1.EnvVar{{
+			Name:  "RESTORE_FILE",
+			Value: path.Join(restoreMountPath, dest.PVC.FilePath(restore.BackupName)),
+		}}
+	case dest.S3 != nil:
+		env = []corev1.EnvVar{
+			{Name: "S3_URL", Value: dest.S3.URL(restore.BackupName)},
+			{Name: "S3_REGION", Value: lo.CoalesceOrEmpty(dest.S3.Region, "us-east-1")},
+			secretKeyEnv("S3_ACCESS_KEY", dest.S3.AccessKeyRef.Name, dest.S3.AccessKeyRef.Key),
+			secretKeyEnv("S3_SECRET_KEY", dest.S3.SecretKeyRef.Name, dest.S3.SecretKeyRef.Key),
+		}
+	}
+
+	if restore.Checksum != "" {
+		env = append(env, corev1.EnvVar{Name: "RESTORE_CHECKSUM", Value: restore.Checksum})
+	}
+
+	return corev1.Container{
+		Name:         RestoreContainerName,
+		Image:        restore.Image,
+		Command:      []string{"sh", "-c", restoreScript},
+		Env:          env,
+		VolumeMounts: mounts,
+		// data volume is owned by root until Valkey starts
+		SecurityContext: &corev1.SecurityContext{RunAsUser: utils.Pointer(int64(0))},
+	}, volume
+}
@@ -0,0 +1,23 @@
+package render
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+)
+
+// Secret renders the Secret with password owned by operator
+func Secret(item *v1alpha1.Valkey, password []byte) *corev1.Secret {
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Secret",
+		},
+		ObjectMeta: objectMeta(item, item.Name),
+		Type:       corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			SecretKeyPassword: password,
+		},
+	}
+}
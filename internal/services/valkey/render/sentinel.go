@@ -0,0 +1,152 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/utils"
+)
+
+// sentinelScript writes Sentinel config and starts it. Sentinel rewrites
+// its config after failover, so on restart the current primary is asked
+// from the running quorum and the first pod is used only on bootstrap.
+const sentinelScript = `set -e
+PRIMARY=%[1]s
+ADDR=$(valkey-cli -h %[2]s -p %[3]d --raw SENTINEL GET-MASTER-ADDR-BY-NAME %[4]s 2>/dev/null | head -n 1 || true)
+case "$ADDR" in
+  ""|*" "*) ;;
+  *) PRIMARY=$ADDR ;;
+esac
+cat > %[5]s/sentinel.conf <<EOF
+port %[3]d
+sentinel resolve-hostnames yes
+sentinel announce-hostnames yes
+sentinel announce-ip ${POD_NAME}.%[2]s
+sentinel monitor %[4]s ${PRIMARY} %[6]d %[7]d
+sentinel down-after-milliseconds %[4]s 5000
+sentinel failover-timeout %[4]s 60000
+sentinel parallel-syncs %[4]s 1
+sentinel auth-pass %[4]s "${VALKEY_PASSWORD}"
+%[8]sEOF
+exec valkey-server %[5]s/sentinel.conf --sentinel
+`
+
+// SentinelService renders headless service of Sentinel quorum
+func SentinelService(item *v1alpha1.Valkey) *corev1.Service {
+	res := newService(item, SentinelName(item.Name), SentinelSelectorLabels(item.Name), SentinelPort)
+	res.Spec.ClusterIP = corev1.ClusterIPNone
+
+	return res
+}
+
+// SentinelStatefulSet renders Sentinel quorum which monitors the primary,
+// Valkey image is used when Sentinel image isn't set, pods are restarted
+// when hash of the password is changed, so they authenticate with new one
+func SentinelStatefulSet(item *v1alpha1.Valkey, opts Options) *appsv1.StatefulSet {
+	crdName, namespace, sentinel := item.Name, item.Namespace, item.Spec.Sentinel
+
+	image := lo.CoalesceOrEmpty(sentinel.Image, item.Spec.Image)
+
+	replicas := sentinel.Replicas
+	if replicas == 0 {
+		replicas = 3
+	}
+	quorum := sentinel.Quorum
+	if quorum == 0 {
+		quorum = replicas/2 + 1
+	}
+
+	volumes := []corev1.Volume{{
+		Name: sentinelConfigVolume,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	}}
+	mounts := []corev1.VolumeMount{{
+		Name:      sentinelConfigVolume,
+		MountPath: sentinelConfigPath,
+	}}
+
+	// Sentinel itself listens on plaintext port,
+	// but connects to Valkey over TLS like replicas do
+	port, tlsConfig := ContainerPort, ""
+	if item.Spec.TLSEnabled() {
+		port = TLSPort
+		files := TLSFiles()
+		for _, key := range []string{"tls-cert-file", "tls-key-file", "tls-ca-cert-file"} {
+			tlsConfig += key + " " + files[key] + "\n"
+		}
+		tlsConfig += "tls-replication yes\n"
+
+		volume, mount := tlsVolume(item)
+		volumes = append(volumes, volume)
+		mounts = append(mounts, mount)
+	}
+
+	var templateAnnotations map[string]string
+	if opts.PasswordHash != "" {
+		templateAnnotations = map[string]string{AnnotationPasswordHash: opts.PasswordHash}
+	}
+
+	serviceHost := SentinelName(crdName) + "." + namespace + ".svc"
+	script := fmt.Sprintf(sentinelScript,
+		PodHost(PodName(crdName, 0), crdName, namespace),
+		serviceHost,
+		SentinelPort,
+		crdName,
+		sentinelConfigPath,
+		port,
+		quorum,
+		tlsConfig,
+	)
+
+	return &appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "StatefulSet",
+		},
+		ObjectMeta: objectMeta(item, SentinelName(crdName)),
+		Spec: appsv1.StatefulSetSpec{
+			ServiceName: SentinelName(crdName),
+			Replicas:    utils.Pointer(replicas),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: SentinelSelectorLabels(crdName),
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      SentinelSelectorLabels(crdName),
+					Annotations: templateAnnotations,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:    sentinelContainerName,
+							Image:   image,
+							Command: []string{"sh", "-c", strings.TrimSpace(script)},
+							Env: []corev1.EnvVar{
+								{
+									Name: "POD_NAME",
+									ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "metadata.name",
+										},
+									},
+								},
+								passwordEnv(item),
+							},
+							Ports:        []corev1.ContainerPort{{ContainerPort: SentinelPort, Protocol: corev1.ProtocolTCP}},
+							VolumeMounts: mounts,
+						},
+					},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+}
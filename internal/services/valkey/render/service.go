@@ -0,0 +1,61 @@
+package render
+
+import (
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+)
+
+// Service renders headless service which gives every pod stable network identity
+func Service(item *v1alpha1.Valkey) *corev1.Service {
+	res := newService(item, item.Name, SelectorLabels(item.Name), servicePorts(item)...)
+	res.Spec.ClusterIP = corev1.ClusterIPNone
+
+	return res
+}
+
+// ReplicationServices renders read-write service pointing to the primary
+// and read-only service balancing between replicas. Read-write service
+// selects the primary pod by name, so switching primary is a single update.
+// The first pod is used when primary isn't known yet.
+func ReplicationServices(item *v1alpha1.Valkey) (*corev1.Service, *corev1.Service) {
+	primarySelector := SelectorLabels(item.Name)
+	primarySelector[LabelPodName] = lo.CoalesceOrEmpty(item.Status.Primary, PodName(item.Name, 0))
+
+	replicaSelector := SelectorLabels(item.Name)
+	replicaSelector[LabelRole] = RoleReplica
+
+	return newService(item, ReadWriteServiceName(item.Name), primarySelector, servicePorts(item)...),
+		newService(item, ReadOnlyServiceName(item.Name), replicaSelector, servicePorts(item)...)
+}
+
+// portNames are required when service has several ports
+var portNames = map[int32]string{
+	ContainerPort: "valkey",
+	TLSPort:       "tls",
+	SentinelPort:  "sentinel",
+}
+
+func newService(item *v1alpha1.Valkey, name string, selector map[string]string, ports ...int32) *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Service",
+		},
+		ObjectMeta: objectMeta(item, name),
+		Spec: corev1.ServiceSpec{
+			Selector: selector,
+			Ports: lo.Map(ports, func(port int32, _ int) corev1.ServicePort {
+				return corev1.ServicePort{
+					Name:       portNames[port],
+					Port:       port,
+					Protocol:   corev1.ProtocolTCP,
+					TargetPort: intstr.FromInt32(port),
+				}
+			}),
+		},
+	}
+}
@@ -0,0 +1,305 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/utils"
+)
+
+// replicaScript starts the pod as replica of the primary behind read-write
+// service, so restarted replica doesn't serve as standalone primary. When the
+// primary isn't available, e.g. it's this pod restarted without volume, the
+// pod joins running replica instead of coming back as empty primary, then
+// operator promotes the replica with the most data. The first pod starts as
+// primary only when nothing is running, e.g. on bootstrap
+const replicaScript = `SELF=${POD_NAME}.%[1]s
+PRIMARY=%[2]s
+for SVC in %[3]s %[4]s; do
+  HOST=$(valkey-cli -h "$SVC" -p %[5]d %[6]s-a "$VALKEY_PASSWORD" --no-auth-warning --raw CONFIG GET replica-announce-ip 2>/dev/null | tail -n 1 || true)
+  case "$HOST" in
+    "$SELF") ;;
+    *.%[1]s) PRIMARY=$HOST; break ;;
+  esac
+done
+if [ "$PRIMARY" = "$SELF" ]; then
+  exec docker-entrypoint.sh "$@"
+fi
+exec docker-entrypoint.sh "$@" --replicaof "$PRIMARY" %[7]d
+`
+
+// StatefulSet builds StatefulSet where every replica has its own
+// persistent volume claim created from the volume claim template,
+// pods are restarted when hash of the password or static config is changed
+func StatefulSet(item *v1alpha1.Valkey, opts Options) (*appsv1.StatefulSet, error) {
+	var volumeMounts []corev1.VolumeMount
+	var claimTemplates []corev1.PersistentVolumeClaim
+	var initContainers []corev1.Container
+	var volumes []corev1.Volume
+
+	if item.Spec.Volume.Enabled {
+		storage, err := quantity("storage", item.Spec.ClaimStorage())
+		if err != nil {
+			return nil, err
+		}
+
+		volumeMounts = []corev1.VolumeMount{{
+			Name:      dataVolumeName,
+			MountPath: dataMountPath,
+		}}
+		claimTemplates = []corev1.PersistentVolumeClaim{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   dataVolumeName,
+				Labels: SelectorLabels(item.Name),
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{
+					corev1.ReadWriteOnce,
+				},
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: storage,
+					},
+				},
+			},
+		}}
+
+		if opts.Restore != nil {
+			container, volume := restoreSpec(opts.Restore)
+			initContainers = []corev1.Container{container}
+			if volume != nil {
+				volumes = []corev1.Volume{*volume}
+			}
+		}
+	}
+
+	cpu, err := quantity("cpu", item.Spec.Resource.CPU)
+	if err != nil {
+		return nil, err
+	}
+	memory, err := quantity("memory", item.Spec.Resource.Memory)
+	if err != nil {
+		return nil, err
+	}
+
+	resourceList := corev1.ResourceList{
+		corev1.ResourceCPU:    cpu,
+		corev1.ResourceMemory: memory,
+	}
+
+	templateAnnotations := make(map[string]string)
+	if opts.PasswordHash != "" {
+		templateAnnotations[AnnotationPasswordHash] = opts.PasswordHash
+	}
+	// dynamic parameters are applied by CONFIG SET, so only
+	// changes of static ones restart pods
+	if hash := ConfigHash(staticConfig(item.Spec.Config)); hash != "" {
+		templateAnnotations[AnnotationConfigHash] = hash
+	}
+	if len(templateAnnotations) == 0 {
+		templateAnnotations = nil
+	}
+
+	args := []string{"valkey-server"}
+	var ports []corev1.ContainerPort
+	for _, port := range servicePorts(item) {
+		ports = append(ports, corev1.ContainerPort{ContainerPort: port, Protocol: corev1.ProtocolTCP})
+	}
+	switch item.Spec.Mode {
+	case v1alpha1.TypeModeReplication:
+		// replicas announce stable DNS names instead of pod IPs,
+		// so Sentinel reports primary address which survives restarts
+		args = append(args,
+			"--replica-announce-ip", PodHost("$(POD_NAME)", item.Name, item.Namespace),
+		)
+	case v1alpha1.TypeModeCluster:
+		// node id is kept in nodes.conf, so pod with volume
+		// rejoins the cluster as the same node after restart
+		args = append(args,
+			"--cluster-enabled", "yes",
+			"--cluster-config-file", dataMountPath+"/"+clusterConfigFile,
+			"--cluster-node-timeout", clusterNodeTimeout,
+			"--cluster-announce-hostname", PodHost("$(POD_NAME)", item.Name, item.Namespace),
+			"--cluster-preferred-endpoint-type", "hostname",
+		)
+		ports = append(ports, corev1.ContainerPort{ContainerPort: clusterBusPort(item), Protocol: corev1.ProtocolTCP})
+	}
+
+	// password is expanded by kubelet from env, so it isn't stored in the spec,
+	// replicas authenticate to the primary with the same password
+	args = append(args,
+		"--requirepass", "$("+envPassword+")",
+		"--masterauth", "$("+envPassword+")",
+	)
+
+	if item.Spec.TLSEnabled() {
+		args = append(args, tlsArgs(item)...)
+
+		volume, mount := tlsVolume(item)
+		volumes = append(volumes, volume)
+		volumeMounts = append(volumeMounts, mount)
+	}
+
+	// config file is passed even without parameters, so changing them doesn't
+	// change pod template, pods are restarted only by hash of static ones.
+	// Config file should be the first argument of the server
+	args = slices.Insert(args, 1, configMountPath+"/"+configFile)
+
+	volume, mount := configVolume(item)
+	volumes = append(volumes, volume)
+	volumeMounts = append(volumeMounts, mount)
+
+	// server arguments are passed to the script
+	var command []string
+	if item.Spec.Mode == v1alpha1.TypeModeReplication {
+		command = []string{"sh", "-c", replicaCommand(item), containerName}
+	}
+
+	return &appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "StatefulSet",
+		},
+		ObjectMeta: objectMeta(item, item.Name),
+		Spec: appsv1.StatefulSetSpec{
+			// headless service gives every pod stable network identity
+			ServiceName: item.Name,
+			Replicas:    utils.Pointer(item.Spec.PodsCount()),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: SelectorLabels(item.Name),
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      SelectorLabels(item.Name),
+					Annotations: templateAnnotations,
+				},
+				Spec: corev1.PodSpec{
+					InitContainers: initContainers,
+					Containers: []corev1.Container{
+						{
+							Name:    containerName,
+							Image:   item.Spec.Image,
+							Command: command,
+							Args:    args,
+							Env: []corev1.EnvVar{
+								{
+									Name: "POD_NAME",
+									ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "metadata.name",
+										},
+									},
+								},
+								{
+									Name:  "VALKEY_USER",
+									Value: item.Spec.User,
+								},
+								passwordEnv(item),
+							},
+							Ports:        ports,
+							VolumeMounts: volumeMounts,
+							Resources: corev1.ResourceRequirements{
+								Requests: resourceList,
+								Limits:   resourceList,
+							},
+						},
+					},
+					Volumes: volumes,
+				},
+			},
+			VolumeClaimTemplates:                 claimTemplates,
+			PersistentVolumeClaimRetentionPolicy: claimRetentionPolicy(item),
+		},
+	}, nil
+}
+
+// replicaCommand renders replicaScript, the current primary is asked
+// over TLS when plaintext port is closed
+func replicaCommand(item *v1alpha1.Valkey) string {
+	domain := item.Name + "." + item.Namespace + ".svc"
+	port, cliTLS := ContainerPort, ""
+	if item.Spec.TLSEnabled() && item.Spec.TLS.DisablePlaintext {
+		port, cliTLS = TLSPort, "--tls --cacert "+TLSFiles()["tls-ca-cert-file"]+" "
+	}
+	// replicas connect to TLS port like operator does
+	replicationPort := ContainerPort
+	if item.Spec.TLSEnabled() {
+		replicationPort = TLSPort
+	}
+
+	return strings.TrimSpace(fmt.Sprintf(replicaScript,
+		domain,
+		PodHost(PodName(item.Name, 0), item.Name, item.Namespace),
+		ReadWriteServiceName(item.Name)+"."+item.Namespace+".svc",
+		ReadOnlyServiceName(item.Name)+"."+item.Namespace+".svc",
+		port,
+		cliTLS,
+		replicationPort,
+	))
+}
+
+// claimRetentionPolicy makes StatefulSet own claims created from templates,
+// so they are removed with it while claims of scaled down pods are kept.
+// Claims aren't owned when they should be retained after deletion
+func claimRetentionPolicy(item *v1alpha1.Valkey) *appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy {
+	whenDeleted := appsv1.DeletePersistentVolumeClaimRetentionPolicyType
+	if item.Spec.DeletionPolicy == v1alpha1.TypeDeletionPolicyRetain {
+		whenDeleted = appsv1.RetainPersistentVolumeClaimRetentionPolicyType
+	}
+
+	return &appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy{
+		WhenDeleted: whenDeleted,
+		WhenScaled:  appsv1.RetainPersistentVolumeClaimRetentionPolicyType,
+	}
+}
+
+// clusterBusPort is derived by Valkey from the port which cluster nodes
+// connect to, it's TLS port when cluster bus is encrypted
+func clusterBusPort(item *v1alpha1.Valkey) int32 {
+	if item.Spec.TLSEnabled() {
+		return TLSPort + clusterBusPortOffset
+	}
+
+	return ContainerPort + clusterBusPortOffset
+}
+
+func passwordEnv(item *v1alpha1.Valkey) corev1.EnvVar {
+	name, key := PasswordSecret(item.Name, item.Spec.PasswordSecretRef())
+
+	return secretKeyEnv(envPassword, name, key)
+}
+
+func secretKeyEnv(env, name, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: env,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: name,
+				},
+				Key: key,
+			},
+		},
+	}
+}
+
+// ErrInvalidQuantity is returned for resources which can't be parsed,
+// e.g. omitted ones when defaulting webhook isn't run
+var ErrInvalidQuantity = errors.New("invalid quantity")
+
+func quantity(field, value string) (resource.Quantity, error) {
+	res, err := resource.ParseQuantity(value)
+	if err != nil {
+		return res, errors.Wrapf(ErrInvalidQuantity, "invalid %s %q, %s", field, value, err)
+	}
+
+	return res, nil
+}
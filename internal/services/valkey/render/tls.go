@@ -0,0 +1,156 @@
+package render
+
+import (
+	"strconv"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+)
+
+const (
+	// TLSPort accepts TLS connections of clients, replicas and cluster nodes
+	TLSPort = 6380
+	// TLSVolumeName is a name of volume with certificates of the pod
+	TLSVolumeName = "tls"
+
+	SecretKeyTLSCert = "tls.crt"
+	SecretKeyTLSKey  = "tls.key"
+	SecretKeyTLSCA   = "ca.crt"
+
+	tlsMountPath = "/tls"
+)
+
+var certificateGVK = schema.GroupVersionKind{
+	Group:   "cert-manager.io",
+	Version: "v1",
+	Kind:    "Certificate",
+}
+
+// TLSSecret returns name of the Secret with certificates,
+// the Secret named after CRD is issued by cert-manager
+func TLSSecret(crdName string, tls *v1alpha1.TLS) string {
+	if tls.SecretName != "" {
+		return tls.SecretName
+	}
+
+	return crdName + "-tls"
+}
+
+// TLSFiles returns parameters pointing to certificates of the mounted Secret,
+// setting them again by CONFIG SET reloads renewed certificates
+func TLSFiles() map[string]string {
+	return map[string]string{
+		"tls-cert-file":    tlsMountPath + "/" + SecretKeyTLSCert,
+		"tls-key-file":     tlsMountPath + "/" + SecretKeyTLSKey,
+		"tls-ca-cert-file": tlsMountPath + "/" + SecretKeyTLSCA,
+	}
+}
+
+// PodTLSSecret returns name of the Secret mounted to the pod, it's empty
+// when the pod listens only on plaintext port, e.g. it isn't restarted yet
+// after TLS was enabled
+func PodTLSSecret(pod *corev1.Pod) string {
+	volume, ok := lo.Find(pod.Spec.Volumes, func(v corev1.Volume) bool { return v.Name == TLSVolumeName })
+	if !ok || volume.Secret == nil {
+		return ""
+	}
+
+	return volume.Secret.SecretName
+}
+
+// PodPort returns port which replicas, cluster nodes and operator connect to,
+// traffic between pods is encrypted when the pod has certificates
+func PodPort(pod *corev1.Pod) int {
+	if PodTLSSecret(pod) != "" {
+		return TLSPort
+	}
+
+	return ContainerPort
+}
+
+// tlsArgs makes replication and cluster bus use TLS too, clients
+// aren't asked for certificates because they are authenticated by password
+func tlsArgs(item *v1alpha1.Valkey) []string {
+	files := TLSFiles()
+	res := []string{
+		"--tls-port", strconv.Itoa(TLSPort),
+		"--tls-cert-file", files["tls-cert-file"],
+		"--tls-key-file", files["tls-key-file"],
+		"--tls-ca-cert-file", files["tls-ca-cert-file"],
+		"--tls-auth-clients", "no",
+		"--tls-replication", "yes",
+	}
+	if item.Spec.Mode == v1alpha1.TypeModeCluster {
+		res = append(res, "--tls-cluster", "yes")
+	}
+	if item.Spec.TLS.DisablePlaintext {
+		res = append(res, "--port", "0")
+	}
+
+	return res
+}
+
+func tlsVolume(item *v1alpha1.Valkey) (corev1.Volume, corev1.VolumeMount) {
+	volume := corev1.Volume{
+		Name: TLSVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: TLSSecret(item.Name, item.Spec.TLS),
+			},
+		},
+	}
+	mount := corev1.VolumeMount{
+		Name:      TLSVolumeName,
+		MountPath: tlsMountPath,
+		ReadOnly:  true,
+	}
+
+	return volume, mount
+}
+
+// servicePorts returns ports of Valkey, plaintext port is closed
+// when only TLS connections are accepted
+func servicePorts(item *v1alpha1.Valkey) []int32 {
+	if !item.Spec.TLSEnabled() {
+		return []int32{ContainerPort}
+	}
+	if item.Spec.TLS.DisablePlaintext {
+		return []int32{TLSPort}
+	}
+
+	return []int32{ContainerPort, TLSPort}
+}
+
+// Certificate renders cert-manager Certificate which is written to the TLS
+// Secret, it's valid for every pod and service of the instance
+func Certificate(item *v1alpha1.Valkey) *unstructured.Unstructured {
+	crdName, namespace, ref := item.Name, item.Namespace, item.Spec.TLS.IssuerRef
+
+	var dnsNames []string
+	for _, name := range []string{"*." + crdName, crdName, ReadWriteServiceName(crdName), ReadOnlyServiceName(crdName)} {
+		host := name + "." + namespace + ".svc"
+		dnsNames = append(dnsNames, host, host+".cluster.local")
+	}
+
+	meta := objectMeta(item, TLSSecret(crdName, item.Spec.TLS))
+
+	res := new(unstructured.Unstructured)
+	res.SetGroupVersionKind(certificateGVK)
+	res.SetName(meta.Name)
+	res.SetNamespace(meta.Namespace)
+	res.SetLabels(meta.Labels)
+	res.SetOwnerReferences(meta.OwnerReferences)
+	_ = unstructured.SetNestedField(res.Object, meta.Name, "spec", "secretName")
+	_ = unstructured.SetNestedStringSlice(res.Object, dnsNames, "spec", "dnsNames")
+	_ = unstructured.SetNestedStringMap(res.Object, map[string]string{
+		"name":  ref.Name,
+		"kind":  lo.CoalesceOrEmpty(ref.Kind, "Issuer"),
+		"group": lo.CoalesceOrEmpty(ref.Group, certificateGVK.Group),
+	}, "spec", "issuerRef")
+
+	return res
+}
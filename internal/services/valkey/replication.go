@@ -0,0 +1,231 @@
+package valkey
+
+import (
+	"context"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/uagolang/k8s-operator/internal/lib/validator"
+	"github.com/uagolang/k8s-operator/internal/lib/valkeyclient"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
+)
+
+type SyncReplicationRequest struct {
+	CrdName   string `json:"crd_name" validate:"required"`
+	Namespace string `json:"namespace" validate:"required"`
+	// Primary is a name of the pod which is currently known as primary,
+	// the first pod of StatefulSet is used when it's empty
+	Primary string `json:"primary" validate:"omitempty"`
+	// Sentinel means that failover is managed by Sentinel, so the primary
+	// is taken from Sentinel quorum and is never promoted by operator
+	Sentinel bool `json:"sentinel"`
+}
+
+// SyncReplication makes sure that the primary accepts writes and every other
+// ready pod replicates from it, then points read-write service to the primary
+// and marks pods with role label used by read-only service.
+// Returns name of the primary pod.
+func (s *valkeyService) SyncReplication(ctx context.Context, i *SyncReplicationRequest) (string, error) {
+	if err := validator.Validate(ctx, i); err != nil {
+		return "", err
+	}
+
+	logger := log.FromContext(ctx)
+
+	pods, err := s.listPods(ctx, i.CrdName, i.Namespace)
+	if err != nil {
+		return "", err
+	}
+
+	primaryName := i.Primary
+	if i.Sentinel {
+		name, err := s.sentinelPrimary(ctx, i.CrdName, i.Namespace, pods)
+		if err != nil {
+			// quorum could be not deployed yet
+			logger.Info("sentinel is not available", "error", err.Error())
+		}
+		if name != "" {
+			primaryName = name
+		}
+	}
+
+	if _, ok := lo.Find(pods, func(p corev1.Pod) bool { return p.Name == primaryName }); !ok {
+		primaryName = render.PodName(i.CrdName, 0)
+	}
+
+	primary, ok := lo.Find(pods, func(p corev1.Pod) bool { return p.Name == primaryName })
+	if !ok || !isPodReady(&primary) {
+		// replicas keep serving reads until primary comes back
+		return primaryName, nil
+	}
+
+	primaryOpts, err := s.podOptions(ctx, &primary)
+	if err != nil {
+		return "", err
+	}
+	role, err := s.valkeyClient.Role(ctx, primaryOpts)
+	if err != nil {
+		return "", err
+	}
+	if role.Role != valkeyclient.RolePrimary && i.Sentinel {
+		// failover is in progress, Sentinel will promote new primary
+		return primaryName, nil
+	}
+
+	replicas, err := s.podRoles(ctx, pods, primary.Name)
+	if err != nil {
+		return "", err
+	}
+
+	if !i.Sentinel && role.Offset <= 0 {
+		// primary restarted without volume is empty, the replica with the
+		// most data is promoted instead of it, so replicas don't resync to
+		// empty dataset. Replicas have no data on bootstrap, so the first
+		// pod stays primary
+		best := lo.MaxBy(replicas, func(a, b podRole) bool { return a.role.Offset > b.role.Offset })
+		if best.pod != nil && best.role.Offset > 0 {
+			logger.Info("primary has no data, replica is promoted instead", "pod", primary.Name, "replica", best.pod.Name)
+			empty := primary
+			replicas = append(lo.Reject(replicas, func(v podRole, _ int) bool { return v.pod == best.pod }),
+				podRole{pod: &empty, opts: primaryOpts, role: role})
+			primary, primaryOpts, role = *best.pod, best.opts, best.role
+		}
+	}
+
+	if role.Role != valkeyclient.RolePrimary {
+		logger.Info("promoting pod to primary", "pod", primary.Name)
+		if err = s.valkeyClient.ReplicaOfNoOne(ctx, primaryOpts); err != nil {
+			return "", err
+		}
+	}
+
+	// replicas connect to TLS port when the primary has certificates
+	primaryHost, primaryPort := render.PodHost(primary.Name, i.CrdName, i.Namespace), render.PodPort(&primary)
+	for _, replica := range replicas {
+		role := replica.role
+		if role.Role == valkeyclient.RoleReplica && role.PrimaryHost == primaryHost && role.PrimaryPort == primaryPort {
+			continue
+		}
+
+		logger.Info("configuring replica", "pod", replica.pod.Name, "primary", primary.Name)
+		if err = s.valkeyClient.ReplicaOf(ctx, replica.opts, primaryHost, primaryPort); err != nil {
+			return "", err
+		}
+	}
+
+	err = s.setPrimaryService(ctx, i.CrdName, i.Namespace, primary.Name)
+	if err != nil {
+		return "", err
+	}
+
+	for idx := range pods {
+		pod := &pods[idx]
+		role := render.RoleReplica
+		if pod.Name == primary.Name {
+			role = render.RolePrimary
+		}
+
+		if err = s.setPodRole(ctx, pod, role); err != nil {
+			return "", err
+		}
+	}
+
+	return primary.Name, nil
+}
+
+// podRole is a replication role reported by the pod
+type podRole struct {
+	pod  *corev1.Pod
+	opts valkeyclient.Options
+	role *valkeyclient.Role
+}
+
+// podRoles returns roles of ready pods except the primary
+func (s *valkeyService) podRoles(ctx context.Context, pods []corev1.Pod, primary string) ([]podRole, error) {
+	var res []podRole
+	for idx := range pods {
+		pod := &pods[idx]
+		if pod.Name == primary || !isPodReady(pod) {
+			continue
+		}
+
+		opts, err := s.podOptions(ctx, pod)
+		if err != nil {
+			return nil, err
+		}
+		role, err := s.valkeyClient.Role(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, podRole{pod: pod, opts: opts, role: role})
+	}
+
+	return res, nil
+}
+
+func (s *valkeyService) listPods(ctx context.Context, crdName, namespace string) ([]corev1.Pod, error) {
+	res := new(corev1.PodList)
+	err := s.k8sClient.List(ctx, res,
+		client.InNamespace(namespace),
+		client.MatchingLabels(render.SelectorLabels(crdName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Items, nil
+}
+
+func (s *valkeyService) setPrimaryService(ctx context.Context, crdName, namespace, primary string) error {
+	svc, err := s.getService(ctx, types.NamespacedName{
+		Name:      render.ReadWriteServiceName(crdName),
+		Namespace: namespace,
+	})
+	if err != nil {
+		return err
+	}
+	if svc == nil || svc.Spec.Selector[render.LabelPodName] == primary {
+		return nil
+	}
+
+	patch := client.MergeFrom(svc.DeepCopy())
+	svc.Spec.Selector = render.SelectorLabels(crdName)
+	svc.Spec.Selector[render.LabelPodName] = primary
+
+	return s.k8sClient.Patch(ctx, svc, patch)
+}
+
+func (s *valkeyService) setPodRole(ctx context.Context, pod *corev1.Pod, role string) error {
+	if pod.Labels[render.LabelRole] == role {
+		return nil
+	}
+
+	patch := client.MergeFrom(pod.DeepCopy())
+	if pod.Labels == nil {
+		pod.Labels = make(map[string]string)
+	}
+	pod.Labels[render.LabelRole] = role
+
+	return s.k8sClient.Patch(ctx, pod, patch)
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	if pod.Status.PodIP == "" || !pod.DeletionTimestamp.IsZero() {
+		return false
+	}
+
+	_, ok := lo.Find(pod.Status.Conditions, func(c corev1.PodCondition) bool {
+		return c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue
+	})
+
+	return ok
+}
+
+func (s *valkeyService) podOptions(ctx context.Context, pod *corev1.Pod) (valkeyclient.Options, error) {
+	return PodOptions(ctx, s.k8sClient, pod)
+}
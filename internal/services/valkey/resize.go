@@ -0,0 +1,112 @@
+package valkey
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/uagolang/k8s-operator/internal/lib/validator"
+)
+
+type ResizeStorageRequest struct {
+	Name      string `json:"name" validate:"required"`
+	Namespace string `json:"namespace" validate:"required"`
+	Storage   string `json:"storage" validate:"required"`
+}
+
+// ResizeStorage expands volume claims online when storage class allows it,
+// returns true while file systems of claims aren't expanded yet.
+// Claim templates of StatefulSet can't be changed, so claims of new
+// replicas are created with the old size and expanded on the next call
+func (s *valkeyService) ResizeStorage(ctx context.Context, i *ResizeStorageRequest) (bool, error) {
+	if err := validator.Validate(ctx, i); err != nil {
+		return false, err
+	}
+
+	storage, err := resource.ParseQuantity(i.Storage)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid storage %q", i.Storage)
+	}
+
+	claims, err := s.listClaims(ctx, types.NamespacedName{
+		Name:      i.Name,
+		Namespace: i.Namespace,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	// nothing is patched if any claim should be shrunk
+	for _, claim := range claims {
+		requested := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+		if requested.Cmp(storage) > 0 {
+			return false, errors.Wrapf(ErrStorageShrink, "%s requests %s", claim.Name, requested.String())
+		}
+	}
+
+	var resizing bool
+	for idx := range claims {
+		claim := &claims[idx]
+		requested := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+		if requested.Cmp(storage) == 0 {
+			capacity, ok := claim.Status.Capacity[corev1.ResourceStorage]
+			resizing = resizing || (ok && capacity.Cmp(requested) < 0)
+			continue
+		}
+
+		err = s.checkExpansion(ctx, claim)
+		if err != nil {
+			return false, err
+		}
+
+		patch := client.MergeFrom(claim.DeepCopy())
+		if claim.Spec.Resources.Requests == nil {
+			claim.Spec.Resources.Requests = make(corev1.ResourceList)
+		}
+		claim.Spec.Resources.Requests[corev1.ResourceStorage] = storage
+
+		log.FromContext(ctx).Info("expanding claim", "claim", claim.Name, "from", requested.String(), "to", storage.String())
+		err = s.k8sClient.Patch(ctx, claim, patch)
+		if err != nil {
+			return false, err
+		}
+		resizing = true
+	}
+
+	return resizing, nil
+}
+
+// checkExpansion returns ErrExpansionNotSupported if storage class
+// of the claim doesn't allow volume expansion
+func (s *valkeyService) checkExpansion(ctx context.Context, claim *corev1.PersistentVolumeClaim) error {
+	name := ""
+	if claim.Spec.StorageClassName != nil {
+		name = *claim.Spec.StorageClassName
+	}
+	if name == "" {
+		return errors.Wrapf(ErrExpansionNotSupported, "%s has no storage class", claim.Name)
+	}
+
+	class := new(storagev1.StorageClass)
+	err := s.k8sClient.Get(ctx, types.NamespacedName{Name: name}, class)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return errors.Wrapf(ErrExpansionNotSupported, "storage class %s not found", name)
+		}
+
+		return err
+	}
+
+	if class.AllowVolumeExpansion == nil || !*class.AllowVolumeExpansion {
+		return errors.Wrapf(ErrExpansionNotSupported, "storage class %s", name)
+	}
+
+	return nil
+}
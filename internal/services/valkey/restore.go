@@ -0,0 +1,98 @@
+package valkey
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
+)
+
+// restoreSource resolves where RDB file of the new instance is taken from,
+// nil is returned when the instance starts from empty volumes
+func (s *valkeyService) restoreSource(ctx context.Context, item *v1alpha1.Valkey) (*render.Restore, error) {
+	src := item.Spec.RestoreFrom
+	if src == nil {
+		return nil, nil
+	}
+
+	switch {
+	case !item.Spec.Volume.Enabled:
+		return nil, errors.Wrap(ErrRestoreNotSupported, "instance has no persistent volume")
+	case item.Spec.Mode == v1alpha1.TypeModeCluster:
+		// every shard needs its own RDB file
+		return nil, errors.Wrap(ErrRestoreNotSupported, "cluster mode can't be restored")
+	case src.BackupName != "":
+		return s.restoreFromBackup(ctx, item.Namespace, src)
+	default:
+		return s.restoreFromValkey(ctx, item, src)
+	}
+}
+
+func (s *valkeyService) restoreFromBackup(ctx context.Context, namespace string, src *v1alpha1.RestoreSource) (*render.Restore, error) {
+	backup := new(v1alpha1.ValkeyBackup)
+	err := s.k8sClient.Get(ctx, types.NamespacedName{
+		Name:      src.BackupName,
+		Namespace: namespace,
+	}, backup)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, errors.Wrapf(ErrRestoreSourceNotFound, "backup %s/%s", namespace, src.BackupName)
+		}
+
+		return nil, err
+	}
+
+	if backup.Status.Phase != v1alpha1.TypeBackupPhaseCompleted {
+		return nil, errors.Wrapf(ErrRestoreSourceNotReady, "backup %s is %s", backup.Name,
+			lo.CoalesceOrEmpty(backup.Status.Phase, v1alpha1.TypeBackupPhasePending))
+	}
+
+	return &render.Restore{
+		Image:       src.Image,
+		BackupName:  backup.Name,
+		Destination: backup.Spec.Destination.DeepCopy(),
+		Checksum:    backup.Status.Checksum,
+	}, nil
+}
+
+// restoreFromValkey clones the primary, so replica lag doesn't lose writes
+func (s *valkeyService) restoreFromValkey(ctx context.Context, item *v1alpha1.Valkey, src *v1alpha1.RestoreSource) (*render.Restore, error) {
+	source := new(v1alpha1.Valkey)
+	err := s.k8sClient.Get(ctx, types.NamespacedName{
+		Name:      src.ValkeyName,
+		Namespace: item.Namespace,
+	}, source)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, errors.Wrapf(ErrRestoreSourceNotFound, "valkey %s/%s", item.Namespace, src.ValkeyName)
+		}
+
+		return nil, err
+	}
+
+	if source.Spec.Mode == v1alpha1.TypeModeCluster {
+		return nil, errors.Wrap(ErrRestoreNotSupported, "instance in cluster mode can't be cloned")
+	}
+	if source.Spec.TLSEnabled() && source.Spec.TLS.DisablePlaintext {
+		// init container has no certificates of the source
+		return nil, errors.Wrap(ErrRestoreNotSupported, "instance without plaintext port can't be cloned")
+	}
+	if source.Status.Phase != v1alpha1.TypePhaseRunning {
+		return nil, errors.Wrapf(ErrRestoreSourceNotReady, "valkey %s is not running", source.Name)
+	}
+
+	pod := lo.CoalesceOrEmpty(source.Status.Primary, render.PodName(source.Name, 0))
+	name, key := render.PasswordSecret(source.Name, source.Spec.PasswordSecretRef())
+
+	return &render.Restore{
+		// valkey-cli of the instance image downloads RDB file
+		Image:             item.Spec.Image,
+		SourceHost:        render.PodHost(pod, source.Name, source.Namespace),
+		SourcePasswordRef: &v1alpha1.SecretKeyRef{Name: name, Key: key},
+	}, nil
+}
@@ -0,0 +1,76 @@
+package valkey
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/lib/valkeyclient"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
+)
+
+// removeSentinel deletes Sentinel quorum when Sentinel is disabled
+func (s *valkeyService) removeSentinel(ctx context.Context, item *v1alpha1.Valkey) error {
+	res, err := s.getStatefulSet(ctx, types.NamespacedName{
+		Name:      render.SentinelName(item.Name),
+		Namespace: item.Namespace,
+	})
+	if err != nil || res == nil {
+		return err
+	}
+
+	return s.deleteSentinel(ctx, types.NamespacedName{
+		Name:      item.Name,
+		Namespace: item.Namespace,
+	})
+}
+
+func (s *valkeyService) deleteSentinel(ctx context.Context, i types.NamespacedName) error {
+	namespaced := types.NamespacedName{
+		Name:      render.SentinelName(i.Name),
+		Namespace: i.Namespace,
+	}
+
+	err := s.k8sClient.Delete(ctx, &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      namespaced.Name,
+			Namespace: namespaced.Namespace,
+		},
+	})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	return s.deleteService(ctx, namespaced)
+}
+
+// sentinelPrimary asks Sentinel quorum for the current primary
+// and returns name of its pod, empty name means primary is unknown
+func (s *valkeyService) sentinelPrimary(ctx context.Context, crdName, namespace string, pods []corev1.Pod) (string, error) {
+	host, _, err := s.valkeyClient.SentinelPrimary(ctx, valkeyclient.Options{
+		Addr: net.JoinHostPort(render.SentinelName(crdName)+"."+namespace+".svc", strconv.Itoa(render.SentinelPort)),
+	}, crdName)
+	if err != nil {
+		return "", err
+	}
+	if host == "" {
+		return "", nil
+	}
+
+	pod, ok := lo.Find(pods, func(p corev1.Pod) bool {
+		return host == render.PodHost(p.Name, crdName, namespace) || host == p.Status.PodIP
+	})
+	if !ok {
+		return "", nil
+	}
+
+	return pod.Name, nil
+}
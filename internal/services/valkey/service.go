@@ -4,17 +4,27 @@ import (
 	"context"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/lib/valkeyclient"
 )
 
 type Service interface {
 	Create(ctx context.Context, i *CreateRequest) error
-	Update(ctx context.Context, i *UpdateRequest) error
+	Update(ctx context.Context, i *UpdateRequest) ([]string, error)
 	IsReady(ctx context.Context, i *IsReadyRequest) (bool, int32, error)
+	IsStorageReady(ctx context.Context, i *IsReadyRequest) (bool, error)
+	ResizeStorage(ctx context.Context, i *ResizeStorageRequest) (bool, error)
+	SyncReplication(ctx context.Context, i *SyncReplicationRequest) (string, error)
+	SyncCluster(ctx context.Context, i *SyncClusterRequest) ([]v1alpha1.ShardStatus, error)
+	ReloadTLS(ctx context.Context, i *ReloadTLSRequest) (bool, error)
+	ApplyConfig(ctx context.Context, i *ApplyConfigRequest) error
 	Delete(ctx context.Context, i *DeleteRequest) error
 }
 
 type valkeyService struct {
-	k8sClient client.Client
+	k8sClient    client.Client
+	valkeyClient valkeyclient.Client
 }
 
 type Option func(s *valkeyService)
@@ -25,6 +35,12 @@ func WithK8sClient(v client.Client) Option {
 	}
 }
 
+func WithValkeyClient(v valkeyclient.Client) Option {
+	return func(s *valkeyService) {
+		s.valkeyClient = v
+	}
+}
+
 func NewValkeyService(opts ...Option) Service {
 	s := new(valkeyService)
 	for _, opt := range opts {
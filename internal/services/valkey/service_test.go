@@ -2,21 +2,37 @@ package valkey_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
+	"fmt"
+	"math/big"
 	"testing"
+	"time"
 
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/uagolang/k8s-operator/api/v1alpha1"
 	validatorlib "github.com/uagolang/k8s-operator/internal/lib/validator"
+	"github.com/uagolang/k8s-operator/internal/lib/valkeyclient"
 	"github.com/uagolang/k8s-operator/internal/services/valkey"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
 	"github.com/uagolang/k8s-operator/internal/utils"
 	"github.com/uagolang/k8s-operator/mocks"
 )
@@ -37,7 +53,7 @@ func TestService(t *testing.T) {
 		Image:     "nesymno/k8s-operator:latest",
 		User:      "user",
 		Password:  "password",
-		Replicas:  1,
+		Replicas:  utils.Pointer(int32(1)),
 		Volume: v1alpha1.Volume{
 			Enabled: true,
 			Storage: storage,
@@ -47,6 +63,14 @@ func TestService(t *testing.T) {
 			Memory:  "200Mi",
 			Storage: storage,
 		},
+		Owner: &metav1.OwnerReference{
+			APIVersion:         v1alpha1.GroupVersion.String(),
+			Kind:               "Valkey",
+			Name:               "valkey",
+			UID:                "valkey-uid",
+			Controller:         utils.Pointer(true),
+			BlockOwnerDeletion: utils.Pointer(true),
+		},
 	}
 
 	isReadyRequest := &valkey.IsReadyRequest{
@@ -57,6 +81,12 @@ func TestService(t *testing.T) {
 	updateRequest := &valkey.UpdateRequest{
 		CrdName:   createRequest.CrdName,
 		Namespace: createRequest.Namespace,
+		Image:     &createRequest.Image,
+		User:      &createRequest.User,
+		Replicas:  createRequest.Replicas,
+		Volume:    &createRequest.Volume,
+		Resource:  &createRequest.Resource,
+		Owner:     createRequest.Owner,
 	}
 
 	deleteRequest := &valkey.DeleteRequest{
@@ -64,19 +94,374 @@ func TestService(t *testing.T) {
 		Namespace: createRequest.Namespace,
 	}
 
+	// applyObjects expects objects which don't exist yet to be applied
+	// and returns them by kind and name
+	applyObjects := func(t *testing.T, times int) map[string]runtimeclient.Object {
+		res := make(map[string]runtimeclient.Object)
+		k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(k8serrors.NewNotFound(schema.GroupResource{}, "")).Times(times)
+		k8sClient.EXPECT().Patch(gomock.Any(), gomock.Any(), runtimeclient.Apply, gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.Object, _ runtimeclient.Patch, opts ...runtimeclient.PatchOption) error {
+				require.Contains(t, opts, runtimeclient.FieldOwner("valkey-operator"))
+				require.NotEmpty(t, obj.GetAnnotations()["database.kuberly.io/desired-hash"])
+				res[obj.GetObjectKind().GroupVersionKind().Kind+"/"+obj.GetName()] = obj
+				return nil
+			}).Times(times)
+
+		return res
+	}
+
+	// listClaims expects volume claims of the instance to be listed
+	listClaims := func(claims ...v1.PersistentVolumeClaim) {
+		k8sClient.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&v1.PersistentVolumeClaimList{}), gomock.Any()).DoAndReturn(
+			func(_ context.Context, list runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+				list.(*v1.PersistentVolumeClaimList).Items = claims
+				return nil
+			})
+	}
+
 	t.Run("create", func(t *testing.T) {
 
 		t.Run("success", func(t *testing.T) {
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).Return(nil)
+			applied := applyObjects(t, 4)
+			listClaims()
+
+			err := s.Create(ctx, createRequest)
+			require.NoError(t, err)
+			require.Contains(t, applied, "Secret/valkey")
+
+			sts := applied["StatefulSet/valkey"].(*appsv1.StatefulSet)
+			require.Equal(t, createRequest.CrdName, sts.Spec.ServiceName)
+			require.Len(t, sts.Spec.VolumeClaimTemplates, 1)
+			require.Equal(t, map[string]string{"app": createRequest.CrdName},
+				sts.Spec.VolumeClaimTemplates[0].Labels)
+			require.Equal(t, []metav1.OwnerReference{*createRequest.Owner}, sts.OwnerReferences)
+			require.Equal(t, appsv1.DeletePersistentVolumeClaimRetentionPolicyType,
+				sts.Spec.PersistentVolumeClaimRetentionPolicy.WhenDeleted)
+
+			svc := applied["Service/valkey"].(*v1.Service)
+			require.Equal(t, map[string]string{"app": createRequest.CrdName}, svc.Spec.Selector)
+			require.Equal(t, []metav1.OwnerReference{*createRequest.Owner}, svc.OwnerReferences)
+		})
+
+		t.Run("success with generated password", func(t *testing.T) {
+			req := *createRequest
+			req.Password = ""
+
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).DoAndReturn(
+				func(_ context.Context, obj runtimeclient.Object, _ ...runtimeclient.CreateOption) error {
+					require.NotEmpty(t, obj.(*v1.Secret).Data["password"])
+					require.Equal(t, []metav1.OwnerReference{*createRequest.Owner}, obj.GetOwnerReferences())
+					return nil
+				})
+			applied := applyObjects(t, 4)
+			listClaims()
+
+			err := s.Create(ctx, &req)
+			require.NoError(t, err)
+
+			sts := applied["StatefulSet/valkey"].(*appsv1.StatefulSet)
+			require.NotEmpty(t, sts.Spec.Template.Annotations["database.kuberly.io/password-hash"])
+		})
+
+		t.Run("success with password secret ref", func(t *testing.T) {
+			req := *createRequest
+			req.Password = ""
+			req.PasswordSecretRef = &v1alpha1.SecretKeyRef{Name: "valkey-auth", Key: "pass"}
+
+			k8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{
+				Name:      "valkey-auth",
+				Namespace: req.Namespace,
+			}, gomock.AssignableToTypeOf(&v1.Secret{})).DoAndReturn(
+				func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+					obj.(*v1.Secret).Data = map[string][]byte{"pass": []byte("secret")}
+					return nil
+				})
+			applied := applyObjects(t, 3)
+			listClaims()
+
+			err := s.Create(ctx, &req)
+			require.NoError(t, err)
+
+			sts := applied["StatefulSet/valkey"].(*appsv1.StatefulSet)
+			env, ok := lo.Find(sts.Spec.Template.Spec.Containers[0].Env, func(e v1.EnvVar) bool {
+				return e.Name == "VALKEY_PASSWORD"
+			})
+			require.True(t, ok)
+			require.Equal(t, "valkey-auth", env.ValueFrom.SecretKeyRef.Name)
+			require.Equal(t, "pass", env.ValueFrom.SecretKeyRef.Key)
+			require.NotEmpty(t, sts.Spec.Template.Annotations["database.kuberly.io/password-hash"])
+		})
+
+		t.Run("adopts retained claims", func(t *testing.T) {
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).Return(nil)
+			applyObjects(t, 4)
+			listClaims(
+				v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+					Name:   "data-valkey-0",
+					Labels: map[string]string{"app": "valkey", v1alpha1.LabelRetainedFrom: "valkey"},
+				}},
+				v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+					Name:   "data-valkey-1",
+					Labels: map[string]string{"app": "valkey"},
+				}},
+			)
+			// only retained claim is released
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.PersistentVolumeClaim{}), gomock.Any()).DoAndReturn(
+				func(_ context.Context, obj runtimeclient.Object, _ runtimeclient.Patch, _ ...runtimeclient.PatchOption) error {
+					require.Equal(t, "data-valkey-0", obj.GetName())
+					require.NotContains(t, obj.GetLabels(), v1alpha1.LabelRetainedFrom)
+					return nil
+				})
+
+			err := s.Create(ctx, createRequest)
+			require.NoError(t, err)
+		})
+
+		getBackup := func(phase v1alpha1.TypeBackupPhase) {
+			k8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{
+				Name:      "nightly",
+				Namespace: createRequest.Namespace,
+			}, gomock.AssignableToTypeOf(&v1alpha1.ValkeyBackup{})).DoAndReturn(
+				func(_ context.Context, key types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+					backup := obj.(*v1alpha1.ValkeyBackup)
+					backup.Name = key.Name
+					backup.Spec.Destination = v1alpha1.BackupDestination{
+						PVC: &v1alpha1.PVCDestination{ClaimName: "backups"},
+					}
+					backup.Status = v1alpha1.ValkeyBackupStatus{Phase: phase, Checksum: "abc"}
+					return nil
+				})
+		}
+
+		t.Run("issues certificate", func(t *testing.T) {
+			req := *createRequest
+			req.TLS = &v1alpha1.TLS{Enabled: true, IssuerRef: &v1alpha1.IssuerRef{Name: "ca"}}
+
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).Return(nil)
+			applied := applyObjects(t, 5)
+			listClaims()
+
+			err := s.Create(ctx, &req)
+			require.NoError(t, err)
+			require.Contains(t, applied, "Certificate/valkey-tls")
+
+			spec := applied["StatefulSet/valkey"].(*appsv1.StatefulSet).Spec.Template.Spec
+			require.Equal(t, "valkey-tls", spec.Volumes[0].Secret.SecretName)
+		})
+
+		t.Run("renders config", func(t *testing.T) {
+			req := *createRequest
+			req.Config = map[string]string{"maxmemory-policy": "allkeys-lru", "databases": "4"}
+
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).Return(nil)
+			applied := applyObjects(t, 4)
+			listClaims()
+
+			err := s.Create(ctx, &req)
+			require.NoError(t, err)
+			require.Equal(t, "databases 4\nmaxmemory-policy allkeys-lru\n",
+				applied["ConfigMap/valkey-config"].(*v1.ConfigMap).Data["valkey.conf"])
+
+			template := applied["StatefulSet/valkey"].(*appsv1.StatefulSet).Spec.Template
+			require.Equal(t, render.ConfigHash(map[string]string{"databases": "4"}),
+				template.Annotations[render.AnnotationConfigHash])
+		})
+
+		t.Run("invalid config", func(t *testing.T) {
+			req := *createRequest
+			req.Config = map[string]string{"port": "6390"}
+
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).Return(nil)
+
+			err := s.Create(ctx, &req)
+			require.ErrorIs(t, err, render.ErrInvalidConfig)
+		})
+
+		t.Run("restores from backup", func(t *testing.T) {
+			req := *createRequest
+			req.RestoreFrom = &v1alpha1.RestoreSource{BackupName: "nightly", Image: "curlimages/curl:8.10.1"}
+
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).Return(nil)
+			getBackup(v1alpha1.TypeBackupPhaseCompleted)
+			applied := applyObjects(t, 4)
+			listClaims()
+
+			err := s.Create(ctx, &req)
+			require.NoError(t, err)
+
+			spec := applied["StatefulSet/valkey"].(*appsv1.StatefulSet).Spec.Template.Spec
+			require.Len(t, spec.InitContainers, 1)
+			require.Equal(t, "curlimages/curl:8.10.1", spec.InitContainers[0].Image)
+			require.Equal(t, []v1.EnvVar{
+				{Name: "RESTORE_FILE", Value: "/restore/nightly.rdb"},
+				{Name: "RESTORE_CHECKSUM", Value: "abc"},
+			}, spec.InitContainers[0].Env)
+			require.Equal(t, "backups", spec.Volumes[0].PersistentVolumeClaim.ClaimName)
+		})
+
+		t.Run("clones instance", func(t *testing.T) {
+			req := *createRequest
+			req.RestoreFrom = &v1alpha1.RestoreSource{ValkeyName: "prod"}
+
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).Return(nil)
+			k8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{
+				Name:      "prod",
+				Namespace: createRequest.Namespace,
+			}, gomock.AssignableToTypeOf(&v1alpha1.Valkey{})).DoAndReturn(
+				func(_ context.Context, key types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+					source := obj.(*v1alpha1.Valkey)
+					source.Name, source.Namespace = key.Name, key.Namespace
+					source.Spec.Mode = v1alpha1.TypeModeReplication
+					source.Status = v1alpha1.ValkeyStatus{Phase: v1alpha1.TypePhaseRunning, Primary: "prod-2"}
+					return nil
+				})
+			applied := applyObjects(t, 4)
+			listClaims()
+
+			err := s.Create(ctx, &req)
+			require.NoError(t, err)
+
+			spec := applied["StatefulSet/valkey"].(*appsv1.StatefulSet).Spec.Template.Spec
+			require.Equal(t, createRequest.Image, spec.InitContainers[0].Image)
+			require.Equal(t, "SOURCE_HOST", spec.InitContainers[0].Env[0].Name)
+			require.Equal(t, "prod-2.prod.default.svc", spec.InitContainers[0].Env[0].Value)
+			// password of the source is taken from its Secret
+			require.Equal(t, "SOURCE_PASSWORD", spec.InitContainers[0].Env[1].Name)
+			require.Equal(t, "prod", spec.InitContainers[0].Env[1].ValueFrom.SecretKeyRef.Name)
+			// only config is mounted, data is copied over network
+			require.Len(t, spec.Volumes, 1)
+			require.NotNil(t, spec.Volumes[0].ConfigMap)
+		})
+
+		t.Run("restore source isn't ready", func(t *testing.T) {
+			req := *createRequest
+			req.RestoreFrom = &v1alpha1.RestoreSource{BackupName: "nightly"}
+
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).Return(nil)
+			getBackup(v1alpha1.TypeBackupPhaseCopying)
+
+			err := s.Create(ctx, &req)
+			require.ErrorIs(t, err, valkey.ErrRestoreSourceNotReady)
+		})
+
+		t.Run("restore source not found", func(t *testing.T) {
+			req := *createRequest
+			req.RestoreFrom = &v1alpha1.RestoreSource{ValkeyName: "prod"}
+
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).Return(nil)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&v1alpha1.Valkey{})).
+				Return(k8serrors.NewNotFound(schema.GroupResource{Group: "database.kuberly.io", Resource: "valkeys"}, "prod"))
+
+			err := s.Create(ctx, &req)
+			require.ErrorIs(t, err, valkey.ErrRestoreSourceNotFound)
+		})
+
+		t.Run("clone of instance without plaintext port", func(t *testing.T) {
+			req := *createRequest
+			req.RestoreFrom = &v1alpha1.RestoreSource{ValkeyName: "prod"}
+
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).Return(nil)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&v1alpha1.Valkey{})).DoAndReturn(
+				func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+					source := obj.(*v1alpha1.Valkey)
+					source.Spec.TLS = &v1alpha1.TLS{Enabled: true, SecretName: "prod-certs", DisablePlaintext: true}
+					source.Status.Phase = v1alpha1.TypePhaseRunning
+					return nil
+				})
+
+			err := s.Create(ctx, &req)
+			require.ErrorIs(t, err, valkey.ErrRestoreNotSupported)
+		})
+
+		t.Run("restore in cluster mode", func(t *testing.T) {
+			req := *createRequest
+			req.Mode = v1alpha1.TypeModeCluster
+			req.Cluster = &v1alpha1.Cluster{Shards: 3}
+			req.RestoreFrom = &v1alpha1.RestoreSource{BackupName: "nightly"}
+
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).Return(nil)
+
+			err := s.Create(ctx, &req)
+			require.ErrorIs(t, err, valkey.ErrRestoreNotSupported)
+		})
+
+		t.Run("password secret not found", func(t *testing.T) {
+			req := *createRequest
+			req.PasswordSecretRef = &v1alpha1.SecretKeyRef{Name: "valkey-auth"}
+
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(k8serrors.NewNotFound(schema.GroupResource{
+					Group:    "",
+					Resource: "secrets",
+				}, "valkey-auth"))
+
+			err := s.Create(ctx, &req)
+			require.ErrorIs(t, err, valkey.ErrPasswordSecretNotFound)
+		})
+
+		t.Run("success in replication mode", func(t *testing.T) {
+			req := *createRequest
+			req.Mode = v1alpha1.TypeModeReplication
+
 			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+			applied := applyObjects(t, 6)
+			listClaims()
+
+			err := s.Create(ctx, &req)
+			require.NoError(t, err)
+			require.Equal(t, map[string]string{"app": "valkey", "statefulset.kubernetes.io/pod-name": "valkey-0"},
+				applied["Service/valkey-rw"].(*v1.Service).Spec.Selector)
+			require.Equal(t, map[string]string{"app": "valkey", "database.kuberly.io/role": "replica"},
+				applied["Service/valkey-ro"].(*v1.Service).Spec.Selector)
+		})
+
+		t.Run("success in cluster mode", func(t *testing.T) {
+			req := *createRequest
+			req.Replicas = nil
+			req.Mode = v1alpha1.TypeModeCluster
+			req.Cluster = &v1alpha1.Cluster{Shards: 3, ReplicasPerShard: 1}
+
 			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+			applied := applyObjects(t, 4)
+			listClaims()
+
+			err := s.Create(ctx, &req)
+			require.NoError(t, err)
+
+			sts := applied["StatefulSet/valkey"].(*appsv1.StatefulSet)
+			require.Equal(t, int32(6), *sts.Spec.Replicas)
+			require.Contains(t, sts.Spec.Template.Spec.Containers[0].Args, "--cluster-enabled")
+		})
+
+		t.Run("cluster mode without topology", func(t *testing.T) {
+			req := *createRequest
+			req.Mode = v1alpha1.TypeModeCluster
+
+			err := s.Create(ctx, &req)
+			require.Error(t, err)
+			require.Len(t, validatorlib.GetErrors(err), 1)
+		})
+
+		t.Run("success with sentinel", func(t *testing.T) {
+			req := *createRequest
+			req.Mode = v1alpha1.TypeModeReplication
+			req.Sentinel = &v1alpha1.Sentinel{Enabled: true, Replicas: 3, Quorum: 2}
+
 			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+			// secret, headless, read-write, read-only and sentinel services with two StatefulSets
+			applied := applyObjects(t, 8)
+			listClaims()
 
-			err := s.Create(ctx, createRequest)
+			err := s.Create(ctx, &req)
 			require.NoError(t, err)
+			require.Contains(t, applied, "Service/valkey-sentinel")
+
+			sentinel := applied["StatefulSet/valkey-sentinel"].(*appsv1.StatefulSet)
+			require.Equal(t, int32(3), *sentinel.Spec.Replicas)
+			require.Contains(t, sentinel.Spec.Template.Spec.Containers[0].Command[2],
+				"sentinel monitor valkey ${PRIMARY} 6379 2")
 		})
 
 		t.Run("with validation errors", func(t *testing.T) {
@@ -122,79 +507,39 @@ func TestService(t *testing.T) {
 			require.Error(t, err)
 		})
 
-		t.Run("wait secret failed", func(t *testing.T) {
-			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			notFoundErr := k8serrors.NewNotFound(schema.GroupResource{
-				Group:    "",
-				Resource: "secrets",
-			}, createRequest.CrdName)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(notFoundErr)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
-
-			err := s.Create(ctx, createRequest)
-			require.Error(t, err)
-		})
-
-		t.Run("create deployment failed", func(t *testing.T) {
-			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(mockErr)
-
-			err := s.Create(ctx, createRequest)
-			require.Error(t, err)
-		})
-
-		t.Run("create deployment failed", func(t *testing.T) {
-			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(mockErr)
-
-			err := s.Create(ctx, createRequest)
-			require.Error(t, err)
-		})
-
-		t.Run("wait deployment failed", func(t *testing.T) {
+		t.Run("get statefulset failed", func(t *testing.T) {
 			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			notFoundErr := k8serrors.NewNotFound(schema.GroupResource{
-				Group:    "",
-				Resource: "deployments",
-			}, createRequest.CrdName)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(notFoundErr)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).Return(nil)
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{}), gomock.Any(), gomock.Any()).Return(nil)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&v1.ConfigMap{})).Return(nil)
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.ConfigMap{}), gomock.Any(), gomock.Any()).Return(nil)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&appsv1.StatefulSet{})).Return(mockErr)
 
 			err := s.Create(ctx, createRequest)
 			require.Error(t, err)
 		})
 
-		t.Run("create service failed", func(t *testing.T) {
+		t.Run("apply statefulset failed", func(t *testing.T) {
 			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(mockErr)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).Return(nil)
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{}), gomock.Any(), gomock.Any()).Return(nil)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&v1.ConfigMap{})).Return(nil)
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.ConfigMap{}), gomock.Any(), gomock.Any()).Return(nil)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&appsv1.StatefulSet{})).Return(nil)
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&appsv1.StatefulSet{}), gomock.Any(), gomock.Any()).Return(mockErr)
 
 			err := s.Create(ctx, createRequest)
 			require.Error(t, err)
 		})
 
-		t.Run("wait service failed", func(t *testing.T) {
-			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		t.Run("apply service failed", func(t *testing.T) {
 			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
-			notFoundErr := k8serrors.NewNotFound(schema.GroupResource{
-				Group:    "",
-				Resource: "services",
-			}, createRequest.CrdName)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(notFoundErr)
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).Return(nil)
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{}), gomock.Any(), gomock.Any()).Return(nil)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(3)
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.ConfigMap{}), gomock.Any(), gomock.Any()).Return(nil)
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&appsv1.StatefulSet{}), gomock.Any(), gomock.Any()).Return(nil)
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Service{}), gomock.Any(), gomock.Any()).Return(mockErr)
 
 			err := s.Create(ctx, createRequest)
 			require.Error(t, err)
@@ -204,8 +549,8 @@ func TestService(t *testing.T) {
 	t.Run("is_ready", func(t *testing.T) {
 
 		t.Run("success", func(t *testing.T) {
-			deployment := &appsv1.Deployment{
-				Status: appsv1.DeploymentStatus{
+			sts := &appsv1.StatefulSet{
+				Status: appsv1.StatefulSetStatus{
 					ReadyReplicas: 1,
 				},
 			}
@@ -213,9 +558,9 @@ func TestService(t *testing.T) {
 			k8sClient.EXPECT().Get(ctx, types.NamespacedName{
 				Name:      createRequest.CrdName,
 				Namespace: createRequest.Namespace,
-			}, gomock.AssignableToTypeOf(deployment)).DoAndReturn(
+			}, gomock.AssignableToTypeOf(sts)).DoAndReturn(
 				func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
-					*obj.(*appsv1.Deployment) = *(deployment)
+					*obj.(*appsv1.StatefulSet) = *(sts)
 					return nil
 				})
 		})
@@ -256,8 +601,8 @@ func TestService(t *testing.T) {
 	})
 
 	t.Run("get crd failed", func(t *testing.T) {
-		deployment := &appsv1.Deployment{
-			Status: appsv1.DeploymentStatus{
+		sts := &appsv1.StatefulSet{
+			Status: appsv1.StatefulSetStatus{
 				ReadyReplicas: 0,
 			},
 		}
@@ -265,9 +610,9 @@ func TestService(t *testing.T) {
 		k8sClient.EXPECT().Get(ctx, types.NamespacedName{
 			Name:      createRequest.CrdName,
 			Namespace: createRequest.Namespace,
-		}, gomock.AssignableToTypeOf(deployment)).DoAndReturn(
+		}, gomock.AssignableToTypeOf(sts)).DoAndReturn(
 			func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
-				*obj.(*appsv1.Deployment) = *(deployment)
+				*obj.(*appsv1.StatefulSet) = *(sts)
 				return nil
 			})
 		k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
@@ -283,246 +628,480 @@ func TestService(t *testing.T) {
 		require.Equal(t, int32(0), readyReplicas)
 	})
 
-	t.Run("update", func(t *testing.T) {
-		secret := &v1.Secret{}
-		deployment := &appsv1.Deployment{
-			Spec: appsv1.DeploymentSpec{
-				Template: v1.PodTemplateSpec{
-					Spec: v1.PodSpec{
-						Containers: []v1.Container{
-							{
-								Name:  "valkey",
-								Image: "myimage",
-							},
-						},
-					},
-				},
-				Replicas: utils.Pointer(int32(1)),
-			},
-			Status: appsv1.DeploymentStatus{
-				ReadyReplicas: 2,
-			},
+	t.Run("is_storage_ready", func(t *testing.T) {
+		listClaims := func(phases ...v1.PersistentVolumeClaimPhase) {
+			k8sClient.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&v1.PersistentVolumeClaimList{}), gomock.Any()).DoAndReturn(
+				func(_ context.Context, obj runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+					for _, phase := range phases {
+						obj.(*v1.PersistentVolumeClaimList).Items = append(obj.(*v1.PersistentVolumeClaimList).Items,
+							v1.PersistentVolumeClaim{Status: v1.PersistentVolumeClaimStatus{Phase: phase}})
+					}
+					return nil
+				})
 		}
-		service := &v1.Service{
-			Status: v1.ServiceStatus{},
+
+		t.Run("claims are bound", func(t *testing.T) {
+			listClaims(v1.ClaimBound, v1.ClaimBound)
+
+			ready, err := s.IsStorageReady(ctx, isReadyRequest)
+			require.NoError(t, err)
+			require.True(t, ready)
+		})
+
+		t.Run("claim is pending", func(t *testing.T) {
+			listClaims(v1.ClaimBound, v1.ClaimPending)
+
+			ready, err := s.IsStorageReady(ctx, isReadyRequest)
+			require.NoError(t, err)
+			require.False(t, ready)
+		})
+
+		t.Run("no claims", func(t *testing.T) {
+			listClaims()
+
+			ready, err := s.IsStorageReady(ctx, isReadyRequest)
+			require.NoError(t, err)
+			require.False(t, ready)
+		})
+
+		t.Run("list claims failed", func(t *testing.T) {
+			k8sClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+
+			_, err := s.IsStorageReady(ctx, isReadyRequest)
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("resize_storage", func(t *testing.T) {
+		resizeRequest := &valkey.ResizeStorageRequest{
+			Name:      createRequest.CrdName,
+			Namespace: createRequest.Namespace,
+			Storage:   "2Gi",
 		}
 
-		t.Run("success", func(t *testing.T) {
-			k8sClient.EXPECT().Get(ctx, types.NamespacedName{
-				Name:      createRequest.CrdName,
-				Namespace: createRequest.Namespace,
-			}, gomock.AssignableToTypeOf(secret)).DoAndReturn(
-				func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
-					*obj.(*v1.Secret) = *(secret)
-					return nil
-				})
-			k8sClient.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		claim := func(requested, capacity string) v1.PersistentVolumeClaim {
+			return v1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "data-valkey-0"},
+				Spec: v1.PersistentVolumeClaimSpec{
+					StorageClassName: utils.Pointer("standard"),
+					Resources: v1.VolumeResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse(requested)},
+					},
+				},
+				Status: v1.PersistentVolumeClaimStatus{
+					Capacity: v1.ResourceList{v1.ResourceStorage: resource.MustParse(capacity)},
+				},
+			}
+		}
 
-			k8sClient.EXPECT().Get(ctx, types.NamespacedName{
-				Name:      createRequest.CrdName,
-				Namespace: createRequest.Namespace,
-			}, gomock.AssignableToTypeOf(deployment)).DoAndReturn(
+		getStorageClass := func(allowExpansion *bool) {
+			k8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "standard"}, gomock.AssignableToTypeOf(&storagev1.StorageClass{})).DoAndReturn(
 				func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
-					*obj.(*appsv1.Deployment) = *(deployment)
+					obj.(*storagev1.StorageClass).AllowVolumeExpansion = allowExpansion
 					return nil
 				})
-			k8sClient.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		}
 
-			k8sClient.EXPECT().Get(ctx, types.NamespacedName{
-				Name:      createRequest.CrdName,
-				Namespace: createRequest.Namespace,
-			}, gomock.AssignableToTypeOf(service)).DoAndReturn(
-				func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
-					*obj.(*v1.Service) = *(service)
+		t.Run("expands claims", func(t *testing.T) {
+			listClaims(claim("1Gi", "1Gi"))
+			getStorageClass(utils.Pointer(true))
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.PersistentVolumeClaim{}), gomock.Any()).DoAndReturn(
+				func(_ context.Context, obj runtimeclient.Object, _ runtimeclient.Patch, _ ...runtimeclient.PatchOption) error {
+					requested := obj.(*v1.PersistentVolumeClaim).Spec.Resources.Requests[v1.ResourceStorage]
+					require.Equal(t, "2Gi", requested.String())
 					return nil
 				})
-			k8sClient.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 
-			err := s.Update(ctx, &valkey.UpdateRequest{
-				CrdName:   createRequest.CrdName,
-				Namespace: createRequest.Namespace,
-				Image:     &createRequest.Image,
-				Password:  &createRequest.Password,
-				Replicas:  utils.Pointer(int32(2)),
-				Resource: &v1alpha1.Resource{
-					CPU:     "50m",
-					Memory:  "64Mi",
-					Storage: "0",
-				},
-			})
+			resizing, err := s.ResizeStorage(ctx, resizeRequest)
 			require.NoError(t, err)
+			require.True(t, resizing)
 		})
 
-		t.Run("with validation errors", func(t *testing.T) {
-			req := *updateRequest
-			req.CrdName = ""
-			req.Namespace = ""
+		t.Run("file system is expanded", func(t *testing.T) {
+			listClaims(claim("2Gi", "1Gi"))
 
-			err := s.Update(ctx, &req)
-			require.Error(t, err)
+			resizing, err := s.ResizeStorage(ctx, resizeRequest)
+			require.NoError(t, err)
+			require.True(t, resizing)
+		})
 
-			// get validator errors
-			errs := validatorlib.GetErrors(err)
-			if len(errs) == 0 {
-				require.Errorf(t, err, "expected validation errors")
-			}
+		t.Run("claims have requested size", func(t *testing.T) {
+			listClaims(claim("2Gi", "2Gi"))
 
-			require.Len(t, errs, 2)
-			require.Equal(t, validatorlib.Errors{
-				{
-					Field:   "crd_name",
-					Message: "crd_name is a required field",
-				},
-				{
-					Field:   "namespace",
-					Message: "namespace is a required field",
-				},
-			}, errs)
+			resizing, err := s.ResizeStorage(ctx, resizeRequest)
+			require.NoError(t, err)
+			require.False(t, resizing)
 		})
 
-		t.Run("update secret failed", func(t *testing.T) {
-			req := *updateRequest
-			req.Password = utils.Pointer("password2")
-			req.Image = utils.Pointer("myimage")
-			req.Replicas = utils.Pointer(int32(3))
+		t.Run("shrink is rejected", func(t *testing.T) {
+			listClaims(claim("2Gi", "2Gi"), claim("3Gi", "3Gi"))
+
+			_, err := s.ResizeStorage(ctx, resizeRequest)
+			require.ErrorIs(t, err, valkey.ErrStorageShrink)
+		})
 
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+		t.Run("storage class doesn't allow expansion", func(t *testing.T) {
+			listClaims(claim("1Gi", "1Gi"))
+			getStorageClass(nil)
 
-			err := s.Update(ctx, &req)
-			require.Error(t, err)
+			_, err := s.ResizeStorage(ctx, resizeRequest)
+			require.ErrorIs(t, err, valkey.ErrExpansionNotSupported)
 		})
 
-		t.Run("update deployment failed", func(t *testing.T) {
-			req := *updateRequest
-			// do not update Password to skip update secret
-			req.Image = utils.Pointer("myimage")
-			req.Replicas = utils.Pointer(int32(2))
+		t.Run("claim without storage class", func(t *testing.T) {
+			c := claim("1Gi", "1Gi")
+			c.Spec.StorageClassName = nil
+			listClaims(c)
 
-			k8sClient.EXPECT().Get(ctx, types.NamespacedName{
+			_, err := s.ResizeStorage(ctx, resizeRequest)
+			require.ErrorIs(t, err, valkey.ErrExpansionNotSupported)
+		})
+
+		t.Run("with validation errors", func(t *testing.T) {
+			_, err := s.ResizeStorage(ctx, &valkey.ResizeStorageRequest{})
+			require.Len(t, validatorlib.GetErrors(err), 3)
+		})
+
+		t.Run("list claims failed", func(t *testing.T) {
+			k8sClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+
+			_, err := s.ResizeStorage(ctx, resizeRequest)
+			require.ErrorIs(t, err, mockErr)
+		})
+
+		t.Run("patch claim failed", func(t *testing.T) {
+			listClaims(claim("1Gi", "1Gi"))
+			getStorageClass(utils.Pointer(true))
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+
+			_, err := s.ResizeStorage(ctx, resizeRequest)
+			require.ErrorIs(t, err, mockErr)
+		})
+	})
+
+	t.Run("update", func(t *testing.T) {
+		secret := &v1.Secret{}
+		sts := &appsv1.StatefulSet{
+			Spec: appsv1.StatefulSetSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{
+							{
+								Name:  "valkey",
+								Image: "myimage",
+							},
+						},
+					},
+				},
+				Replicas: utils.Pointer(int32(1)),
+			},
+			Status: appsv1.StatefulSetStatus{
+				ReadyReplicas: 2,
+			},
+		}
+		// secret generated by operator is kept when password is empty
+		secretNotFound := k8serrors.NewNotFound(schema.GroupResource{
+			Group:    "",
+			Resource: "secrets",
+		}, updateRequest.CrdName)
+		statefulSetNotFound := k8serrors.NewNotFound(schema.GroupResource{
+			Group:    "apps",
+			Resource: "statefulsets",
+		}, updateRequest.CrdName)
+
+		noLegacyDeployment := func() {
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&appsv1.Deployment{})).
+				Return(k8serrors.NewNotFound(schema.GroupResource{
+					Group:    "apps",
+					Resource: "deployments",
+				}, updateRequest.CrdName))
+		}
+
+		// getLegacyDeployment returns Deployment of previous operator versions,
+		// claim is mounted when its name isn't empty
+		getLegacyDeployment := func(replicas int32, claim string) {
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&appsv1.Deployment{})).DoAndReturn(
+				func(_ context.Context, key types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+					dep := obj.(*appsv1.Deployment)
+					dep.Name, dep.Namespace = key.Name, key.Namespace
+					dep.Spec.Replicas = utils.Pointer(replicas)
+					dep.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": key.Name}}
+					if claim != "" {
+						dep.Spec.Template.Spec.Volumes = []v1.Volume{{
+							Name: "data",
+							VolumeSource: v1.VolumeSource{
+								PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+							},
+						}}
+					}
+					return nil
+				})
+		}
+
+		listLegacyPods := func(pods ...v1.Pod) {
+			k8sClient.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&v1.PodList{}), gomock.Any()).DoAndReturn(
+				func(_ context.Context, obj runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+					obj.(*v1.PodList).Items = pods
+					return nil
+				})
+		}
+
+		noSentinel := func() {
+			k8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{
+				Name:      "valkey-sentinel",
+				Namespace: createRequest.Namespace,
+			}, gomock.Any()).Return(k8serrors.NewNotFound(schema.GroupResource{
+				Group:    "apps",
+				Resource: "statefulsets",
+			}, "valkey-sentinel"))
+		}
+
+		applyConfigMap := func() {
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&v1.ConfigMap{})).Return(nil)
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.ConfigMap{}), gomock.Any(), gomock.Any()).Return(nil)
+		}
+
+		getStatefulSet := func(live *appsv1.StatefulSet) {
+			k8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{
 				Name:      createRequest.CrdName,
 				Namespace: createRequest.Namespace,
-			}, gomock.AssignableToTypeOf(deployment)).DoAndReturn(
+			}, gomock.AssignableToTypeOf(sts)).DoAndReturn(
 				func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
-					*obj.(*appsv1.Deployment) = *(deployment)
+					*obj.(*appsv1.StatefulSet) = *(live.DeepCopy())
 					return nil
 				})
-			k8sClient.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
-
-			err := s.Update(ctx, &req)
-			require.Error(t, err)
-		})
+		}
 
-		t.Run("update service failed", func(t *testing.T) {
+		t.Run("success", func(t *testing.T) {
 			req := *updateRequest
-			// do not update Password to skip update secret
-			req.Image = utils.Pointer("myimage")
+			req.Password = &createRequest.Password
 			req.Replicas = utils.Pointer(int32(2))
+			req.Resource = &v1alpha1.Resource{
+				CPU:     "50m",
+				Memory:  "64Mi",
+				Storage: "0",
+			}
 
 			k8sClient.EXPECT().Get(ctx, types.NamespacedName{
 				Name:      createRequest.CrdName,
 				Namespace: createRequest.Namespace,
-			}, gomock.AssignableToTypeOf(deployment)).DoAndReturn(
+			}, gomock.AssignableToTypeOf(secret)).DoAndReturn(
 				func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
-					*obj.(*appsv1.Deployment) = *(deployment)
+					*obj.(*v1.Secret) = *(secret)
 					return nil
 				})
-			k8sClient.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+			noLegacyDeployment()
+			getStatefulSet(sts)
+			noSentinel()
+			applied := applyObjects(t, 4)
 
-			k8sClient.EXPECT().Get(ctx, types.NamespacedName{
-				Name:      createRequest.CrdName,
-				Namespace: createRequest.Namespace,
-			}, gomock.AssignableToTypeOf(service)).DoAndReturn(
+			drifted, err := s.Update(ctx, &req)
+			require.NoError(t, err)
+			require.Empty(t, drifted)
+
+			sec := applied["Secret/valkey"].(*v1.Secret)
+			require.Equal(t, []byte("password"), sec.Data["password"])
+			require.Equal(t, []metav1.OwnerReference{*createRequest.Owner}, sec.OwnerReferences)
+
+			updated := applied["StatefulSet/valkey"].(*appsv1.StatefulSet)
+			require.Equal(t, int32(2), *updated.Spec.Replicas)
+			require.Equal(t, createRequest.Image, updated.Spec.Template.Spec.Containers[0].Image)
+			require.True(t, resource.MustParse("50m").Equal(
+				updated.Spec.Template.Spec.Containers[0].Resources.Limits[v1.ResourceCPU]))
+			require.NotEmpty(t, updated.Spec.Template.Annotations["database.kuberly.io/password-hash"])
+			require.Equal(t, []metav1.OwnerReference{*createRequest.Owner}, updated.OwnerReferences)
+
+			require.Contains(t, applied, "Service/valkey")
+		})
+
+		t.Run("keeps generated password", func(t *testing.T) {
+			req := *updateRequest
+
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).DoAndReturn(
 				func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
-					*obj.(*v1.Service) = *(service)
+					obj.(*v1.Secret).Data = map[string][]byte{"password": []byte("generated")}
 					return nil
 				})
-			k8sClient.EXPECT().Update(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+			noLegacyDeployment()
+			getStatefulSet(sts)
+			noSentinel()
+			applied := applyObjects(t, 4)
 
-			err := s.Update(ctx, &req)
-			require.Error(t, err)
+			_, err := s.Update(ctx, &req)
+			require.NoError(t, err)
+			require.Equal(t, []byte("generated"), applied["Secret/valkey"].(*v1.Secret).Data["password"])
 		})
 
-		t.Run("not found resources", func(t *testing.T) {
+		t.Run("rotated password secret restarts pods", func(t *testing.T) {
 			req := *updateRequest
-			req.Password = utils.Pointer("password2")
+			req.PasswordSecretRef = &v1alpha1.SecretKeyRef{Name: "valkey-auth"}
 
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
-				Return(k8serrors.NewNotFound(schema.GroupResource{
-					Group:    "",
-					Resource: "secrets",
-				}, updateRequest.CrdName))
+			k8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{
+				Name:      "valkey-auth",
+				Namespace: req.Namespace,
+			}, gomock.AssignableToTypeOf(secret)).DoAndReturn(
+				func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+					obj.(*v1.Secret).Data = map[string][]byte{"password": []byte("rotated")}
+					return nil
+				})
+			noLegacyDeployment()
+			getStatefulSet(sts)
+			noSentinel()
+			// referenced secret isn't applied
+			applied := applyObjects(t, 3)
 
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
-				Return(k8serrors.NewNotFound(schema.GroupResource{
-					Group:    "",
-					Resource: "deployments",
-				}, updateRequest.CrdName))
+			_, err := s.Update(ctx, &req)
+			require.NoError(t, err)
 
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
-				Return(k8serrors.NewNotFound(schema.GroupResource{
-					Group:    "",
-					Resource: "services",
-				}, updateRequest.CrdName))
+			updated := applied["StatefulSet/valkey"].(*appsv1.StatefulSet)
+			require.NotEmpty(t, updated.Spec.Template.Annotations["database.kuberly.io/password-hash"])
+		})
+
+		t.Run("keeps claim templates and pods of removed shards", func(t *testing.T) {
+			req := *updateRequest
+			req.Mode = utils.Pointer(v1alpha1.TypeModeCluster)
+			req.Cluster = &v1alpha1.Cluster{Shards: 2, ReplicasPerShard: 1}
+			req.Resource = &v1alpha1.Resource{
+				CPU:     "100m",
+				Memory:  "200Mi",
+				Storage: "5Gi",
+			}
 
-			err := s.Update(ctx, &req)
+			live := sts.DeepCopy()
+			live.Spec.Replicas = utils.Pointer(int32(6))
+			live.Spec.VolumeClaimTemplates = []v1.PersistentVolumeClaim{{
+				ObjectMeta: metav1.ObjectMeta{Name: "data"},
+				Spec: v1.PersistentVolumeClaimSpec{
+					Resources: v1.VolumeResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceStorage: resource.MustParse("1Gi"),
+						},
+					},
+				},
+			}}
+
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+			noLegacyDeployment()
+			getStatefulSet(live)
+			noSentinel()
+			applied := applyObjects(t, 3)
+
+			_, err := s.Update(ctx, &req)
 			require.NoError(t, err)
+
+			updated := applied["StatefulSet/valkey"].(*appsv1.StatefulSet)
+			require.Equal(t, int32(6), *updated.Spec.Replicas)
+			require.Equal(t, live.Spec.VolumeClaimTemplates, updated.Spec.VolumeClaimTemplates)
 		})
 
-		t.Run("get secret failed", func(t *testing.T) {
+		t.Run("keeps restore init container", func(t *testing.T) {
 			req := *updateRequest
-			req.Password = utils.Pointer("password2")
+			req.Volume = &v1alpha1.Volume{Enabled: true, Storage: storage}
 
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+			restore := v1.Container{Name: "restore", Image: "curlimages/curl:8.10.1"}
+			restoreVolume := v1.Volume{Name: "restore"}
+			live := sts.DeepCopy()
+			live.Spec.Template.Spec.InitContainers = []v1.Container{restore, {Name: "manual"}}
+			live.Spec.Template.Spec.Volumes = []v1.Volume{restoreVolume, {Name: "manual"}}
 
-			err := s.Update(ctx, &req)
-			require.Error(t, err)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+			noLegacyDeployment()
+			getStatefulSet(live)
+			noSentinel()
+			applied := applyObjects(t, 3)
+
+			_, err := s.Update(ctx, &req)
+			require.NoError(t, err)
+
+			// only restore is kept, manual changes are reverted
+			spec := applied["StatefulSet/valkey"].(*appsv1.StatefulSet).Spec.Template.Spec
+			require.Equal(t, []v1.Container{restore}, spec.InitContainers)
+			require.Len(t, spec.Volumes, 2)
+			require.NotNil(t, spec.Volumes[0].ConfigMap)
+			require.Equal(t, restoreVolume, spec.Volumes[1])
 		})
 
-		t.Run("get deployment failed", func(t *testing.T) {
+		t.Run("read-write service selects current primary", func(t *testing.T) {
 			req := *updateRequest
+			req.Mode = utils.Pointer(v1alpha1.TypeModeReplication)
+			req.Primary = "valkey-1"
 
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+			noLegacyDeployment()
+			getStatefulSet(sts)
+			noSentinel()
+			applied := applyObjects(t, 5)
 
-			err := s.Update(ctx, &req)
-			require.Error(t, err)
+			_, err := s.Update(ctx, &req)
+			require.NoError(t, err)
+			require.Equal(t, map[string]string{"app": "valkey", "statefulset.kubernetes.io/pod-name": "valkey-1"},
+				applied["Service/valkey-rw"].(*v1.Service).Spec.Selector)
 		})
 
-		t.Run("get service failed", func(t *testing.T) {
+		t.Run("reports reverted manual changes", func(t *testing.T) {
 			req := *updateRequest
 
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).
-				Return(k8serrors.NewNotFound(schema.GroupResource{
-					Group:    "",
-					Resource: "deployments",
-				}, updateRequest.CrdName))
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+			noLegacyDeployment()
+			getStatefulSet(sts)
+			noSentinel()
+			applied := applyObjects(t, 3)
 
-			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+			drifted, err := s.Update(ctx, &req)
+			require.NoError(t, err)
+			require.Empty(t, drifted)
 
-			err := s.Update(ctx, &req)
-			require.Error(t, err)
-		})
+			// image is changed manually while desired state is the same
+			edited := applied["StatefulSet/valkey"].(*appsv1.StatefulSet).DeepCopy()
+			edited.Spec.Template.Spec.Containers[0].Image = "valkey/valkey:manual"
 
-	})
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+			noLegacyDeployment()
+			getStatefulSet(edited)
+			getStatefulSet(edited)
+			noSentinel()
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(sts), runtimeclient.Apply, gomock.Any()).Return(nil)
+			applyObjects(t, 2)
 
-	t.Run("delete", func(t *testing.T) {
+			drifted, err = s.Update(ctx, &req)
+			require.NoError(t, err)
+			require.Equal(t, []string{"StatefulSet/valkey"}, drifted)
+		})
 
-		t.Run("success", func(t *testing.T) {
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		t.Run("recreates removed objects", func(t *testing.T) {
+			req := *updateRequest
+			req.Password = utils.Pointer("password2")
 
-			err := s.Delete(ctx, deleteRequest)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+			noLegacyDeployment()
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(sts)).Return(statefulSetNotFound)
+			noSentinel()
+			applied := applyObjects(t, 4)
+
+			drifted, err := s.Update(ctx, &req)
 			require.NoError(t, err)
+			require.Empty(t, drifted)
+			require.Contains(t, applied, "Secret/valkey")
+			require.Contains(t, applied, "StatefulSet/valkey")
+			require.Contains(t, applied, "Service/valkey")
+		})
+
+		t.Run("password secret not found", func(t *testing.T) {
+			req := *updateRequest
+			req.PasswordSecretRef = &v1alpha1.SecretKeyRef{Name: "valkey-auth"}
+
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+
+			_, err := s.Update(ctx, &req)
+			require.ErrorIs(t, err, valkey.ErrPasswordSecretNotFound)
 		})
 
 		t.Run("with validation errors", func(t *testing.T) {
-			req := *deleteRequest
-			req.Name = ""
+			req := *updateRequest
+			req.CrdName = ""
 			req.Namespace = ""
 
-			err := s.Delete(ctx, &req)
+			_, err := s.Update(ctx, &req)
 			require.Error(t, err)
 
 			// get validator errors
@@ -534,8 +1113,8 @@ func TestService(t *testing.T) {
 			require.Len(t, errs, 2)
 			require.Equal(t, validatorlib.Errors{
 				{
-					Field:   "name",
-					Message: "name is a required field",
+					Field:   "crd_name",
+					Message: "crd_name is a required field",
 				},
 				{
 					Field:   "namespace",
@@ -544,61 +1123,1264 @@ func TestService(t *testing.T) {
 			}, errs)
 		})
 
-		t.Run("delete secret failed", func(t *testing.T) {
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+		t.Run("get secret failed", func(t *testing.T) {
+			req := *updateRequest
+			req.Password = utils.Pointer("password2")
 
-			err := s.Delete(ctx, deleteRequest)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+
+			_, err := s.Update(ctx, &req)
 			require.Error(t, err)
 		})
 
-		t.Run("delete deployment failed", func(t *testing.T) {
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+		t.Run("apply secret failed", func(t *testing.T) {
+			req := *updateRequest
+			req.Password = utils.Pointer("password2")
 
-			err := s.Delete(ctx, deleteRequest)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(nil).Times(2)
+			noLegacyDeployment()
+			getStatefulSet(sts)
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(secret), gomock.Any(), gomock.Any()).Return(mockErr)
+
+			_, err := s.Update(ctx, &req)
 			require.Error(t, err)
 		})
 
-		t.Run("delete pvc failed", func(t *testing.T) {
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+		t.Run("get legacy deployment failed", func(t *testing.T) {
+			req := *updateRequest
 
-			err := s.Delete(ctx, deleteRequest)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+
+			_, err := s.Update(ctx, &req)
 			require.Error(t, err)
 		})
 
-		t.Run("delete service failed", func(t *testing.T) {
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+		t.Run("get statefulset failed", func(t *testing.T) {
+			req := *updateRequest
 
-			err := s.Delete(ctx, deleteRequest)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+			noLegacyDeployment()
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+
+			_, err := s.Update(ctx, &req)
 			require.Error(t, err)
 		})
 
-		t.Run("idempotent delete", func(t *testing.T) {
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(k8serrors.NewNotFound(schema.GroupResource{
-				Group:    "",
-				Resource: "secrets",
-			}, createRequest.CrdName))
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(k8serrors.NewNotFound(schema.GroupResource{
-				Group:    "",
-				Resource: "deployments",
-			}, createRequest.CrdName))
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(k8serrors.NewNotFound(schema.GroupResource{
-				Group:    "",
-				Resource: "persistentvolumeclaims",
-			}, createRequest.CrdName))
-			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(k8serrors.NewNotFound(schema.GroupResource{
-				Group:    "",
-				Resource: "services",
-			}, createRequest.CrdName))
+		t.Run("apply statefulset failed", func(t *testing.T) {
+			req := *updateRequest
 
-			err := s.Delete(ctx, deleteRequest)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+			noLegacyDeployment()
+			getStatefulSet(sts)
+			applyConfigMap()
+			getStatefulSet(sts)
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(sts), gomock.Any(), gomock.Any()).Return(mockErr)
+
+			_, err := s.Update(ctx, &req)
+			require.Error(t, err)
+		})
+
+		t.Run("apply service failed", func(t *testing.T) {
+			req := *updateRequest
+
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+			noLegacyDeployment()
+			getStatefulSet(sts)
+			applyConfigMap()
+			getStatefulSet(sts)
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(sts), gomock.Any(), gomock.Any()).Return(nil)
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&v1.Service{})).Return(nil)
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Service{}), gomock.Any(), gomock.Any()).Return(mockErr)
+
+			_, err := s.Update(ctx, &req)
+			require.Error(t, err)
+		})
+
+		t.Run("migrate legacy deployment", func(t *testing.T) {
+			req := *updateRequest
+
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+
+			legacyPvc := &v1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "valkey-pvc",
+					Namespace: createRequest.Namespace,
+				},
+				Spec: v1.PersistentVolumeClaimSpec{
+					StorageClassName: utils.Pointer("standard"),
+					Resources: v1.VolumeResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceStorage: resource.MustParse("1Gi"),
+						},
+					},
+				},
+			}
+
+			getLegacyDeployment(0, "valkey-pvc")
+			listLegacyPods()
+			k8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{
+				Name:      "valkey-pvc",
+				Namespace: createRequest.Namespace,
+			}, gomock.AssignableToTypeOf(legacyPvc)).DoAndReturn(
+				func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+					*obj.(*v1.PersistentVolumeClaim) = *(legacyPvc)
+					return nil
+				})
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(legacyPvc)).DoAndReturn(
+				func(_ context.Context, obj runtimeclient.Object, _ ...runtimeclient.CreateOption) error {
+					pvc := obj.(*v1.PersistentVolumeClaim)
+					require.Equal(t, "data-valkey-0", pvc.Name)
+					require.Equal(t, "valkey-pvc", pvc.Spec.DataSource.Name)
+					require.Equal(t, "standard", *pvc.Spec.StorageClassName)
+					require.True(t, resource.MustParse("1Gi").Equal(pvc.Spec.Resources.Requests[v1.ResourceStorage]))
+					return nil
+				})
+			k8sClient.EXPECT().Delete(gomock.Any(), gomock.AssignableToTypeOf(&appsv1.Deployment{}), gomock.Any()).Return(nil)
+			noSentinel()
+			// StatefulSet is applied by migration and then by update
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(sts)).Return(statefulSetNotFound)
+			applied := applyObjects(t, 4)
+
+			_, err := s.Update(ctx, &req)
 			require.NoError(t, err)
+			require.Contains(t, applied, "StatefulSet/valkey")
 		})
 
-	})
+		t.Run("migrate legacy deployment failed", func(t *testing.T) {
+			req := *updateRequest
+
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+
+			getLegacyDeployment(0, "valkey-pvc")
+			listLegacyPods()
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&v1.PersistentVolumeClaim{})).Return(mockErr)
+
+			_, err := s.Update(ctx, &req)
+			require.Error(t, err)
+		})
+
+		t.Run("legacy deployment is scaled down before migration", func(t *testing.T) {
+			req := *updateRequest
+
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+			getLegacyDeployment(1, "valkey-pvc")
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&appsv1.Deployment{}), gomock.Any()).DoAndReturn(
+				func(_ context.Context, obj runtimeclient.Object, _ runtimeclient.Patch, _ ...runtimeclient.PatchOption) error {
+					require.Equal(t, int32(0), *obj.(*appsv1.Deployment).Spec.Replicas)
+					return nil
+				})
+
+			_, err := s.Update(ctx, &req)
+			require.ErrorIs(t, err, valkey.ErrMigrationInProgress)
+		})
+
+		t.Run("migration waits for legacy pods", func(t *testing.T) {
+			req := *updateRequest
+
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+			getLegacyDeployment(0, "valkey-pvc")
+			listLegacyPods(v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "valkey-5d8f-abcde"}})
+
+			_, err := s.Update(ctx, &req)
+			require.ErrorIs(t, err, valkey.ErrMigrationInProgress)
+		})
+
+		t.Run("legacy claim of other instance isn't cloned", func(t *testing.T) {
+			req := *updateRequest
+
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(secret)).Return(secretNotFound)
+			getLegacyDeployment(0, "")
+			listLegacyPods()
+			k8sClient.EXPECT().Delete(gomock.Any(), gomock.AssignableToTypeOf(&appsv1.Deployment{}), gomock.Any()).Return(nil)
+			noSentinel()
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(sts)).Return(statefulSetNotFound)
+			applied := applyObjects(t, 4)
+
+			_, err := s.Update(ctx, &req)
+			require.NoError(t, err)
+			require.Contains(t, applied, "StatefulSet/valkey")
+		})
+
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		getSnapshot := func(status map[string]any) {
+			k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&unstructured.Unstructured{})).DoAndReturn(
+				func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+					obj.(*unstructured.Unstructured).Object["status"] = status
+					return nil
+				})
+		}
+
+		t.Run("success", func(t *testing.T) {
+			k8sClient.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&v1.PersistentVolumeClaimList{}), gomock.Any()).DoAndReturn(
+				func(_ context.Context, list runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+					list.(*v1.PersistentVolumeClaimList).Items = []v1.PersistentVolumeClaim{
+						{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:            "data-valkey-0",
+								OwnerReferences: []metav1.OwnerReference{{Kind: "StatefulSet", Name: "valkey"}},
+							},
+						},
+						{ObjectMeta: metav1.ObjectMeta{Name: "data-valkey-1"}},
+					}
+					return nil
+				})
+			// only claim without owner is deleted, the rest is removed by garbage collector
+			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, obj runtimeclient.Object, _ ...runtimeclient.DeleteOption) error {
+					require.Equal(t, "data-valkey-1", obj.GetName())
+					return nil
+				})
+
+			err := s.Delete(ctx, deleteRequest)
+			require.NoError(t, err)
+		})
+
+		t.Run("with validation errors", func(t *testing.T) {
+			req := *deleteRequest
+			req.Name = ""
+			req.Namespace = ""
+
+			err := s.Delete(ctx, &req)
+			require.Error(t, err)
+
+			// get validator errors
+			errs := validatorlib.GetErrors(err)
+			if len(errs) == 0 {
+				require.Errorf(t, err, "expected validation errors")
+			}
+
+			require.Len(t, errs, 2)
+			require.Equal(t, validatorlib.Errors{
+				{
+					Field:   "name",
+					Message: "name is a required field",
+				},
+				{
+					Field:   "namespace",
+					Message: "namespace is a required field",
+				},
+			}, errs)
+		})
+
+		t.Run("list pvc failed", func(t *testing.T) {
+			k8sClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+
+			err := s.Delete(ctx, deleteRequest)
+			require.Error(t, err)
+		})
+
+		t.Run("delete pvc failed", func(t *testing.T) {
+			k8sClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, list runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+					list.(*v1.PersistentVolumeClaimList).Items = []v1.PersistentVolumeClaim{
+						{ObjectMeta: metav1.ObjectMeta{Name: "data-valkey-0"}},
+					}
+					return nil
+				})
+			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+
+			err := s.Delete(ctx, deleteRequest)
+			require.Error(t, err)
+		})
+
+		t.Run("idempotent delete", func(t *testing.T) {
+			k8sClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, list runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+					list.(*v1.PersistentVolumeClaimList).Items = []v1.PersistentVolumeClaim{
+						{ObjectMeta: metav1.ObjectMeta{Name: "data-valkey-0"}},
+					}
+					return nil
+				})
+			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(k8serrors.NewNotFound(schema.GroupResource{
+				Group:    "",
+				Resource: "persistentvolumeclaims",
+			}, "data-valkey-0"))
+
+			err := s.Delete(ctx, deleteRequest)
+			require.NoError(t, err)
+		})
+
+		t.Run("retain", func(t *testing.T) {
+			req := *deleteRequest
+			req.DeletionPolicy = v1alpha1.TypeDeletionPolicyRetain
+
+			listClaims(v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+				Name:            "data-valkey-0",
+				Labels:          map[string]string{"app": "valkey"},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "StatefulSet", Name: "valkey"}},
+			}})
+			// claim is released from StatefulSet and nothing is deleted
+			k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.PersistentVolumeClaim{}), gomock.Any()).DoAndReturn(
+				func(_ context.Context, obj runtimeclient.Object, _ runtimeclient.Patch, _ ...runtimeclient.PatchOption) error {
+					require.Empty(t, obj.GetOwnerReferences())
+					require.Equal(t, "valkey", obj.GetLabels()[v1alpha1.LabelRetainedFrom])
+					return nil
+				})
+
+			err := s.Delete(ctx, &req)
+			require.NoError(t, err)
+		})
+
+		t.Run("snapshot", func(t *testing.T) {
+			req := *deleteRequest
+			req.UID = "0123456789abcdef"
+			req.DeletionPolicy = v1alpha1.TypeDeletionPolicySnapshot
+
+			claim := v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+				Name:      "data-valkey-0",
+				Namespace: "default",
+				Labels:    map[string]string{"app": "valkey"},
+			}}
+			listClaims(claim)
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.AssignableToTypeOf(&unstructured.Unstructured{})).DoAndReturn(
+				func(_ context.Context, obj runtimeclient.Object, _ ...runtimeclient.CreateOption) error {
+					snapshot := obj.(*unstructured.Unstructured)
+					require.Equal(t, "VolumeSnapshot", snapshot.GetKind())
+					require.Equal(t, "data-valkey-0-01234567", snapshot.GetName())
+					source, _, _ := unstructured.NestedString(snapshot.Object, "spec", "source", "persistentVolumeClaimName")
+					require.Equal(t, "data-valkey-0", source)
+					return nil
+				})
+			getSnapshot(map[string]any{"readyToUse": true})
+			// claims are deleted after snapshots are taken
+			listClaims(claim)
+			k8sClient.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+			err := s.Delete(ctx, &req)
+			require.NoError(t, err)
+		})
+
+		t.Run("deletion waits for snapshot", func(t *testing.T) {
+			req := *deleteRequest
+			req.DeletionPolicy = v1alpha1.TypeDeletionPolicySnapshot
+
+			listClaims(v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data-valkey-0"}})
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).
+				Return(k8serrors.NewAlreadyExists(schema.GroupResource{}, "data-valkey-0"))
+			getSnapshot(map[string]any{"readyToUse": false})
+
+			// claims aren't deleted
+			err := s.Delete(ctx, &req)
+			require.ErrorIs(t, err, valkey.ErrSnapshotNotReady)
+		})
+
+		t.Run("snapshot error is reported", func(t *testing.T) {
+			req := *deleteRequest
+			req.DeletionPolicy = v1alpha1.TypeDeletionPolicySnapshot
+
+			listClaims(v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data-valkey-0"}})
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+			getSnapshot(map[string]any{"error": map[string]any{"message": "no default snapshot class"}})
+
+			err := s.Delete(ctx, &req)
+			require.ErrorIs(t, err, valkey.ErrSnapshotFailed)
+			require.ErrorContains(t, err, "no default snapshot class")
+		})
+
+		t.Run("snapshot failed", func(t *testing.T) {
+			req := *deleteRequest
+			req.DeletionPolicy = v1alpha1.TypeDeletionPolicySnapshot
+
+			listClaims(v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data-valkey-0"}})
+			k8sClient.EXPECT().Create(gomock.Any(), gomock.Any()).Return(mockErr)
+
+			err := s.Delete(ctx, &req)
+			require.ErrorIs(t, err, mockErr)
+		})
+
+	})
+}
+
+func TestSyncReplication(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockErr := errors.New("mock error")
+	k8sClient := mocks.NewMockK8sClient(ctrl)
+	valkeyClient := mocks.NewMockValkeyClient(ctrl)
+	s := valkey.NewValkeyService(
+		valkey.WithK8sClient(k8sClient),
+		valkey.WithValkeyClient(valkeyClient),
+	)
+
+	req := &valkey.SyncReplicationRequest{
+		CrdName:   "valkey",
+		Namespace: "default",
+	}
+
+	newPod := func(name, ip, role string) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: req.Namespace,
+				Labels: map[string]string{
+					"app":                      req.CrdName,
+					"database.kuberly.io/role": role,
+				},
+			},
+			Status: v1.PodStatus{
+				PodIP: ip,
+				Conditions: []v1.PodCondition{{
+					Type:   v1.PodReady,
+					Status: v1.ConditionTrue,
+				}},
+			},
+		}
+	}
+
+	listPods := func(pods ...v1.Pod) {
+		k8sClient.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&v1.PodList{}), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+				obj.(*v1.PodList).Items = pods
+				return nil
+			})
+	}
+
+	getPrimaryService := func(primary string) {
+		k8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{
+			Name:      "valkey-rw",
+			Namespace: req.Namespace,
+		}, gomock.AssignableToTypeOf(&v1.Service{})).DoAndReturn(
+			func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+				obj.(*v1.Service).Spec.Selector = map[string]string{
+					"app":                                req.CrdName,
+					"statefulset.kubernetes.io/pod-name": primary,
+				}
+				return nil
+			})
+	}
+
+	primaryHost := "valkey-0.valkey.default.svc"
+
+	t.Run("configure replicas", func(t *testing.T) {
+		listPods(
+			newPod("valkey-0", "10.0.0.1", ""),
+			newPod("valkey-1", "10.0.0.2", ""),
+		)
+
+		valkeyClient.EXPECT().Role(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.1:6379"}).
+			Return(&valkeyclient.Role{Role: valkeyclient.RolePrimary}, nil)
+		valkeyClient.EXPECT().Role(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.2:6379"}).
+			Return(&valkeyclient.Role{Role: valkeyclient.RolePrimary}, nil)
+		valkeyClient.EXPECT().ReplicaOf(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.2:6379"}, primaryHost, 6379).
+			Return(nil)
+		getPrimaryService("valkey-0")
+
+		roles := map[string]string{}
+		k8sClient.EXPECT().Patch(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.Object, _ runtimeclient.Patch, _ ...runtimeclient.PatchOption) error {
+				roles[obj.GetName()] = obj.GetLabels()["database.kuberly.io/role"]
+				return nil
+			}).Times(2)
+
+		primary, err := s.SyncReplication(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, "valkey-0", primary)
+		require.Equal(t, map[string]string{"valkey-0": "primary", "valkey-1": "replica"}, roles)
+	})
+
+	t.Run("already configured", func(t *testing.T) {
+		listPods(
+			newPod("valkey-0", "10.0.0.1", "primary"),
+			newPod("valkey-1", "10.0.0.2", "replica"),
+		)
+
+		valkeyClient.EXPECT().Role(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.1:6379"}).
+			Return(&valkeyclient.Role{Role: valkeyclient.RolePrimary}, nil)
+		valkeyClient.EXPECT().Role(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.2:6379"}).
+			Return(&valkeyclient.Role{
+				Role:        valkeyclient.RoleReplica,
+				PrimaryHost: primaryHost,
+				PrimaryPort: 6379,
+			}, nil)
+		getPrimaryService("valkey-0")
+
+		primary, err := s.SyncReplication(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, "valkey-0", primary)
+	})
+
+	t.Run("replicas connect to tls port", func(t *testing.T) {
+		certPEM, _ := newCertificate(t, "valkey-0.valkey.default.svc")
+		withTLS := func(pod v1.Pod) v1.Pod {
+			pod.Spec.Volumes = []v1.Volume{{
+				Name:         "tls",
+				VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "valkey-tls"}},
+			}}
+			return pod
+		}
+		listPods(
+			withTLS(newPod("valkey-0", "10.0.0.1", "primary")),
+			withTLS(newPod("valkey-1", "10.0.0.2", "replica")),
+		)
+		k8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{
+			Name:      "valkey-tls",
+			Namespace: req.Namespace,
+		}, gomock.AssignableToTypeOf(&v1.Secret{})).DoAndReturn(
+			func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+				obj.(*v1.Secret).Data = map[string][]byte{"ca.crt": certPEM}
+				return nil
+			}).Times(2)
+
+		tlsAddr := func(addr, serverName string) gomock.Matcher {
+			return gomock.Cond(func(x any) bool {
+				opts := x.(valkeyclient.Options)
+				return opts.Addr == addr && opts.TLS != nil && opts.TLS.ServerName == serverName
+			})
+		}
+		valkeyClient.EXPECT().Role(gomock.Any(), tlsAddr("10.0.0.1:6380", "valkey-0.valkey.default.svc")).
+			Return(&valkeyclient.Role{Role: valkeyclient.RolePrimary}, nil)
+		valkeyClient.EXPECT().Role(gomock.Any(), tlsAddr("10.0.0.2:6380", "valkey-1.valkey.default.svc")).
+			Return(&valkeyclient.Role{
+				Role:        valkeyclient.RoleReplica,
+				PrimaryHost: primaryHost,
+				PrimaryPort: 6379,
+			}, nil)
+		valkeyClient.EXPECT().ReplicaOf(gomock.Any(), tlsAddr("10.0.0.2:6380", "valkey-1.valkey.default.svc"), primaryHost, 6380).
+			Return(nil)
+		getPrimaryService("valkey-0")
+
+		primary, err := s.SyncReplication(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, "valkey-0", primary)
+	})
+
+	t.Run("promote known primary", func(t *testing.T) {
+		listPods(
+			newPod("valkey-0", "10.0.0.1", "replica"),
+			newPod("valkey-1", "10.0.0.2", "primary"),
+		)
+
+		valkeyClient.EXPECT().Role(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.2:6379"}).
+			Return(&valkeyclient.Role{Role: valkeyclient.RoleReplica}, nil)
+		valkeyClient.EXPECT().ReplicaOfNoOne(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.2:6379"}).
+			Return(nil)
+		valkeyClient.EXPECT().Role(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.1:6379"}).
+			Return(&valkeyclient.Role{
+				Role:        valkeyclient.RoleReplica,
+				PrimaryHost: "valkey-1.valkey.default.svc",
+				PrimaryPort: 6379,
+			}, nil)
+		getPrimaryService("valkey-0")
+
+		var selector map[string]string
+		k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Service{}), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.Object, _ runtimeclient.Patch, _ ...runtimeclient.PatchOption) error {
+				selector = obj.(*v1.Service).Spec.Selector
+				return nil
+			})
+
+		primary, err := s.SyncReplication(ctx, &valkey.SyncReplicationRequest{
+			CrdName:   req.CrdName,
+			Namespace: req.Namespace,
+			Primary:   "valkey-1",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "valkey-1", primary)
+		require.Equal(t, "valkey-1", selector["statefulset.kubernetes.io/pod-name"])
+	})
+
+	t.Run("primary from sentinel", func(t *testing.T) {
+		listPods(
+			newPod("valkey-0", "10.0.0.1", "primary"),
+			newPod("valkey-1", "10.0.0.2", "replica"),
+		)
+
+		valkeyClient.EXPECT().SentinelPrimary(gomock.Any(), valkeyclient.Options{
+			Addr: "valkey-sentinel.default.svc:26379",
+		}, req.CrdName).Return("valkey-1.valkey.default.svc", 6379, nil)
+		valkeyClient.EXPECT().Role(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.2:6379"}).
+			Return(&valkeyclient.Role{Role: valkeyclient.RolePrimary}, nil)
+		valkeyClient.EXPECT().Role(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.1:6379"}).
+			Return(&valkeyclient.Role{
+				Role:        valkeyclient.RoleReplica,
+				PrimaryHost: "valkey-1.valkey.default.svc",
+				PrimaryPort: 6379,
+			}, nil)
+		getPrimaryService("valkey-1")
+
+		roles := map[string]string{}
+		k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Pod{}), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.Object, _ runtimeclient.Patch, _ ...runtimeclient.PatchOption) error {
+				roles[obj.GetName()] = obj.GetLabels()["database.kuberly.io/role"]
+				return nil
+			}).Times(2)
+
+		primary, err := s.SyncReplication(ctx, &valkey.SyncReplicationRequest{
+			CrdName:   req.CrdName,
+			Namespace: req.Namespace,
+			Primary:   "valkey-0",
+			Sentinel:  true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "valkey-1", primary)
+		require.Equal(t, map[string]string{"valkey-0": "replica", "valkey-1": "primary"}, roles)
+	})
+
+	t.Run("sentinel failover in progress", func(t *testing.T) {
+		listPods(
+			newPod("valkey-0", "10.0.0.1", "primary"),
+			newPod("valkey-1", "10.0.0.2", "replica"),
+		)
+
+		valkeyClient.EXPECT().SentinelPrimary(gomock.Any(), gomock.Any(), req.CrdName).Return("", 0, mockErr)
+		valkeyClient.EXPECT().Role(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.1:6379"}).
+			Return(&valkeyclient.Role{Role: valkeyclient.RoleReplica}, nil)
+
+		primary, err := s.SyncReplication(ctx, &valkey.SyncReplicationRequest{
+			CrdName:   req.CrdName,
+			Namespace: req.Namespace,
+			Primary:   "valkey-0",
+			Sentinel:  true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "valkey-0", primary)
+	})
+
+	t.Run("empty primary isn't kept while replicas have data", func(t *testing.T) {
+		listPods(
+			newPod("valkey-0", "10.0.0.1", "primary"),
+			newPod("valkey-1", "10.0.0.2", "replica"),
+			newPod("valkey-2", "10.0.0.3", "replica"),
+		)
+
+		// primary is restarted without volume
+		valkeyClient.EXPECT().Role(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.1:6379"}).
+			Return(&valkeyclient.Role{Role: valkeyclient.RolePrimary}, nil)
+		valkeyClient.EXPECT().Role(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.2:6379"}).
+			Return(&valkeyclient.Role{Role: valkeyclient.RoleReplica, Offset: 100, PrimaryHost: primaryHost, PrimaryPort: 6379}, nil)
+		valkeyClient.EXPECT().Role(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.3:6379"}).
+			Return(&valkeyclient.Role{Role: valkeyclient.RoleReplica, Offset: 50, PrimaryHost: primaryHost, PrimaryPort: 6379}, nil)
+		// replica with the most data is promoted
+		valkeyClient.EXPECT().ReplicaOfNoOne(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.2:6379"}).Return(nil)
+		valkeyClient.EXPECT().ReplicaOf(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.1:6379"}, "valkey-1.valkey.default.svc", 6379).
+			Return(nil)
+		valkeyClient.EXPECT().ReplicaOf(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.3:6379"}, "valkey-1.valkey.default.svc", 6379).
+			Return(nil)
+		getPrimaryService("valkey-0")
+		k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Service{}), gomock.Any()).Return(nil)
+
+		roles := map[string]string{}
+		k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Pod{}), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.Object, _ runtimeclient.Patch, _ ...runtimeclient.PatchOption) error {
+				roles[obj.GetName()] = obj.GetLabels()["database.kuberly.io/role"]
+				return nil
+			}).Times(2)
+
+		primary, err := s.SyncReplication(ctx, &valkey.SyncReplicationRequest{
+			CrdName:   req.CrdName,
+			Namespace: req.Namespace,
+			Primary:   "valkey-0",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "valkey-1", primary)
+		require.Equal(t, map[string]string{"valkey-0": "replica", "valkey-1": "primary"}, roles)
+	})
+
+	t.Run("empty pod isn't promoted while replicas have data", func(t *testing.T) {
+		listPods(
+			newPod("valkey-0", "10.0.0.1", "primary"),
+			newPod("valkey-1", "10.0.0.2", "replica"),
+		)
+
+		// restarted pod joined the replica on start
+		replicaHost := "valkey-1.valkey.default.svc"
+		valkeyClient.EXPECT().Role(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.1:6379"}).
+			Return(&valkeyclient.Role{Role: valkeyclient.RoleReplica, Offset: -1, PrimaryHost: replicaHost, PrimaryPort: 6379}, nil)
+		valkeyClient.EXPECT().Role(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.2:6379"}).
+			Return(&valkeyclient.Role{Role: valkeyclient.RoleReplica, Offset: 100, PrimaryHost: primaryHost, PrimaryPort: 6379}, nil)
+		valkeyClient.EXPECT().ReplicaOfNoOne(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.2:6379"}).Return(nil)
+		getPrimaryService("valkey-0")
+		k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Service{}), gomock.Any()).Return(nil)
+		k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Pod{}), gomock.Any()).Return(nil).Times(2)
+
+		primary, err := s.SyncReplication(ctx, &valkey.SyncReplicationRequest{
+			CrdName:   req.CrdName,
+			Namespace: req.Namespace,
+			Primary:   "valkey-0",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "valkey-1", primary)
+	})
+
+	t.Run("primary is not ready", func(t *testing.T) {
+		notReady := newPod("valkey-0", "", "primary")
+		listPods(notReady, newPod("valkey-1", "10.0.0.2", "replica"))
+
+		primary, err := s.SyncReplication(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, "valkey-0", primary)
+	})
+
+	t.Run("with validation errors", func(t *testing.T) {
+		_, err := s.SyncReplication(ctx, &valkey.SyncReplicationRequest{})
+		require.Error(t, err)
+		require.Len(t, validatorlib.GetErrors(err), 2)
+	})
+
+	t.Run("list pods failed", func(t *testing.T) {
+		k8sClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+
+		_, err := s.SyncReplication(ctx, req)
+		require.Error(t, err)
+	})
+
+	t.Run("replica of failed", func(t *testing.T) {
+		listPods(
+			newPod("valkey-0", "10.0.0.1", "primary"),
+			newPod("valkey-1", "10.0.0.2", "replica"),
+		)
+
+		valkeyClient.EXPECT().Role(gomock.Any(), gomock.Any()).
+			Return(&valkeyclient.Role{Role: valkeyclient.RolePrimary}, nil).Times(2)
+		valkeyClient.EXPECT().ReplicaOf(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+
+		_, err := s.SyncReplication(ctx, req)
+		require.Error(t, err)
+	})
+}
+
+func TestSyncCluster(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockErr := errors.New("mock error")
+	k8sClient := mocks.NewMockK8sClient(ctrl)
+	valkeyClient := mocks.NewMockValkeyClient(ctrl)
+	s := valkey.NewValkeyService(
+		valkey.WithK8sClient(k8sClient),
+		valkey.WithValkeyClient(valkeyClient),
+	)
+
+	req := &valkey.SyncClusterRequest{
+		CrdName:   "valkey",
+		Namespace: "default",
+		Shards:    3,
+	}
+
+	newPod := func(ordinal int, ready bool) v1.Pod {
+		pod := v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("valkey-%d", ordinal),
+				Namespace: req.Namespace,
+				Labels:    map[string]string{"app": req.CrdName},
+			},
+		}
+		if ready {
+			pod.Status = v1.PodStatus{
+				PodIP: fmt.Sprintf("10.0.0.%d", ordinal),
+				Conditions: []v1.PodCondition{{
+					Type:   v1.PodReady,
+					Status: v1.ConditionTrue,
+				}},
+			}
+		}
+
+		return pod
+	}
+
+	listPods := func(count int) {
+		pods := make([]v1.Pod, 0, count)
+		for ordinal := 0; ordinal < count; ordinal++ {
+			pods = append(pods, newPod(ordinal, true))
+		}
+
+		k8sClient.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&v1.PodList{}), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+				obj.(*v1.PodList).Items = pods
+				return nil
+			})
+	}
+
+	newNode := func(ordinal int, primary string, slots ...valkeyclient.SlotRange) valkeyclient.ClusterNode {
+		flags := []string{valkeyclient.RolePrimary}
+		if primary != "" {
+			flags = []string{valkeyclient.RoleReplica}
+		}
+
+		return valkeyclient.ClusterNode{
+			ID:        fmt.Sprintf("node-%d", ordinal),
+			Addr:      fmt.Sprintf("10.0.0.%d:6379", ordinal),
+			Flags:     flags,
+			PrimaryID: primary,
+			LinkState: "connected",
+			Slots:     slots,
+		}
+	}
+
+	// expectNodes makes every node of the view to see the same cluster
+	expectNodes := func(view ...valkeyclient.ClusterNode) {
+		for idx, node := range view {
+			nodes := make([]valkeyclient.ClusterNode, len(view))
+			copy(nodes, view)
+			nodes[idx].Flags = append([]string{"myself"}, node.Flags...)
+
+			valkeyClient.EXPECT().ClusterNodes(gomock.Any(), valkeyclient.Options{Addr: node.Addr}).Return(nodes, nil)
+		}
+	}
+
+	slots := []valkeyclient.SlotRange{
+		{Start: 0, End: 5461},
+		{Start: 5462, End: 10922},
+		{Start: 10923, End: 16383},
+	}
+
+	t.Run("pods are not ready", func(t *testing.T) {
+		pods := []v1.Pod{newPod(0, true), newPod(1, false)}
+		k8sClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+				obj.(*v1.PodList).Items = pods
+				return nil
+			})
+		expectNodes(newNode(0, ""))
+
+		shards, err := s.SyncCluster(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, []v1alpha1.ShardStatus{
+			{Index: 0, Status: v1alpha1.TypeStatusUpdating, Primary: "valkey-0"},
+			{Index: 1, Status: v1alpha1.TypeStatusUpdating},
+			{Index: 2, Status: v1alpha1.TypeStatusUpdating},
+		}, shards)
+	})
+
+	t.Run("meet nodes", func(t *testing.T) {
+		listPods(3)
+		for ordinal := 0; ordinal < 3; ordinal++ {
+			expectNodes(newNode(ordinal, ""))
+		}
+
+		seed := valkeyclient.Options{Addr: "10.0.0.0:6379"}
+		valkeyClient.EXPECT().ClusterMeet(gomock.Any(), seed, "10.0.0.1", 6379).Return(nil)
+		valkeyClient.EXPECT().ClusterMeet(gomock.Any(), seed, "10.0.0.2", 6379).Return(nil)
+
+		_, err := s.SyncCluster(ctx, req)
+		require.NoError(t, err)
+	})
+
+	t.Run("assign slots", func(t *testing.T) {
+		listPods(3)
+		expectNodes(newNode(0, ""), newNode(1, ""), newNode(2, ""))
+
+		for ordinal, r := range slots {
+			valkeyClient.EXPECT().ClusterAddSlotsRange(gomock.Any(), valkeyclient.Options{
+				Addr: fmt.Sprintf("10.0.0.%d:6379", ordinal),
+			}, r).Return(nil)
+		}
+
+		_, err := s.SyncCluster(ctx, req)
+		require.NoError(t, err)
+	})
+
+	t.Run("configure replicas", func(t *testing.T) {
+		listPods(6)
+		expectNodes(
+			newNode(0, "", slots[0]),
+			newNode(1, "node-0"),
+			newNode(2, "", slots[1]),
+			newNode(3, ""),
+			newNode(4, "", slots[2]),
+			newNode(5, "node-4"),
+		)
+
+		valkeyClient.EXPECT().ClusterReplicate(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.3:6379"}, "node-2").Return(nil)
+
+		shards, err := s.SyncCluster(ctx, &valkey.SyncClusterRequest{
+			CrdName:          req.CrdName,
+			Namespace:        req.Namespace,
+			Shards:           3,
+			ReplicasPerShard: 1,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []v1alpha1.ShardStatus{
+			{Index: 0, Status: v1alpha1.TypeStatusHealthy, Primary: "valkey-0", Slots: "0-5461", ReadyReplicas: 1},
+			{Index: 1, Status: v1alpha1.TypeStatusUpdating, Primary: "valkey-2", Slots: "5462-10922"},
+			{Index: 2, Status: v1alpha1.TypeStatusHealthy, Primary: "valkey-4", Slots: "10923-16383", ReadyReplicas: 1},
+		}, shards)
+	})
+
+	t.Run("rebalance slots to new shard", func(t *testing.T) {
+		listPods(4)
+		expectNodes(
+			newNode(0, "", slots[0]),
+			newNode(1, "", slots[1]),
+			newNode(2, "", slots[2]),
+			newNode(3, ""),
+		)
+
+		var moved []int
+		valkeyClient.EXPECT().MigrateSlot(gomock.Any(), gomock.Any(), valkeyclient.Options{Addr: "10.0.0.3:6379"}, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _, _ valkeyclient.Options, slot int) error {
+				moved = append(moved, slot)
+				return nil
+			}).Times(128)
+
+		shards, err := s.SyncCluster(ctx, &valkey.SyncClusterRequest{
+			CrdName:   req.CrdName,
+			Namespace: req.Namespace,
+			Shards:    4,
+		})
+		require.NoError(t, err)
+		require.Len(t, shards, 4)
+		require.Equal(t, v1alpha1.TypeStatusUpdating, shards[3].Status)
+		// slots above the share of the first shard are moved first
+		require.Equal(t, 4096, moved[0])
+	})
+
+	t.Run("scale down drained shard", func(t *testing.T) {
+		listPods(4)
+		expectNodes(
+			newNode(0, "", slots[0]),
+			newNode(1, "", slots[1]),
+			newNode(2, "", slots[2]),
+			newNode(3, ""),
+		)
+
+		k8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{
+			Name:      req.CrdName,
+			Namespace: req.Namespace,
+		}, gomock.AssignableToTypeOf(&appsv1.StatefulSet{})).DoAndReturn(
+			func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+				obj.(*appsv1.StatefulSet).Spec.Replicas = utils.Pointer(int32(4))
+				return nil
+			})
+
+		var replicas int32
+		k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&appsv1.StatefulSet{}), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.Object, _ runtimeclient.Patch, _ ...runtimeclient.PatchOption) error {
+				replicas = *obj.(*appsv1.StatefulSet).Spec.Replicas
+				return nil
+			})
+
+		shards, err := s.SyncCluster(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, shards, 4)
+		require.Equal(t, int32(3), replicas)
+	})
+
+	t.Run("with validation errors", func(t *testing.T) {
+		_, err := s.SyncCluster(ctx, &valkey.SyncClusterRequest{})
+		require.Error(t, err)
+		require.Len(t, validatorlib.GetErrors(err), 3)
+	})
+
+	t.Run("list pods failed", func(t *testing.T) {
+		k8sClient.EXPECT().List(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+
+		_, err := s.SyncCluster(ctx, req)
+		require.Error(t, err)
+	})
+
+	t.Run("cluster nodes failed", func(t *testing.T) {
+		listPods(3)
+		valkeyClient.EXPECT().ClusterNodes(gomock.Any(), gomock.Any()).Return(nil, mockErr)
+
+		_, err := s.SyncCluster(ctx, req)
+		require.Error(t, err)
+	})
+}
+
+func TestReloadTLS(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockErr := errors.New("mock error")
+	k8sClient := mocks.NewMockK8sClient(ctrl)
+	valkeyClient := mocks.NewMockValkeyClient(ctrl)
+	s := valkey.NewValkeyService(
+		valkey.WithK8sClient(k8sClient),
+		valkey.WithValkeyClient(valkeyClient),
+	)
+
+	req := &valkey.ReloadTLSRequest{
+		CrdName:   "valkey",
+		Namespace: "default",
+	}
+
+	certPEM, cert := newCertificate(t, "*.valkey.default.svc")
+	_, staleCert := newCertificate(t, "*.valkey.default.svc")
+
+	newPod := func(name, ip string, tls bool) v1.Pod {
+		res := v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: req.Namespace,
+				Labels:    map[string]string{"app": req.CrdName},
+			},
+			Status: v1.PodStatus{
+				PodIP: ip,
+				Conditions: []v1.PodCondition{{
+					Type:   v1.PodReady,
+					Status: v1.ConditionTrue,
+				}},
+			},
+		}
+		if tls {
+			res.Spec.Volumes = []v1.Volume{{
+				Name:         "tls",
+				VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "valkey-tls"}},
+			}}
+		}
+
+		return res
+	}
+
+	listPods := func(pods ...v1.Pod) {
+		k8sClient.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&v1.PodList{}), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+				obj.(*v1.PodList).Items = pods
+				return nil
+			})
+	}
+
+	getSecret := func(data map[string][]byte) {
+		k8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{
+			Name:      "valkey-tls",
+			Namespace: req.Namespace,
+		}, gomock.AssignableToTypeOf(&v1.Secret{})).DoAndReturn(
+			func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+				obj.(*v1.Secret).Data = data
+				return nil
+			})
+	}
+
+	secretData := map[string][]byte{"tls.crt": certPEM, "ca.crt": certPEM}
+
+	t.Run("certificate is loaded", func(t *testing.T) {
+		listPods(newPod("valkey-0", "10.0.0.1", true))
+		getSecret(secretData)
+		valkeyClient.EXPECT().Certificate(gomock.Any(), gomock.Any()).Return(cert, nil)
+
+		loaded, err := s.ReloadTLS(ctx, req)
+		require.NoError(t, err)
+		require.True(t, loaded)
+	})
+
+	t.Run("stale certificate is reloaded", func(t *testing.T) {
+		listPods(newPod("valkey-0", "10.0.0.1", true))
+		getSecret(secretData)
+		valkeyClient.EXPECT().Certificate(gomock.Any(), gomock.Any()).Return(staleCert, nil)
+		valkeyClient.EXPECT().ConfigSet(gomock.Any(), gomock.Any(), map[string]string{
+			"tls-cert-file":    "/tls/tls.crt",
+			"tls-key-file":     "/tls/tls.key",
+			"tls-ca-cert-file": "/tls/ca.crt",
+		}).Return(nil)
+
+		loaded, err := s.ReloadTLS(ctx, req)
+		require.NoError(t, err)
+		require.False(t, loaded)
+	})
+
+	t.Run("pods without certificates are skipped", func(t *testing.T) {
+		listPods(newPod("valkey-0", "10.0.0.1", false))
+
+		loaded, err := s.ReloadTLS(ctx, req)
+		require.NoError(t, err)
+		require.True(t, loaded)
+	})
+
+	t.Run("tls secret not found", func(t *testing.T) {
+		listPods(newPod("valkey-0", "10.0.0.1", true))
+		k8sClient.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.AssignableToTypeOf(&v1.Secret{})).
+			Return(k8serrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "valkey-tls"))
+
+		_, err := s.ReloadTLS(ctx, req)
+		require.ErrorIs(t, err, valkey.ErrTLSSecretNotFound)
+	})
+
+	t.Run("tls secret without ca", func(t *testing.T) {
+		listPods(newPod("valkey-0", "10.0.0.1", true))
+		getSecret(map[string][]byte{"tls.crt": certPEM})
+
+		_, err := s.ReloadTLS(ctx, req)
+		require.ErrorIs(t, err, valkey.ErrTLSSecretInvalid)
+	})
+
+	t.Run("certificate failed", func(t *testing.T) {
+		listPods(newPod("valkey-0", "10.0.0.1", true))
+		getSecret(secretData)
+		valkeyClient.EXPECT().Certificate(gomock.Any(), gomock.Any()).Return(nil, mockErr)
+
+		_, err := s.ReloadTLS(ctx, req)
+		require.ErrorIs(t, err, mockErr)
+	})
+
+	t.Run("with validation errors", func(t *testing.T) {
+		_, err := s.ReloadTLS(ctx, &valkey.ReloadTLSRequest{})
+		require.Error(t, err)
+	})
+}
+
+func TestApplyConfig(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockErr := errors.New("mock error")
+	k8sClient := mocks.NewMockK8sClient(ctrl)
+	valkeyClient := mocks.NewMockValkeyClient(ctrl)
+	s := valkey.NewValkeyService(
+		valkey.WithK8sClient(k8sClient),
+		valkey.WithValkeyClient(valkeyClient),
+	)
+
+	req := &valkey.ApplyConfigRequest{
+		CrdName:   "valkey",
+		Namespace: "default",
+		Config: map[string]string{
+			"maxmemory-policy": "allkeys-lru",
+			"databases":        "4",
+		},
+	}
+
+	newPod := func(name, ip, hash string) v1.Pod {
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   req.Namespace,
+				Labels:      map[string]string{"app": req.CrdName},
+				Annotations: map[string]string{"database.kuberly.io/applied-config-hash": hash},
+			},
+			Status: v1.PodStatus{
+				PodIP: ip,
+				Conditions: []v1.PodCondition{{
+					Type:   v1.PodReady,
+					Status: v1.ConditionTrue,
+				}},
+			},
+		}
+	}
+
+	listPods := func(pods ...v1.Pod) {
+		k8sClient.EXPECT().List(gomock.Any(), gomock.AssignableToTypeOf(&v1.PodList{}), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.ObjectList, _ ...runtimeclient.ListOption) error {
+				obj.(*v1.PodList).Items = pods
+				return nil
+			})
+	}
+
+	var appliedHash string
+	t.Run("dynamic parameters are applied", func(t *testing.T) {
+		listPods(newPod("valkey-0", "10.0.0.1", ""))
+		valkeyClient.EXPECT().ConfigSet(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.1:6379"},
+			map[string]string{"maxmemory-policy": "allkeys-lru"}).Return(nil)
+		k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Pod{}), gomock.Any()).DoAndReturn(
+			func(_ context.Context, obj runtimeclient.Object, _ runtimeclient.Patch, _ ...runtimeclient.PatchOption) error {
+				appliedHash = obj.GetAnnotations()["database.kuberly.io/applied-config-hash"]
+				return nil
+			})
+
+		err := s.ApplyConfig(ctx, req)
+		require.NoError(t, err)
+		require.NotEmpty(t, appliedHash)
+	})
+
+	t.Run("already applied", func(t *testing.T) {
+		listPods(newPod("valkey-0", "10.0.0.1", appliedHash))
+
+		err := s.ApplyConfig(ctx, req)
+		require.NoError(t, err)
+	})
+
+	t.Run("static parameters aren't applied", func(t *testing.T) {
+		listPods(newPod("valkey-0", "10.0.0.1", appliedHash))
+		k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Pod{}), gomock.Any()).Return(nil)
+
+		err := s.ApplyConfig(ctx, &valkey.ApplyConfigRequest{
+			CrdName:   req.CrdName,
+			Namespace: req.Namespace,
+			Config:    map[string]string{"databases": "8"},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("password is sent to pod which requires it", func(t *testing.T) {
+		pod := newPod("valkey-0", "10.0.0.1", "")
+		pod.Spec.Containers = []v1.Container{{
+			Name: "valkey",
+			Args: []string{"valkey-server", "--requirepass", "$(VALKEY_PASSWORD)"},
+			Env: []v1.EnvVar{{
+				Name: "VALKEY_PASSWORD",
+				ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{Name: "valkey"},
+					Key:                  "password",
+				}},
+			}},
+		}}
+		listPods(pod)
+		k8sClient.EXPECT().Get(gomock.Any(), types.NamespacedName{Name: "valkey", Namespace: req.Namespace},
+			gomock.AssignableToTypeOf(&v1.Secret{})).DoAndReturn(
+			func(_ context.Context, _ types.NamespacedName, obj runtimeclient.Object, _ ...runtimeclient.GetOption) error {
+				obj.(*v1.Secret).Data = map[string][]byte{"password": []byte("secret")}
+				return nil
+			})
+		valkeyClient.EXPECT().ConfigSet(gomock.Any(), valkeyclient.Options{Addr: "10.0.0.1:6379", Password: "secret"},
+			gomock.Any()).Return(nil)
+		k8sClient.EXPECT().Patch(gomock.Any(), gomock.AssignableToTypeOf(&v1.Pod{}), gomock.Any()).Return(nil)
+
+		err := s.ApplyConfig(ctx, req)
+		require.NoError(t, err)
+	})
+
+	t.Run("pods which aren't ready are skipped", func(t *testing.T) {
+		pod := newPod("valkey-0", "", "")
+		pod.Status.Conditions = nil
+		listPods(pod)
+
+		err := s.ApplyConfig(ctx, req)
+		require.NoError(t, err)
+	})
+
+	t.Run("config set failed", func(t *testing.T) {
+		listPods(newPod("valkey-0", "10.0.0.1", ""))
+		valkeyClient.EXPECT().ConfigSet(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockErr)
+
+		err := s.ApplyConfig(ctx, req)
+		require.ErrorIs(t, err, mockErr)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		err := s.ApplyConfig(ctx, &valkey.ApplyConfigRequest{
+			CrdName:   req.CrdName,
+			Namespace: req.Namespace,
+			Config:    map[string]string{"port": "6390"},
+		})
+		require.ErrorIs(t, err, render.ErrInvalidConfig)
+	})
+
+	t.Run("with validation errors", func(t *testing.T) {
+		err := s.ApplyConfig(ctx, &valkey.ApplyConfigRequest{})
+		require.Error(t, err)
+	})
+}
+
+// newCertificate returns self-signed certificate which is its own CA
+func newCertificate(t *testing.T, dnsName string) ([]byte, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: dnsName},
+		DNSNames:              []string{dnsName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), cert
 }
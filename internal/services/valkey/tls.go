@@ -0,0 +1,181 @@
+package valkey
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"strconv"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/uagolang/k8s-operator/internal/lib/validator"
+	"github.com/uagolang/k8s-operator/internal/lib/valkeyclient"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
+)
+
+type ReloadTLSRequest struct {
+	CrdName   string `json:"crd_name" validate:"required"`
+	Namespace string `json:"namespace" validate:"required"`
+}
+
+// ReloadTLS makes every ready pod serve certificate stored in the TLS Secret.
+// Kubelet updates files of mounted Secret with a delay, so certificate is
+// reloaded on every call until the pod presents it. Returns true when
+// every pod with certificates serves the current one
+func (s *valkeyService) ReloadTLS(ctx context.Context, i *ReloadTLSRequest) (bool, error) {
+	if err := validator.Validate(ctx, i); err != nil {
+		return false, err
+	}
+
+	pods, err := s.listPods(ctx, i.CrdName, i.Namespace)
+	if err != nil {
+		return false, err
+	}
+
+	loaded := true
+	for idx := range pods {
+		pod := &pods[idx]
+		if !isPodReady(pod) || render.PodTLSSecret(pod) == "" {
+			continue
+		}
+
+		sec, err := getTLSSecret(ctx, s.k8sClient, pod.Namespace, render.PodTLSSecret(pod))
+		if err != nil {
+			return false, err
+		}
+		want, err := leafCertificate(sec)
+		if err != nil {
+			return false, err
+		}
+
+		opts, err := tlsOptions(pod, sec)
+		if err != nil {
+			return false, err
+		}
+		got, err := s.valkeyClient.Certificate(ctx, opts)
+		if err != nil {
+			return false, err
+		}
+		if got.Equal(want) {
+			continue
+		}
+
+		log.FromContext(ctx).Info("reloading certificate", "pod", pod.Name)
+		if err = s.valkeyClient.ConfigSet(ctx, opts, render.TLSFiles()); err != nil {
+			return false, err
+		}
+		loaded = false
+	}
+
+	return loaded, nil
+}
+
+// PodOptions returns how operator connects to the pod, TLS port is used when
+// the pod has certificates and password is sent when the pod requires it,
+// so pods which aren't restarted yet after spec was changed are reached
+// the way they were started
+func PodOptions(ctx context.Context, c client.Reader, pod *corev1.Pod) (valkeyclient.Options, error) {
+	res := valkeyclient.Options{
+		Addr: net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(render.ContainerPort)),
+	}
+
+	if name := render.PodTLSSecret(pod); name != "" {
+		sec, err := getTLSSecret(ctx, c, pod.Namespace, name)
+		if err != nil {
+			return valkeyclient.Options{}, err
+		}
+		res, err = tlsOptions(pod, sec)
+		if err != nil {
+			return valkeyclient.Options{}, err
+		}
+	}
+
+	password, err := podPassword(ctx, c, pod)
+	if err != nil {
+		return valkeyclient.Options{}, err
+	}
+	res.Password = password
+
+	return res, nil
+}
+
+// podPassword returns password which the pod requires,
+// it's read from the Secret referenced by the pod
+func podPassword(ctx context.Context, c client.Reader, pod *corev1.Pod) (string, error) {
+	name, key := render.PodPasswordSecret(pod)
+	if name == "" {
+		return "", nil
+	}
+
+	sec := new(corev1.Secret)
+	err := c.Get(ctx, types.NamespacedName{
+		Name:      name,
+		Namespace: pod.Namespace,
+	}, sec)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return "", errors.Wrapf(ErrPasswordSecretNotFound, "%s/%s", pod.Namespace, name)
+		}
+
+		return "", err
+	}
+
+	return string(sec.Data[key]), nil
+}
+
+// tlsOptions verifies certificate of the pod by CA from the Secret,
+// the certificate should be valid for DNS name of the pod
+func tlsOptions(pod *corev1.Pod, sec *corev1.Secret) (valkeyclient.Options, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(sec.Data[render.SecretKeyTLSCA]) {
+		return valkeyclient.Options{}, errors.Wrapf(ErrTLSSecretInvalid, "%s has no %s", sec.Name, render.SecretKeyTLSCA)
+	}
+
+	return valkeyclient.Options{
+		Addr: net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(render.TLSPort)),
+		TLS: &tls.Config{
+			RootCAs:    pool,
+			ServerName: render.PodHost(pod.Name, pod.Labels[render.LabelApp], pod.Namespace),
+			MinVersion: tls.VersionTLS12,
+		},
+	}, nil
+}
+
+func getTLSSecret(ctx context.Context, c client.Reader, namespace, name string) (*corev1.Secret, error) {
+	res := new(corev1.Secret)
+	err := c.Get(ctx, types.NamespacedName{
+		Name:      name,
+		Namespace: namespace,
+	}, res)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, errors.Wrapf(ErrTLSSecretNotFound, "%s/%s", namespace, name)
+		}
+
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// leafCertificate returns the first certificate of the chain stored in the Secret
+func leafCertificate(sec *corev1.Secret) (*x509.Certificate, error) {
+	block, _ := pem.Decode(sec.Data[render.SecretKeyTLSCert])
+	if block == nil {
+		return nil, errors.Wrapf(ErrTLSSecretInvalid, "%s has no %s", sec.Name, render.SecretKeyTLSCert)
+	}
+
+	res, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(ErrTLSSecretInvalid, "%s: %s", sec.Name, err)
+	}
+
+	return res, nil
+}
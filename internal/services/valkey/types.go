@@ -1,11 +1,83 @@
 package valkey
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var ErrPasswordSecretNotFound = errors.New("password secret not found")
+
+var (
+	ErrRestoreSourceNotFound = errors.New("restore source not found")
+	ErrRestoreSourceNotReady = errors.New("restore source isn't ready")
+	ErrRestoreNotSupported   = errors.New("restore isn't supported")
+)
+
+var (
+	ErrTLSSecretNotFound = errors.New("tls secret not found")
+	ErrTLSSecretInvalid  = errors.New("tls secret is invalid")
+)
+
+// ErrMigrationInProgress is returned while legacy Deployment is stopped
+var ErrMigrationInProgress = errors.New("migration from deployment is in progress")
+
+var (
+	ErrSnapshotNotReady = errors.New("volume snapshot isn't ready")
+	ErrSnapshotFailed   = errors.New("volume snapshot failed")
+)
+
+var (
+	ErrStorageShrink         = errors.New("volume claims can't be shrunk")
+	ErrExpansionNotSupported = errors.New("storage class doesn't allow volume expansion")
+)
+
+var volumeSnapshotGVK = schema.GroupVersionKind{
+	Group:   "snapshot.storage.k8s.io",
+	Version: "v1",
+	Kind:    "VolumeSnapshot",
+}
 
 const (
-	secretKeyPassword = "password"
+	// generatedPasswordLength is a number of random bytes in generated password
+	generatedPasswordLength = 16
+
+	// annotationDesiredHash is a hash of the last applied desired object
+	annotationDesiredHash = "database.kuberly.io/desired-hash"
+
+	// annotationAppliedConfigHash is a hash of dynamic parameters set on the pod
+	annotationAppliedConfigHash = "database.kuberly.io/applied-config-hash"
+
+	// fieldManager owns fields of objects applied by operator
+	fieldManager = "valkey-operator"
 
-	pollInterval = 1 * time.Second
+	// legacyPvcName is a claim which was shared by all Deployment replicas
+	// before Valkey was provisioned as StatefulSet
+	legacyPvcName = "valkey-pvc"
 
-	defaultWaitDuration = time.Second * 5
+	// snapshotUIDLength is a number of uid characters in snapshot name
+	snapshotUIDLength = 8
 )
+
+// secretHash returns hash of the password stored in the Secret
+func secretHash(sec *corev1.Secret, key string) string {
+	value, ok := sec.Data[key]
+	if !ok {
+		value = []byte(sec.StringData[key])
+	}
+
+	return passwordHash(value)
+}
+
+func passwordHash(value []byte) string {
+	if len(value) == 0 {
+		return ""
+	}
+
+	sum := sha256.Sum256(value)
+
+	return hex.EncodeToString(sum[:])
+}
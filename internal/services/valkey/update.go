@@ -2,50 +2,117 @@ package valkey
 
 import (
 	"context"
-	"encoding/base64"
 
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/uagolang/k8s-operator/api/v1alpha1"
 	"github.com/uagolang/k8s-operator/internal/lib/validator"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
 )
 
 type UpdateRequest struct {
-	CrdName   string             `json:"crd_name" validate:"required"`
-	Namespace string             `json:"namespace" validate:"required"`
-	Image     *string            `json:"image,omitempty" validate:"omitempty"`
-	User      *string            `json:"user,omitempty" validate:"omitempty"`
-	Password  *string            `json:"password,omitempty" validate:"omitempty"`
-	Replicas  *int32             `json:"replicas,omitempty" validate:"omitempty"`
-	Volume    *v1alpha1.Volume   `json:"volume,omitempty" validate:"omitempty"`
-	Resource  *v1alpha1.Resource `json:"resource,omitempty" validate:"omitempty"`
+	CrdName           string                 `json:"crd_name" validate:"required"`
+	Namespace         string                 `json:"namespace" validate:"required"`
+	Image             *string                `json:"image" validate:"required"`
+	User              *string                `json:"user" validate:"required"`
+	Password          *string                `json:"password,omitempty" validate:"omitempty"`
+	PasswordSecretRef *v1alpha1.SecretKeyRef `json:"password_secret_ref,omitempty" validate:"omitempty"`
+	Replicas          *int32                 `json:"replicas,omitempty" validate:"omitempty"`
+	Mode              *v1alpha1.TypeMode     `json:"mode,omitempty" validate:"omitempty,oneof=standalone replication cluster"`
+	Sentinel          *v1alpha1.Sentinel     `json:"sentinel,omitempty" validate:"omitempty"`
+	Cluster           *v1alpha1.Cluster      `json:"cluster,omitempty" validate:"omitempty"`
+	Volume            *v1alpha1.Volume       `json:"volume" validate:"required"`
+	Resource          *v1alpha1.Resource     `json:"resource" validate:"required"`
+	TLS               *v1alpha1.TLS          `json:"tls,omitempty" validate:"omitempty"`
+	Config            map[string]string      `json:"config,omitempty" validate:"omitempty"`
+	Primary           string                 `json:"primary,omitempty" validate:"omitempty"`
+	Owner             *metav1.OwnerReference `json:"owner" validate:"required"`
 }
 
-func (s *valkeyService) Update(ctx context.Context, i *UpdateRequest) error {
+// Update renders full desired state of all objects and applies it,
+// returns kind and name of objects where manual changes were reverted
+func (s *valkeyService) Update(ctx context.Context, i *UpdateRequest) ([]string, error) {
 	if err := validator.Validate(ctx, i); err != nil {
-		return err
+		return nil, err
 	}
 
-	err := s.updateSecret(ctx, i)
+	item := i.toValkey()
+	password, passwordHash, err := s.getPassword(ctx, item)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = s.updateDeployment(ctx, i)
+	objs, err := render.Render(item, render.Options{
+		Password:     password,
+		PasswordHash: passwordHash,
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = s.updateService(ctx, i)
+	err = s.migrateDeployment(ctx, item, objs.StatefulSet)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	err = s.keepStatefulSetState(ctx, item, objs.StatefulSet)
+	if err != nil {
+		return nil, err
+	}
+
+	drifted, err := s.applyAll(ctx, objs.List()...)
+	if err != nil {
+		return nil, err
+	}
+
+	if objs.SentinelStatefulSet == nil {
+		err = s.removeSentinel(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return drifted, nil
+}
+
+// toValkey returns instance the request was built for,
+// so objects are rendered the same way on create and update
+func (i *UpdateRequest) toValkey() *v1alpha1.Valkey {
+	res := &v1alpha1.Valkey{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      i.CrdName,
+			Namespace: i.Namespace,
+			UID:       i.Owner.UID,
+		},
+		Spec: v1alpha1.ValkeySpec{
+			Image:    lo.FromPtr(i.Image),
+			Replicas: i.Replicas,
+			Mode:     lo.FromPtr(i.Mode),
+			Sentinel: i.Sentinel,
+			Cluster:  i.Cluster,
+			User:     lo.FromPtr(i.User),
+			Password: lo.FromPtr(i.Password),
+			Volume:   lo.FromPtr(i.Volume),
+			Resource: lo.FromPtr(i.Resource),
+			TLS:      i.TLS,
+			Config:   i.Config,
+		},
+		Status: v1alpha1.ValkeyStatus{
+			Primary: i.Primary,
+		},
+	}
+	if i.PasswordSecretRef != nil {
+		res.Spec.Auth = &v1alpha1.Auth{PasswordSecretRef: i.PasswordSecretRef}
+	}
+
+	return res
 }
 
 func (s *valkeyService) getSecret(ctx context.Context, i types.NamespacedName) (*corev1.Secret, error) {
@@ -65,37 +132,42 @@ func (s *valkeyService) getSecret(ctx context.Context, i types.NamespacedName) (
 	return res, nil
 }
 
-func (s *valkeyService) updateSecret(ctx context.Context, i *UpdateRequest) error {
-	if i.Password == nil || *i.Password == "" {
-		return nil
-	}
-
+// getPassword returns password stored in the Secret owned by operator
+// and hash of the password used by pods, referenced Secret must exist
+func (s *valkeyService) getPassword(ctx context.Context, item *v1alpha1.Valkey) ([]byte, string, error) {
+	ref := item.Spec.PasswordSecretRef()
+	name, key := render.PasswordSecret(item.Name, ref)
 	res, err := s.getSecret(ctx, types.NamespacedName{
-		Name:      i.CrdName,
-		Namespace: i.Namespace,
+		Name:      name,
+		Namespace: item.Namespace,
 	})
 	if err != nil {
-		return err
-	}
-	if res == nil {
-		return nil
+		return nil, "", err
 	}
+	if ref != nil {
+		if res == nil {
+			return nil, "", errors.Wrapf(ErrPasswordSecretNotFound, "%s/%s", item.Namespace, name)
+		}
 
-	if res.Data == nil {
-		res.Data = make(map[string][]byte)
+		return nil, secretHash(res, key), nil
 	}
-	res.Data[secretKeyPassword] = []byte(base64.StdEncoding.EncodeToString([]byte(*i.Password)))
 
-	err = s.k8sClient.Update(ctx, res)
-	if err != nil {
-		return err
+	// empty password means that generated one is kept
+	var value []byte
+	if item.Spec.Password != "" {
+		value = []byte(item.Spec.Password)
+	} else if res != nil {
+		value = res.Data[key]
+	}
+	if len(value) == 0 {
+		return nil, "", nil
 	}
 
-	return nil
+	return value, passwordHash(value), nil
 }
 
-func (s *valkeyService) getDeployment(ctx context.Context, i types.NamespacedName) (*appsv1.Deployment, error) {
-	res := new(appsv1.Deployment)
+func (s *valkeyService) getStatefulSet(ctx context.Context, i types.NamespacedName) (*appsv1.StatefulSet, error) {
+	res := new(appsv1.StatefulSet)
 	err := s.k8sClient.Get(ctx, i, res)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
@@ -108,51 +180,45 @@ func (s *valkeyService) getDeployment(ctx context.Context, i types.NamespacedNam
 	return res, nil
 }
 
-func (s *valkeyService) updateDeployment(ctx context.Context, i *UpdateRequest) error {
-	res, err := s.getDeployment(ctx, types.NamespacedName{
-		Name:      i.CrdName,
-		Namespace: i.Namespace,
-	})
-	if err != nil {
+// keepStatefulSetState copies to desired StatefulSet fields which
+// can't be changed after creation and replicas managed by cluster sync
+func (s *valkeyService) keepStatefulSetState(ctx context.Context, item *v1alpha1.Valkey, desired *appsv1.StatefulSet) error {
+	res, err := s.getStatefulSet(ctx, client.ObjectKeyFromObject(desired))
+	if err != nil || res == nil {
 		return err
 	}
-	if res == nil {
-		return nil
-	}
 
-	var shouldUpdate bool
-	if res.Spec.Replicas != nil && i.Replicas != nil && *res.Spec.Replicas != *i.Replicas {
-		shouldUpdate = true
-		res.Spec.Replicas = i.Replicas
-	}
+	desired.Spec.VolumeClaimTemplates = res.Spec.VolumeClaimTemplates
 
-	if len(res.Spec.Template.Spec.Containers) > 0 {
-		container := &res.Spec.Template.Spec.Containers[0]
-		if i.Image != nil {
-			shouldUpdate = true
-			container.Image = *i.Image
+	// restore source is resolved only on create, init container is kept,
+	// so new replicas are seeded the same way and pods aren't restarted
+	podSpec := &desired.Spec.Template.Spec
+	for _, v := range res.Spec.Template.Spec.InitContainers {
+		if v.Name == render.RestoreContainerName {
+			podSpec.InitContainers = append(podSpec.InitContainers, v)
 		}
-
-		if i.Resource != nil {
-			shouldUpdate = true
-
-			resourceList := corev1.ResourceList{
-				corev1.ResourceCPU:    resource.MustParse(i.Resource.CPU),
-				corev1.ResourceMemory: resource.MustParse(i.Resource.Memory),
-			}
-
-			container.Resources = corev1.ResourceRequirements{
-				Requests: resourceList,
-				Limits:   resourceList,
-			}
+	}
+	for _, v := range res.Spec.Template.Spec.Volumes {
+		if v.Name == render.RestoreVolumeName {
+			podSpec.Volumes = append(podSpec.Volumes, v)
 		}
 	}
 
-	if shouldUpdate {
-		err = s.k8sClient.Update(ctx, res)
-		if err != nil {
-			return err
+	// password of referenced Secret may be unavailable for a moment,
+	// pods aren't restarted until its hash is known
+	if desired.Spec.Template.Annotations[render.AnnotationPasswordHash] == "" &&
+		res.Spec.Template.Annotations[render.AnnotationPasswordHash] != "" {
+		if desired.Spec.Template.Annotations == nil {
+			desired.Spec.Template.Annotations = make(map[string]string)
 		}
+		desired.Spec.Template.Annotations[render.AnnotationPasswordHash] = res.Spec.Template.Annotations[render.AnnotationPasswordHash]
+	}
+
+	// removed shards keep their pods until slots are moved away,
+	// StatefulSet is scaled down by cluster sync
+	if item.Spec.Mode == v1alpha1.TypeModeCluster &&
+		res.Spec.Replicas != nil && *desired.Spec.Replicas < *res.Spec.Replicas {
+		desired.Spec.Replicas = res.Spec.Replicas
 	}
 
 	return nil
@@ -171,23 +237,3 @@ func (s *valkeyService) getService(ctx context.Context, i types.NamespacedName)
 
 	return res, nil
 }
-
-func (s *valkeyService) updateService(ctx context.Context, i *UpdateRequest) error {
-	res, err := s.getService(ctx, types.NamespacedName{
-		Name:      i.CrdName,
-		Namespace: i.Namespace,
-	})
-	if err != nil {
-		return err
-	}
-	if res == nil {
-		return nil
-	}
-
-	err = s.k8sClient.Update(ctx, res)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
@@ -0,0 +1,347 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+	"github.com/uagolang/k8s-operator/internal/lib/validator"
+	"github.com/uagolang/k8s-operator/internal/services/valkey/render"
+)
+
+// imageRegexp matches image reference: [registry[:port]/]name[:tag][@digest]
+var imageRegexp = regexp.MustCompile(`^(?:[a-zA-Z0-9]+(?:[.-][a-zA-Z0-9]+)*(?::[0-9]+)?/)?` +
+	`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+	`(?::[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?(?:@sha256:[a-f0-9]{64})?$`)
+
+// SetupValkeyWebhookWithManager registers defaulting and validating webhooks for Valkey
+func SetupValkeyWebhookWithManager(mgr ctrl.Manager, defaults ValkeyDefaults) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.Valkey{}).
+		WithDefaulter(&ValkeyDefaulter{Defaults: defaults}).
+		WithValidator(new(ValkeyValidator)).
+		Complete()
+}
+
+// ValkeyDefaults are operator level values for fields omitted in ValkeySpec
+type ValkeyDefaults struct {
+	Image         string
+	Replicas      int32
+	CPU           string
+	Memory        string
+	Storage       string
+	VolumeStorage string
+}
+
+// NewValkeyDefaults returns defaults used when operator isn't configured
+func NewValkeyDefaults() ValkeyDefaults {
+	return ValkeyDefaults{
+		Image:         "valkey/valkey:8.0",
+		Replicas:      1,
+		CPU:           "100m",
+		Memory:        "256Mi",
+		Storage:       "1Gi",
+		VolumeStorage: "1Gi",
+	}
+}
+
+//+kubebuilder:webhook:path=/mutate-database-kuberly-io-v1alpha1-valkey,mutating=true,failurePolicy=fail,sideEffects=None,groups=database.kuberly.io,resources=valkeys,verbs=create;update,versions=v1alpha1,name=mvalkey.kuberly.io,admissionReviewVersions=v1
+
+// ValkeyDefaulter fills omitted fields, so stored object is explicit
+// and doesn't change when operator defaults are changed
+type ValkeyDefaulter struct {
+	Defaults ValkeyDefaults
+}
+
+var _ webhook.CustomDefaulter = new(ValkeyDefaulter)
+
+func (d *ValkeyDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	item, ok := obj.(*v1alpha1.Valkey)
+	if !ok {
+		return fmt.Errorf("expected Valkey, got %T", obj)
+	}
+
+	spec := &item.Spec
+	spec.Image = lo.CoalesceOrEmpty(spec.Image, d.Defaults.Image)
+	spec.Mode = lo.CoalesceOrEmpty(spec.Mode, v1alpha1.TypeModeStandalone)
+	spec.DeletionPolicy = lo.CoalesceOrEmpty(spec.DeletionPolicy, v1alpha1.TypeDeletionPolicyDelete)
+	// replicas are calculated from cluster topology in cluster mode,
+	// explicit zero is kept, it stops the instance
+	if spec.Mode != v1alpha1.TypeModeCluster && spec.Replicas == nil {
+		spec.Replicas = lo.ToPtr(d.Defaults.Replicas)
+	}
+
+	spec.Resource.CPU = lo.CoalesceOrEmpty(spec.Resource.CPU, d.Defaults.CPU)
+	spec.Resource.Memory = lo.CoalesceOrEmpty(spec.Resource.Memory, d.Defaults.Memory)
+	spec.Resource.Storage = lo.CoalesceOrEmpty(spec.Resource.Storage, d.Defaults.Storage)
+	if spec.Volume.Enabled {
+		spec.Volume.Storage = lo.CoalesceOrEmpty(spec.Volume.Storage, d.Defaults.VolumeStorage)
+	}
+
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-database-kuberly-io-v1alpha1-valkey,mutating=false,failurePolicy=fail,sideEffects=None,groups=database.kuberly.io,resources=valkeys,verbs=create;update,versions=v1alpha1,name=vvalkey.kuberly.io,admissionReviewVersions=v1
+
+// ValkeyValidator rejects specs which can't be reconciled,
+// e.g. invalid quantities or changes of immutable fields
+type ValkeyValidator struct{}
+
+var _ webhook.CustomValidator = new(ValkeyValidator)
+
+func (v *ValkeyValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	item, ok := obj.(*v1alpha1.Valkey)
+	if !ok {
+		return nil, fmt.Errorf("expected Valkey, got %T", obj)
+	}
+
+	errs := validateSpec(&item.Spec)
+	errs = append(errs, validateRestore(item)...)
+
+	return warnings(item), invalid(item, errs)
+}
+
+func (v *ValkeyValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	prev, ok := oldObj.(*v1alpha1.Valkey)
+	if !ok {
+		return nil, fmt.Errorf("expected Valkey, got %T", oldObj)
+	}
+	item, ok := newObj.(*v1alpha1.Valkey)
+	if !ok {
+		return nil, fmt.Errorf("expected Valkey, got %T", newObj)
+	}
+
+	errs := validateSpec(&item.Spec)
+	errs = append(errs, validateTransition(&prev.Spec, &item.Spec)...)
+
+	return warnings(item), invalid(item, errs)
+}
+
+func (v *ValkeyValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validateSpec(spec *v1alpha1.ValkeySpec) validator.Errors {
+	var errs validator.Errors
+
+	if !imageRegexp.MatchString(spec.Image) {
+		errs = append(errs, validator.Error{
+			Field:   "spec.image",
+			Message: fmt.Sprintf("image %q is not a valid image reference", spec.Image),
+		})
+	}
+
+	errs = append(errs, validateQuantity("spec.resource.cpu", spec.Resource.CPU)...)
+	errs = append(errs, validateQuantity("spec.resource.memory", spec.Resource.Memory)...)
+	errs = append(errs, validateQuantity("spec.resource.storage", spec.Resource.Storage)...)
+	if spec.Volume.Enabled {
+		errs = append(errs, validateQuantity("spec.volume.storage", spec.Volume.Storage)...)
+	}
+
+	if spec.Mode == v1alpha1.TypeModeCluster && spec.Cluster == nil {
+		errs = append(errs, validator.Error{
+			Field:   "spec.cluster",
+			Message: "cluster is required in cluster mode",
+		})
+	}
+
+	if spec.Sentinel != nil && spec.Sentinel.Enabled {
+		if spec.Mode != v1alpha1.TypeModeReplication {
+			errs = append(errs, validator.Error{
+				Field:   "spec.sentinel",
+				Message: "sentinel requires replication mode",
+			})
+		}
+		if spec.Sentinel.Replicas > 0 && spec.Sentinel.Quorum > spec.Sentinel.Replicas {
+			errs = append(errs, validator.Error{
+				Field:   "spec.sentinel.quorum",
+				Message: "quorum can't be greater than sentinel replicas",
+			})
+		}
+	}
+
+	if spec.TLS != nil && spec.TLS.DisablePlaintext && !spec.TLS.Enabled {
+		errs = append(errs, validator.Error{
+			Field:   "spec.tls.disablePlaintext",
+			Message: "plaintext port can't be disabled without TLS",
+		})
+	}
+
+	if err := render.ValidateConfig(spec.Config); err != nil {
+		errs = append(errs, validator.Error{
+			Field:   "spec.config",
+			Message: err.Error(),
+		})
+	}
+
+	return errs
+}
+
+func validateQuantity(path, value string) validator.Errors {
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return validator.Errors{{
+			Field:   path,
+			Message: fmt.Sprintf("%q is not a valid quantity", value),
+		}}
+	}
+	if q.Sign() <= 0 {
+		return validator.Errors{{
+			Field:   path,
+			Message: fmt.Sprintf("%q should be greater than zero", value),
+		}}
+	}
+
+	return nil
+}
+
+// validateRestore checks restore source only on create, it's immutable
+// together with volume and mode it depends on
+func validateRestore(item *v1alpha1.Valkey) validator.Errors {
+	src := item.Spec.RestoreFrom
+	if src == nil {
+		return nil
+	}
+
+	var errs validator.Errors
+	if !item.Spec.Volume.Enabled {
+		errs = append(errs, validator.Error{
+			Field:   "spec.restoreFrom",
+			Message: "restore requires persistent volume",
+		})
+	}
+	if item.Spec.Mode == v1alpha1.TypeModeCluster {
+		errs = append(errs, validator.Error{
+			Field:   "spec.restoreFrom",
+			Message: "restore isn't supported in cluster mode",
+		})
+	}
+	if src.ValkeyName == item.Name {
+		errs = append(errs, validator.Error{
+			Field:   "spec.restoreFrom.valkeyName",
+			Message: "instance can't be cloned from itself",
+		})
+	}
+	// restore seeds only RDB file, which isn't loaded when AOF is enabled
+	if strings.EqualFold(item.Spec.Config["appendonly"], "yes") {
+		errs = append(errs, validator.Error{
+			Field:   "spec.config.appendonly",
+			Message: "appendonly can't be enabled on restore, set it after the instance is restored",
+		})
+	}
+
+	return errs
+}
+
+// validateTransition rejects changes which can't be applied to running instance
+func validateTransition(prev, spec *v1alpha1.ValkeySpec) validator.Errors {
+	var errs validator.Errors
+
+	if prev.User != spec.User {
+		errs = append(errs, validator.Error{
+			Field:   "spec.user",
+			Message: "user can't be changed after creation",
+		})
+	}
+
+	// mode is defaulted, so empty mode is the same as standalone
+	if lo.CoalesceOrEmpty(prev.Mode, v1alpha1.TypeModeStandalone) != lo.CoalesceOrEmpty(spec.Mode, v1alpha1.TypeModeStandalone) {
+		errs = append(errs, validator.Error{
+			Field:   "spec.mode",
+			Message: "mode can't be changed after creation",
+		})
+	}
+
+	if prev.Volume.Enabled != spec.Volume.Enabled {
+		errs = append(errs, validator.Error{
+			Field:   "spec.volume.enabled",
+			Message: "persistent volume can't be enabled or disabled after creation",
+		})
+	}
+
+	if prev.Cluster != nil && spec.Cluster != nil && prev.Cluster.ReplicasPerShard != spec.Cluster.ReplicasPerShard {
+		errs = append(errs, validator.Error{
+			Field:   "spec.cluster.replicas_per_shard",
+			Message: "replicas per shard can't be changed after creation",
+		})
+	}
+
+	if !equality.Semantic.DeepEqual(prev.RestoreFrom, spec.RestoreFrom) {
+		errs = append(errs, validator.Error{
+			Field:   "spec.restoreFrom",
+			Message: "restore source can't be changed after creation",
+		})
+	}
+
+	if spec.Volume.Enabled {
+		// volume storage overrides resource storage, so effective claim sizes are compared
+		path := "spec.resource.storage"
+		if spec.Volume.Storage != "" {
+			path = "spec.volume.storage"
+		}
+		errs = append(errs, validateStorageShrink(path, prev.ClaimStorage(), spec.ClaimStorage())...)
+	}
+
+	return errs
+}
+
+// validateStorageShrink rejects storage decrease, volume claims can only grow
+func validateStorageShrink(path, prev, value string) validator.Errors {
+	prevQ, err := resource.ParseQuantity(prev)
+	if err != nil {
+		return nil
+	}
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		// reported by validateSpec
+		return nil
+	}
+
+	if q.Cmp(prevQ) < 0 {
+		return validator.Errors{{
+			Field:   path,
+			Message: fmt.Sprintf("storage can't be decreased from %s to %s", prev, value),
+		}}
+	}
+
+	return nil
+}
+
+func warnings(item *v1alpha1.Valkey) admission.Warnings {
+	if item.Spec.Password != "" {
+		return admission.Warnings{"spec.password is stored in clear text, use spec.auth.passwordSecretRef instead"}
+	}
+
+	return nil
+}
+
+// invalid converts validation errors into Invalid status,
+// so every error is reported for its own field
+func invalid(item *v1alpha1.Valkey, errs validator.Errors) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	list := lo.Map(errs, func(e validator.Error, _ int) *field.Error {
+		return &field.Error{
+			Type:     field.ErrorTypeInvalid,
+			Field:    e.Field,
+			BadValue: field.OmitValueType{},
+			Detail:   e.Message,
+		}
+	})
+
+	return k8serrors.NewInvalid(v1alpha1.GroupVersion.WithKind("Valkey").GroupKind(), item.Name, list)
+}
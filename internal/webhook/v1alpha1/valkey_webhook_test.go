@@ -0,0 +1,376 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/uagolang/k8s-operator/api/v1alpha1"
+)
+
+func validValkey() *v1alpha1.Valkey {
+	return &v1alpha1.Valkey{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app-db",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.ValkeySpec{
+			Image:    "valkey/valkey:8.0",
+			Replicas: lo.ToPtr(int32(1)),
+			User:     "root",
+			Volume: v1alpha1.Volume{
+				Enabled: true,
+				Storage: "1Gi",
+			},
+			Resource: v1alpha1.Resource{
+				CPU:     "200m",
+				Memory:  "256Mi",
+				Storage: "10Gi",
+			},
+		},
+	}
+}
+
+// invalidFields returns fields of Invalid error causes
+func invalidFields(t *testing.T, err error) []string {
+	t.Helper()
+
+	require.True(t, k8serrors.IsInvalid(err), "expected Invalid error, got %v", err)
+
+	var status k8serrors.APIStatus
+	require.ErrorAs(t, err, &status)
+
+	return lo.Map(status.Status().Details.Causes, func(c metav1.StatusCause, _ int) string { return c.Field })
+}
+
+func TestValkeyValidator_ValidateCreate(t *testing.T) {
+	tests := []struct {
+		name       string
+		mutate     func(item *v1alpha1.Valkey)
+		wantFields []string
+		wantWarn   bool
+	}{
+		{
+			name:   "valid",
+			mutate: func(item *v1alpha1.Valkey) {},
+		},
+		{
+			name: "registry with port and digest",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Image = "registry.local:5000/valkey/valkey@sha256:" +
+					"0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+			},
+		},
+		{
+			name:       "invalid image",
+			mutate:     func(item *v1alpha1.Valkey) { item.Spec.Image = "Valkey:latest tag" },
+			wantFields: []string{"spec.image"},
+		},
+		{
+			name: "invalid quantities",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Resource.CPU = "lots"
+				item.Spec.Resource.Memory = "0"
+				item.Spec.Volume.Storage = "-1Gi"
+			},
+			wantFields: []string{"spec.resource.cpu", "spec.resource.memory", "spec.volume.storage"},
+		},
+		{
+			name: "volume storage is ignored when volume is disabled",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Volume = v1alpha1.Volume{}
+			},
+		},
+		{
+			name:       "cluster mode without cluster",
+			mutate:     func(item *v1alpha1.Valkey) { item.Spec.Mode = v1alpha1.TypeModeCluster },
+			wantFields: []string{"spec.cluster"},
+		},
+		{
+			name: "sentinel without replication",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Sentinel = &v1alpha1.Sentinel{Enabled: true, Replicas: 3, Quorum: 4}
+			},
+			wantFields: []string{"spec.sentinel", "spec.sentinel.quorum"},
+		},
+		{
+			name: "restore from backup",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.RestoreFrom = &v1alpha1.RestoreSource{BackupName: "nightly"}
+			},
+		},
+		{
+			name: "restore without volume",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Volume = v1alpha1.Volume{}
+				item.Spec.RestoreFrom = &v1alpha1.RestoreSource{BackupName: "nightly"}
+			},
+			wantFields: []string{"spec.restoreFrom"},
+		},
+		{
+			name: "clone from itself",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.RestoreFrom = &v1alpha1.RestoreSource{ValkeyName: item.Name}
+			},
+			wantFields: []string{"spec.restoreFrom.valkeyName"},
+		},
+		{
+			name: "restore with appendonly",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.RestoreFrom = &v1alpha1.RestoreSource{BackupName: "nightly"}
+				item.Spec.Config = map[string]string{"appendonly": "yes"}
+			},
+			wantFields: []string{"spec.config.appendonly"},
+		},
+		{
+			name: "tls without plaintext",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.TLS = &v1alpha1.TLS{Enabled: true, SecretName: "certs", DisablePlaintext: true}
+			},
+		},
+		{
+			name: "plaintext disabled without tls",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.TLS = &v1alpha1.TLS{SecretName: "certs", DisablePlaintext: true}
+			},
+			wantFields: []string{"spec.tls.disablePlaintext"},
+		},
+		{
+			name: "supported config",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Config = map[string]string{"maxmemory-policy": "allkeys-lru", "databases": "4"}
+			},
+		},
+		{
+			name: "config managed by operator",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Config = map[string]string{"port": "6390"}
+			},
+			wantFields: []string{"spec.config"},
+		},
+		{
+			name: "config value with line break",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Config = map[string]string{"maxmemory-policy": "allkeys-lru\nport 6390"}
+			},
+			wantFields: []string{"spec.config"},
+		},
+		{
+			name:     "clear text password",
+			mutate:   func(item *v1alpha1.Valkey) { item.Spec.Password = "secret" },
+			wantWarn: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := validValkey()
+			tt.mutate(item)
+
+			warns, err := new(ValkeyValidator).ValidateCreate(context.Background(), item)
+			require.Equal(t, tt.wantWarn, len(warns) > 0)
+			if len(tt.wantFields) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.ElementsMatch(t, tt.wantFields, invalidFields(t, err))
+		})
+	}
+}
+
+func TestValkeyValidator_ValidateUpdate(t *testing.T) {
+	tests := []struct {
+		name       string
+		mutate     func(item *v1alpha1.Valkey)
+		wantFields []string
+	}{
+		{
+			name: "scale and grow storage",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Replicas = lo.ToPtr(int32(3))
+				item.Spec.Resource.Storage = "20Gi"
+				item.Spec.Volume.Storage = "2Gi"
+			},
+		},
+		{
+			name:   "explicit standalone mode",
+			mutate: func(item *v1alpha1.Valkey) { item.Spec.Mode = v1alpha1.TypeModeStandalone },
+		},
+		{
+			name: "shrink storage",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.Resource.Storage = "5Gi"
+				item.Spec.Volume.Storage = "512Mi"
+			},
+			wantFields: []string{"spec.volume.storage"},
+		},
+		{
+			name:   "resource storage is overridden by volume storage",
+			mutate: func(item *v1alpha1.Valkey) { item.Spec.Resource.Storage = "5Gi" },
+		},
+
+		{
+			name: "immutable fields",
+			mutate: func(item *v1alpha1.Valkey) {
+				item.Spec.User = "admin"
+				item.Spec.Mode = v1alpha1.TypeModeReplication
+				item.Spec.Volume = v1alpha1.Volume{}
+				item.Spec.RestoreFrom = &v1alpha1.RestoreSource{BackupName: "nightly"}
+			},
+			wantFields: []string{"spec.user", "spec.mode", "spec.volume.enabled", "spec.restoreFrom"},
+		},
+		{
+			name:       "invalid spec is reported on update",
+			mutate:     func(item *v1alpha1.Valkey) { item.Spec.Resource.CPU = "lots" },
+			wantFields: []string{"spec.resource.cpu"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := validValkey()
+			item := prev.DeepCopy()
+			tt.mutate(item)
+
+			_, err := new(ValkeyValidator).ValidateUpdate(context.Background(), prev, item)
+			if len(tt.wantFields) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.ElementsMatch(t, tt.wantFields, invalidFields(t, err))
+		})
+	}
+}
+
+func TestValkeyValidator_ValidateUpdate_ClaimStorage(t *testing.T) {
+	// claim of instance created without volume storage is sized by resource storage
+	prev := validValkey()
+	prev.Spec.Volume.Storage = ""
+
+	item := prev.DeepCopy()
+	item.Spec.Volume.Storage = "20Gi"
+	_, err := new(ValkeyValidator).ValidateUpdate(context.Background(), prev, item)
+	require.NoError(t, err)
+
+	item.Spec.Volume.Storage = "1Gi"
+	_, err = new(ValkeyValidator).ValidateUpdate(context.Background(), prev, item)
+	require.ElementsMatch(t, []string{"spec.volume.storage"}, invalidFields(t, err))
+}
+
+func TestValkeyValidator_ValidateUpdate_Cluster(t *testing.T) {
+	prev := validValkey()
+	prev.Spec.Mode = v1alpha1.TypeModeCluster
+	prev.Spec.Cluster = &v1alpha1.Cluster{Shards: 3, ReplicasPerShard: 1}
+
+	item := prev.DeepCopy()
+	item.Spec.Cluster.Shards = 5
+	_, err := new(ValkeyValidator).ValidateUpdate(context.Background(), prev, item)
+	require.NoError(t, err)
+
+	item.Spec.Cluster.ReplicasPerShard = 2
+	_, err = new(ValkeyValidator).ValidateUpdate(context.Background(), prev, item)
+	require.ElementsMatch(t, []string{"spec.cluster.replicas_per_shard"}, invalidFields(t, err))
+}
+
+func TestValkeyDefaulter_Default(t *testing.T) {
+	defaults := NewValkeyDefaults()
+
+	tests := []struct {
+		name   string
+		spec   v1alpha1.ValkeySpec
+		expect v1alpha1.ValkeySpec
+	}{
+		{
+			name: "empty spec",
+			spec: v1alpha1.ValkeySpec{User: "root"},
+			expect: v1alpha1.ValkeySpec{
+				Image:          defaults.Image,
+				Replicas:       lo.ToPtr(defaults.Replicas),
+				Mode:           v1alpha1.TypeModeStandalone,
+				DeletionPolicy: v1alpha1.TypeDeletionPolicyDelete,
+				User:           "root",
+				Resource: v1alpha1.Resource{
+					CPU:     defaults.CPU,
+					Memory:  defaults.Memory,
+					Storage: defaults.Storage,
+				},
+			},
+		},
+		{
+			name: "explicit fields are kept",
+			spec: validValkey().Spec,
+			expect: func() v1alpha1.ValkeySpec {
+				spec := validValkey().Spec
+				spec.Mode = v1alpha1.TypeModeStandalone
+				spec.DeletionPolicy = v1alpha1.TypeDeletionPolicyDelete
+				return spec
+			}(),
+		},
+		{
+			name: "volume size",
+			spec: v1alpha1.ValkeySpec{
+				Replicas: lo.ToPtr(int32(3)),
+				Mode:     v1alpha1.TypeModeReplication,
+				Volume:   v1alpha1.Volume{Enabled: true},
+				Resource: v1alpha1.Resource{CPU: "1"},
+			},
+			expect: v1alpha1.ValkeySpec{
+				Image:          defaults.Image,
+				Replicas:       lo.ToPtr(int32(3)),
+				Mode:           v1alpha1.TypeModeReplication,
+				DeletionPolicy: v1alpha1.TypeDeletionPolicyDelete,
+				Volume:         v1alpha1.Volume{Enabled: true, Storage: defaults.VolumeStorage},
+				Resource: v1alpha1.Resource{
+					CPU:     "1",
+					Memory:  defaults.Memory,
+					Storage: defaults.Storage,
+				},
+			},
+		},
+		{
+			name: "zero replicas are kept",
+			spec: v1alpha1.ValkeySpec{User: "root", Replicas: lo.ToPtr(int32(0))},
+			expect: v1alpha1.ValkeySpec{
+				Image:          defaults.Image,
+				Replicas:       lo.ToPtr(int32(0)),
+				Mode:           v1alpha1.TypeModeStandalone,
+				DeletionPolicy: v1alpha1.TypeDeletionPolicyDelete,
+				User:           "root",
+				Resource: v1alpha1.Resource{
+					CPU:     defaults.CPU,
+					Memory:  defaults.Memory,
+					Storage: defaults.Storage,
+				},
+			},
+		},
+		{
+			name: "replicas aren't set in cluster mode",
+			spec: v1alpha1.ValkeySpec{
+				Mode:    v1alpha1.TypeModeCluster,
+				Cluster: &v1alpha1.Cluster{Shards: 3, ReplicasPerShard: 1},
+			},
+			expect: v1alpha1.ValkeySpec{
+				Image:          defaults.Image,
+				Mode:           v1alpha1.TypeModeCluster,
+				DeletionPolicy: v1alpha1.TypeDeletionPolicyDelete,
+				Cluster:        &v1alpha1.Cluster{Shards: 3, ReplicasPerShard: 1},
+				Resource: v1alpha1.Resource{
+					CPU:     defaults.CPU,
+					Memory:  defaults.Memory,
+					Storage: defaults.Storage,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := &v1alpha1.Valkey{Spec: tt.spec}
+
+			err := (&ValkeyDefaulter{Defaults: defaults}).Default(context.Background(), item)
+			require.NoError(t, err)
+			require.Equal(t, tt.expect, item.Spec)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/services/backup (interfaces: Service)
+//
+// Generated by this command:
+//
+//	mockgen -destination ./mocks/mock_backup_service.go -package mocks -mock_names Service=MockBackupService ./internal/services/backup Service
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	backup "github.com/uagolang/k8s-operator/internal/services/backup"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockBackupService is a mock of Service interface.
+type MockBackupService struct {
+	ctrl     *gomock.Controller
+	recorder *MockBackupServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockBackupServiceMockRecorder is the mock recorder for MockBackupService.
+type MockBackupServiceMockRecorder struct {
+	mock *MockBackupService
+}
+
+// NewMockBackupService creates a new mock instance.
+func NewMockBackupService(ctrl *gomock.Controller) *MockBackupService {
+	mock := &MockBackupService{ctrl: ctrl}
+	mock.recorder = &MockBackupServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBackupService) EXPECT() *MockBackupServiceMockRecorder {
+	return m.recorder
+}
+
+// Copy mocks base method.
+func (m *MockBackupService) Copy(ctx context.Context, i *backup.CopyRequest) (*backup.CopyResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Copy", ctx, i)
+	ret0, _ := ret[0].(*backup.CopyResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Copy indicates an expected call of Copy.
+func (mr *MockBackupServiceMockRecorder) Copy(ctx, i any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Copy", reflect.TypeOf((*MockBackupService)(nil).Copy), ctx, i)
+}
+
+// Prune mocks base method.
+func (m *MockBackupService) Prune(ctx context.Context, i *backup.PruneRequest) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Prune", ctx, i)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Prune indicates an expected call of Prune.
+func (mr *MockBackupServiceMockRecorder) Prune(ctx, i any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Prune", reflect.TypeOf((*MockBackupService)(nil).Prune), ctx, i)
+}
+
+// Save mocks base method.
+func (m *MockBackupService) Save(ctx context.Context, i *backup.SaveRequest) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, i)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockBackupServiceMockRecorder) Save(ctx, i any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockBackupService)(nil).Save), ctx, i)
+}
@@ -14,44 +14,45 @@ import (
 	reflect "reflect"
 
 	gomock "go.uber.org/mock/gomock"
+	client "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // MockFlow is a mock of Flow interface.
-type MockFlow struct {
+type MockFlow[T client.Object, S any] struct {
 	ctrl     *gomock.Controller
-	recorder *MockFlowMockRecorder
+	recorder *MockFlowMockRecorder[T, S]
 	isgomock struct{}
 }
 
 // MockFlowMockRecorder is the mock recorder for MockFlow.
-type MockFlowMockRecorder struct {
-	mock *MockFlow
+type MockFlowMockRecorder[T client.Object, S any] struct {
+	mock *MockFlow[T, S]
 }
 
 // NewMockFlow creates a new mock instance.
-func NewMockFlow(ctrl *gomock.Controller) *MockFlow {
-	mock := &MockFlow{ctrl: ctrl}
-	mock.recorder = &MockFlowMockRecorder{mock}
+func NewMockFlow[T client.Object, S any](ctrl *gomock.Controller) *MockFlow[T, S] {
+	mock := &MockFlow[T, S]{ctrl: ctrl}
+	mock.recorder = &MockFlowMockRecorder[T, S]{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockFlow) EXPECT() *MockFlowMockRecorder {
+func (m *MockFlow[T, S]) EXPECT() *MockFlowMockRecorder[T, S] {
 	return m.recorder
 }
 
 // Run mocks base method.
-func (m *MockFlow) Run(ctx context.Context, item any) (any, []string, error) {
+func (m *MockFlow[T, S]) Run(ctx context.Context, item T) (S, []string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Run", ctx, item)
-	ret0, _ := ret[0].(any)
+	ret0, _ := ret[0].(S)
 	ret1, _ := ret[1].([]string)
 	ret2, _ := ret[2].(error)
 	return ret0, ret1, ret2
 }
 
 // Run indicates an expected call of Run.
-func (mr *MockFlowMockRecorder) Run(ctx, item any) *gomock.Call {
+func (mr *MockFlowMockRecorder[T, S]) Run(ctx, item any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockFlow)(nil).Run), ctx, item)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockFlow[T, S])(nil).Run), ctx, item)
 }
@@ -0,0 +1,245 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/lib/valkeyclient (interfaces: Client)
+//
+// Generated by this command:
+//
+//	mockgen -destination ./mocks/mock_valkey_client.go -package mocks -mock_names Client=MockValkeyClient ./internal/lib/valkeyclient Client
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	x509 "crypto/x509"
+	reflect "reflect"
+
+	valkeyclient "github.com/uagolang/k8s-operator/internal/lib/valkeyclient"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockValkeyClient is a mock of Client interface.
+type MockValkeyClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockValkeyClientMockRecorder
+	isgomock struct{}
+}
+
+// MockValkeyClientMockRecorder is the mock recorder for MockValkeyClient.
+type MockValkeyClientMockRecorder struct {
+	mock *MockValkeyClient
+}
+
+// NewMockValkeyClient creates a new mock instance.
+func NewMockValkeyClient(ctrl *gomock.Controller) *MockValkeyClient {
+	mock := &MockValkeyClient{ctrl: ctrl}
+	mock.recorder = &MockValkeyClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockValkeyClient) EXPECT() *MockValkeyClientMockRecorder {
+	return m.recorder
+}
+
+// BGSave mocks base method.
+func (m *MockValkeyClient) BGSave(ctx context.Context, opts valkeyclient.Options) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BGSave", ctx, opts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BGSave indicates an expected call of BGSave.
+func (mr *MockValkeyClientMockRecorder) BGSave(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BGSave", reflect.TypeOf((*MockValkeyClient)(nil).BGSave), ctx, opts)
+}
+
+// Certificate mocks base method.
+func (m *MockValkeyClient) Certificate(ctx context.Context, opts valkeyclient.Options) (*x509.Certificate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Certificate", ctx, opts)
+	ret0, _ := ret[0].(*x509.Certificate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Certificate indicates an expected call of Certificate.
+func (mr *MockValkeyClientMockRecorder) Certificate(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Certificate", reflect.TypeOf((*MockValkeyClient)(nil).Certificate), ctx, opts)
+}
+
+// ClusterAddSlotsRange mocks base method.
+func (m *MockValkeyClient) ClusterAddSlotsRange(ctx context.Context, opts valkeyclient.Options, slots valkeyclient.SlotRange) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClusterAddSlotsRange", ctx, opts, slots)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClusterAddSlotsRange indicates an expected call of ClusterAddSlotsRange.
+func (mr *MockValkeyClientMockRecorder) ClusterAddSlotsRange(ctx, opts, slots any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClusterAddSlotsRange", reflect.TypeOf((*MockValkeyClient)(nil).ClusterAddSlotsRange), ctx, opts, slots)
+}
+
+// ClusterForget mocks base method.
+func (m *MockValkeyClient) ClusterForget(ctx context.Context, opts valkeyclient.Options, nodeID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClusterForget", ctx, opts, nodeID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClusterForget indicates an expected call of ClusterForget.
+func (mr *MockValkeyClientMockRecorder) ClusterForget(ctx, opts, nodeID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClusterForget", reflect.TypeOf((*MockValkeyClient)(nil).ClusterForget), ctx, opts, nodeID)
+}
+
+// ClusterMeet mocks base method.
+func (m *MockValkeyClient) ClusterMeet(ctx context.Context, opts valkeyclient.Options, host string, port int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClusterMeet", ctx, opts, host, port)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClusterMeet indicates an expected call of ClusterMeet.
+func (mr *MockValkeyClientMockRecorder) ClusterMeet(ctx, opts, host, port any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClusterMeet", reflect.TypeOf((*MockValkeyClient)(nil).ClusterMeet), ctx, opts, host, port)
+}
+
+// ClusterNodes mocks base method.
+func (m *MockValkeyClient) ClusterNodes(ctx context.Context, opts valkeyclient.Options) ([]valkeyclient.ClusterNode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClusterNodes", ctx, opts)
+	ret0, _ := ret[0].([]valkeyclient.ClusterNode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClusterNodes indicates an expected call of ClusterNodes.
+func (mr *MockValkeyClientMockRecorder) ClusterNodes(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClusterNodes", reflect.TypeOf((*MockValkeyClient)(nil).ClusterNodes), ctx, opts)
+}
+
+// ClusterReplicate mocks base method.
+func (m *MockValkeyClient) ClusterReplicate(ctx context.Context, opts valkeyclient.Options, nodeID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClusterReplicate", ctx, opts, nodeID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClusterReplicate indicates an expected call of ClusterReplicate.
+func (mr *MockValkeyClientMockRecorder) ClusterReplicate(ctx, opts, nodeID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClusterReplicate", reflect.TypeOf((*MockValkeyClient)(nil).ClusterReplicate), ctx, opts, nodeID)
+}
+
+// ConfigSet mocks base method.
+func (m *MockValkeyClient) ConfigSet(ctx context.Context, opts valkeyclient.Options, params map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfigSet", ctx, opts, params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConfigSet indicates an expected call of ConfigSet.
+func (mr *MockValkeyClientMockRecorder) ConfigSet(ctx, opts, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfigSet", reflect.TypeOf((*MockValkeyClient)(nil).ConfigSet), ctx, opts, params)
+}
+
+// MigrateSlot mocks base method.
+func (m *MockValkeyClient) MigrateSlot(ctx context.Context, from, to valkeyclient.Options, slot int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateSlot", ctx, from, to, slot)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigrateSlot indicates an expected call of MigrateSlot.
+func (mr *MockValkeyClientMockRecorder) MigrateSlot(ctx, from, to, slot any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateSlot", reflect.TypeOf((*MockValkeyClient)(nil).MigrateSlot), ctx, from, to, slot)
+}
+
+// Persistence mocks base method.
+func (m *MockValkeyClient) Persistence(ctx context.Context, opts valkeyclient.Options) (*valkeyclient.Persistence, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Persistence", ctx, opts)
+	ret0, _ := ret[0].(*valkeyclient.Persistence)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Persistence indicates an expected call of Persistence.
+func (mr *MockValkeyClientMockRecorder) Persistence(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Persistence", reflect.TypeOf((*MockValkeyClient)(nil).Persistence), ctx, opts)
+}
+
+// ReplicaOf mocks base method.
+func (m *MockValkeyClient) ReplicaOf(ctx context.Context, opts valkeyclient.Options, host string, port int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplicaOf", ctx, opts, host, port)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplicaOf indicates an expected call of ReplicaOf.
+func (mr *MockValkeyClientMockRecorder) ReplicaOf(ctx, opts, host, port any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplicaOf", reflect.TypeOf((*MockValkeyClient)(nil).ReplicaOf), ctx, opts, host, port)
+}
+
+// ReplicaOfNoOne mocks base method.
+func (m *MockValkeyClient) ReplicaOfNoOne(ctx context.Context, opts valkeyclient.Options) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplicaOfNoOne", ctx, opts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplicaOfNoOne indicates an expected call of ReplicaOfNoOne.
+func (mr *MockValkeyClientMockRecorder) ReplicaOfNoOne(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplicaOfNoOne", reflect.TypeOf((*MockValkeyClient)(nil).ReplicaOfNoOne), ctx, opts)
+}
+
+// Role mocks base method.
+func (m *MockValkeyClient) Role(ctx context.Context, opts valkeyclient.Options) (*valkeyclient.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Role", ctx, opts)
+	ret0, _ := ret[0].(*valkeyclient.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Role indicates an expected call of Role.
+func (mr *MockValkeyClientMockRecorder) Role(ctx, opts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Role", reflect.TypeOf((*MockValkeyClient)(nil).Role), ctx, opts)
+}
+
+// SentinelPrimary mocks base method.
+func (m *MockValkeyClient) SentinelPrimary(ctx context.Context, opts valkeyclient.Options, name string) (string, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SentinelPrimary", ctx, opts, name)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SentinelPrimary indicates an expected call of SentinelPrimary.
+func (mr *MockValkeyClientMockRecorder) SentinelPrimary(ctx, opts, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SentinelPrimary", reflect.TypeOf((*MockValkeyClient)(nil).SentinelPrimary), ctx, opts, name)
+}
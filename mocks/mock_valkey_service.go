@@ -13,6 +13,7 @@ import (
 	context "context"
 	reflect "reflect"
 
+	v1alpha1 "github.com/uagolang/k8s-operator/api/v1alpha1"
 	valkey "github.com/uagolang/k8s-operator/internal/services/valkey"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -41,6 +42,20 @@ func (m *MockValkeyService) EXPECT() *MockValkeyServiceMockRecorder {
 	return m.recorder
 }
 
+// ApplyConfig mocks base method.
+func (m *MockValkeyService) ApplyConfig(ctx context.Context, i *valkey.ApplyConfigRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyConfig", ctx, i)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplyConfig indicates an expected call of ApplyConfig.
+func (mr *MockValkeyServiceMockRecorder) ApplyConfig(ctx, i any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyConfig", reflect.TypeOf((*MockValkeyService)(nil).ApplyConfig), ctx, i)
+}
+
 // Create mocks base method.
 func (m *MockValkeyService) Create(ctx context.Context, i *valkey.CreateRequest) error {
 	m.ctrl.T.Helper()
@@ -85,12 +100,88 @@ func (mr *MockValkeyServiceMockRecorder) IsReady(ctx, i any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsReady", reflect.TypeOf((*MockValkeyService)(nil).IsReady), ctx, i)
 }
 
+// IsStorageReady mocks base method.
+func (m *MockValkeyService) IsStorageReady(ctx context.Context, i *valkey.IsReadyRequest) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsStorageReady", ctx, i)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsStorageReady indicates an expected call of IsStorageReady.
+func (mr *MockValkeyServiceMockRecorder) IsStorageReady(ctx, i any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsStorageReady", reflect.TypeOf((*MockValkeyService)(nil).IsStorageReady), ctx, i)
+}
+
+// ReloadTLS mocks base method.
+func (m *MockValkeyService) ReloadTLS(ctx context.Context, i *valkey.ReloadTLSRequest) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReloadTLS", ctx, i)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReloadTLS indicates an expected call of ReloadTLS.
+func (mr *MockValkeyServiceMockRecorder) ReloadTLS(ctx, i any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReloadTLS", reflect.TypeOf((*MockValkeyService)(nil).ReloadTLS), ctx, i)
+}
+
+// ResizeStorage mocks base method.
+func (m *MockValkeyService) ResizeStorage(ctx context.Context, i *valkey.ResizeStorageRequest) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResizeStorage", ctx, i)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResizeStorage indicates an expected call of ResizeStorage.
+func (mr *MockValkeyServiceMockRecorder) ResizeStorage(ctx, i any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResizeStorage", reflect.TypeOf((*MockValkeyService)(nil).ResizeStorage), ctx, i)
+}
+
+// SyncCluster mocks base method.
+func (m *MockValkeyService) SyncCluster(ctx context.Context, i *valkey.SyncClusterRequest) ([]v1alpha1.ShardStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncCluster", ctx, i)
+	ret0, _ := ret[0].([]v1alpha1.ShardStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SyncCluster indicates an expected call of SyncCluster.
+func (mr *MockValkeyServiceMockRecorder) SyncCluster(ctx, i any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncCluster", reflect.TypeOf((*MockValkeyService)(nil).SyncCluster), ctx, i)
+}
+
+// SyncReplication mocks base method.
+func (m *MockValkeyService) SyncReplication(ctx context.Context, i *valkey.SyncReplicationRequest) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SyncReplication", ctx, i)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SyncReplication indicates an expected call of SyncReplication.
+func (mr *MockValkeyServiceMockRecorder) SyncReplication(ctx, i any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncReplication", reflect.TypeOf((*MockValkeyService)(nil).SyncReplication), ctx, i)
+}
+
 // Update mocks base method.
-func (m *MockValkeyService) Update(ctx context.Context, i *valkey.UpdateRequest) error {
+func (m *MockValkeyService) Update(ctx context.Context, i *valkey.UpdateRequest) ([]string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Update", ctx, i)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.